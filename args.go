@@ -0,0 +1,81 @@
+package sc
+
+import (
+	"context"
+	"time"
+)
+
+// Key2 is a composite cache key of two arguments, used by Cache2.
+type Key2[A, B comparable] struct {
+	A A
+	B B
+}
+
+// Key3 is a composite cache key of three arguments, used by Cache3.
+type Key3[A, B, C comparable] struct {
+	A A
+	B B
+	C C
+}
+
+// Cache2 is a cache wrapping a function with two arguments.
+// All methods of Cache are available with Key2 as the key.
+type Cache2[A, B comparable, V any] struct {
+	*Cache[Key2[A, B], V]
+}
+
+// Cache3 is a cache wrapping a function with three arguments.
+// All methods of Cache are available with Key3 as the key.
+type Cache3[A, B, C comparable, V any] struct {
+	*Cache[Key3[A, B, C], V]
+}
+
+// New2 is similar to New, but wraps a function with two arguments.
+func New2[A, B comparable, V any](fn func(ctx context.Context, a A, b B) (V, error), freshFor, ttl time.Duration, options ...CacheOption) (*Cache2[A, B, V], error) {
+	var replaceFn replaceFunc[Key2[A, B], V]
+	if fn != nil {
+		replaceFn = func(ctx context.Context, key Key2[A, B]) (V, error) {
+			return fn(ctx, key.A, key.B)
+		}
+	}
+	c, err := New(replaceFn, freshFor, ttl, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache2[A, B, V]{Cache: c}, nil
+}
+
+// New3 is similar to New, but wraps a function with three arguments.
+func New3[A, B, C comparable, V any](fn func(ctx context.Context, a A, b B, c C) (V, error), freshFor, ttl time.Duration, options ...CacheOption) (*Cache3[A, B, C, V], error) {
+	var replaceFn replaceFunc[Key3[A, B, C], V]
+	if fn != nil {
+		replaceFn = func(ctx context.Context, key Key3[A, B, C]) (V, error) {
+			return fn(ctx, key.A, key.B, key.C)
+		}
+	}
+	c, err := New(replaceFn, freshFor, ttl, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache3[A, B, C, V]{Cache: c}, nil
+}
+
+// Get2 is a shorthand for Get with Key2{k1, k2} as the key.
+func (c *Cache2[A, B, V]) Get2(ctx context.Context, k1 A, k2 B) (V, error) {
+	return c.Get(ctx, Key2[A, B]{k1, k2})
+}
+
+// Forget2 is a shorthand for Forget with Key2{k1, k2} as the key.
+func (c *Cache2[A, B, V]) Forget2(k1 A, k2 B) {
+	c.Forget(Key2[A, B]{k1, k2})
+}
+
+// Get3 is a shorthand for Get with Key3{k1, k2, k3} as the key.
+func (c *Cache3[A, B, C, V]) Get3(ctx context.Context, k1 A, k2 B, k3 C) (V, error) {
+	return c.Get(ctx, Key3[A, B, C]{k1, k2, k3})
+}
+
+// Forget3 is a shorthand for Forget with Key3{k1, k2, k3} as the key.
+func (c *Cache3[A, B, C, V]) Forget3(k1 A, k2 B, k3 C) {
+	c.Forget(Key3[A, B, C]{k1, k2, k3})
+}
@@ -0,0 +1,121 @@
+package sc
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew2(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid fn", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New2[string, int, string](nil, 0, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		fn := func(ctx context.Context, a string, b int) (string, error) { return "", nil }
+		_, err := New2(fn, 0, 0, WithLRUBackend(0))
+		assert.Error(t, err)
+	})
+}
+
+func TestNew3(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid fn", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New3[string, int, bool, string](nil, 0, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		fn := func(ctx context.Context, a string, b int, c bool) (string, error) { return "", nil }
+		_, err := New3(fn, 0, 0, WithLRUBackend(0))
+		assert.Error(t, err)
+	})
+}
+
+func TestCache2_Get2(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			fn := func(ctx context.Context, a string, b int) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return a + "-" + strconv.Itoa(b), nil
+			}
+			cache, err := New2(fn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			v, err := cache.Get2(context.Background(), "k", 1)
+			assert.NoError(t, err)
+			assert.Equal(t, "k-1", v)
+			v, err = cache.Get2(context.Background(), "k", 2)
+			assert.NoError(t, err)
+			assert.Equal(t, "k-2", v)
+			v, err = cache.Get(context.Background(), Key2[string, int]{"k", 1})
+			assert.NoError(t, err)
+			assert.Equal(t, "k-1", v)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+
+			cache.Forget2("k", 1)
+			v, err = cache.Get2(context.Background(), "k", 1)
+			assert.NoError(t, err)
+			assert.Equal(t, "k-1", v)
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+		})
+	}
+}
+
+func TestCache3_Get3(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			fn := func(ctx context.Context, a string, b int, c bool) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return a + "-" + strconv.Itoa(b) + "-" + strconv.FormatBool(c), nil
+			}
+			cache, err := New3(fn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			v, err := cache.Get3(context.Background(), "k", 1, true)
+			assert.NoError(t, err)
+			assert.Equal(t, "k-1-true", v)
+			v, err = cache.Get3(context.Background(), "k", 1, false)
+			assert.NoError(t, err)
+			assert.Equal(t, "k-1-false", v)
+			v, err = cache.Get(context.Background(), Key3[string, int, bool]{"k", 1, true})
+			assert.NoError(t, err)
+			assert.Equal(t, "k-1-true", v)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+
+			cache.Forget3("k", 1, true)
+			v, err = cache.Get3(context.Background(), "k", 1, true)
+			assert.NoError(t, err)
+			assert.Equal(t, "k-1-true", v)
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+		})
+	}
+}
@@ -10,21 +10,39 @@ import (
 type backend[K comparable, V any] interface {
 	// Get the value for key.
 	Get(key K) (v V, ok bool)
+	// Peek gets the value for key without updating the recent usage.
+	Peek(key K) (v V, ok bool)
 	// Set the value for key.
 	Set(key K, v V)
 	// Delete the value for key.
 	Delete(key K)
 	// DeleteIf deletes all values that match the predicate.
 	DeleteIf(predicate func(key K, value V) bool)
+	// DeleteIfN is similar to DeleteIf, but calls predicate for at most n values, resuming from the position where
+	// the previous call stopped (if supported by the backend). wrapped is true if all values have been visited in turn.
+	DeleteIfN(n int, predicate func(key K, value V) bool) (visited int, wrapped bool)
+	// Range calls f for each value, until f returns false.
+	Range(f func(key K, value V) bool)
 	// Purge all values.
 	Purge()
+	// SetEvictCallback sets a function called when a value is evicted to make room for a new value.
+	SetEvictCallback(onEvict func(key K, value V))
 
 	// Size returns the number of items currently stored.
 	Size() int
 	// Capacity returns the maximum number of items that can be stored.
 	Capacity() int
+	// Resize changes the capacity, evicting values if the number of items exceeds the new capacity.
+	// The evict callback is called for each evicted value.
+	Resize(capacity int) (evicted int)
 }
 
+var (
+	_ backend[string, int] = mapBackend[string, int]{}
+	_ backend[string, int] = &lru.Cache[string, int]{}
+	_ backend[string, int] = &tq.Cache[string, int]{}
+)
+
 type mapBackend[K comparable, V any] map[K]V
 
 func newMapBackend[K comparable, V any](cap int) backend[K, V] {
@@ -36,6 +54,11 @@ func (m mapBackend[K, V]) Get(key K) (v V, ok bool) {
 	return
 }
 
+func (m mapBackend[K, V]) Peek(key K) (v V, ok bool) {
+	v, ok = m[key]
+	return
+}
+
 func (m mapBackend[K, V]) Set(key K, v V) {
 	m[key] = v
 }
@@ -52,6 +75,29 @@ func (m mapBackend[K, V]) DeleteIf(predicate func(key K, value V) bool) {
 	}
 }
 
+// DeleteIfN visits at most n values from a random position, since iteration over a map cannot be resumed.
+// Go randomizes the starting position of each iteration, so that repeated calls visit most values in turn.
+func (m mapBackend[K, V]) DeleteIfN(n int, predicate func(key K, value V) bool) (visited int, wrapped bool) {
+	for k, v := range m {
+		if visited >= n {
+			return visited, false
+		}
+		visited++
+		if predicate(k, v) {
+			delete(m, k)
+		}
+	}
+	return visited, true
+}
+
+func (m mapBackend[K, V]) Range(f func(key K, value V) bool) {
+	for k, v := range m {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
 func (m mapBackend[K, V]) Purge() {
 	// This form is optimized by the Go-compiler; it calls faster internal mapclear() instead of looping, and avoids
 	// allocating new memory.
@@ -61,6 +107,10 @@ func (m mapBackend[K, V]) Purge() {
 	}
 }
 
+func (m mapBackend[K, V]) SetEvictCallback(func(key K, value V)) {
+	// map backend never evicts values
+}
+
 func (m mapBackend[K, V]) Size() int {
 	return len(m)
 }
@@ -69,10 +119,31 @@ func (m mapBackend[K, V]) Capacity() int {
 	return -1
 }
 
+func (m mapBackend[K, V]) Resize(int) int {
+	// map backend has no capacity
+	return 0
+}
+
 func newLRUBackend[K comparable, V any](cap int) backend[K, V] {
 	return lru.New[K, V](lru.WithCapacity(cap))
 }
 
+func newFIFOBackend[K comparable, V any](cap int) backend[K, V] {
+	return lru.New[K, V](lru.WithCapacity(cap), lru.WithFIFO())
+}
+
 func new2QBackend[K comparable, V any](cap int) backend[K, V] {
 	return tq.New[K, V](cap)
 }
+
+func newWeightedLRUBackend[K comparable, V any](maxWeight int64, weigher func(key K, v V) int64) backend[K, value[V]] {
+	return lru.NewWeighted[K, value[V]](maxWeight, func(key K, val value[V]) int64 {
+		return weigher(key, val.v)
+	})
+}
+
+// weightedBackend is implemented by backends bounded by the total weight of the items. See WithMaxWeight.
+type weightedBackend interface {
+	// Weight returns the total weight of the items currently stored.
+	Weight() int64
+}
@@ -0,0 +1,42 @@
+package sc
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackend_SizeCapacity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		backend  backend[string, int]
+		capacity int
+	}{
+		{"map", newMapBackend[string, int](10), -1},
+		{"LRU", newLRUBackend[string, int](10), 10},
+		{"2Q", new2QBackend[string, int](10), 10},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := tt.backend
+			assert.Equal(t, 0, b.Size())
+			assert.Equal(t, tt.capacity, b.Capacity())
+
+			for i := 0; i < 5; i++ {
+				b.Set(strconv.Itoa(i), i)
+			}
+			assert.Equal(t, 5, b.Size())
+			b.Delete("0")
+			assert.Equal(t, 4, b.Size())
+			b.Purge()
+			assert.Equal(t, 0, b.Size())
+			assert.Equal(t, tt.capacity, b.Capacity())
+		})
+	}
+}
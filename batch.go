@@ -0,0 +1,370 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// batchReplaceFunc retrieves values for multiple keys at once.
+// Keys missing from the returned map are considered not found, and are not cached.
+type batchReplaceFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// NewBatched creates a new cache instance which retrieves values with a one-to-many batch function.
+//
+// Keys requested together via GetMulti are retrieved with a single call to batchFn.
+// Get works just as well, retrieving the single key with batchFn.
+// To retrieve keys requested by concurrent Get calls together, use WithBatchWindow option.
+// Keys missing from the map returned by batchFn are not cached, and Get returns ErrNotFound for those keys.
+//
+// See New for the details of the other arguments.
+func NewBatched[K comparable, V any](batchFn batchReplaceFunc[K, V], freshFor, ttl time.Duration, options ...CacheOption) (*Cache[K, V], error) {
+	if batchFn == nil {
+		return nil, ErrNilBatchFn
+	}
+	replaceFn := func(ctx context.Context, key K) (V, error) {
+		values, err := batchFn(ctx, []K{key})
+		if err != nil && !errors.Is(err, ErrSkipCache) {
+			var zero V
+			return zero, err
+		}
+		v, ok := values[key]
+		if !ok {
+			return v, ErrNotFound
+		}
+		return v, err
+	}
+	c, err := New(replaceFn, freshFor, ttl, options...)
+	if err != nil {
+		return nil, err
+	}
+	if c.lowerTier != nil {
+		batchFn = c.lowerTier.wrapBatch(batchFn)
+	}
+	c.batchFn = batchFn
+	return c, nil
+}
+
+// GetMulti retrieves items for multiple keys at once, loading missing items into the cache.
+// The behavior of each key is the same as Get - stale items may be returned while being refreshed in the background.
+//
+// Missing keys are coalesced per key: if another call is already retrieving a key, GetMulti waits for that call
+// instead of retrieving the key again. The rest of the missing keys are retrieved together with a single call to
+// the batch function if the cache was created with NewBatched, or concurrently with replaceFn otherwise.
+// Therefore, concurrent GetMulti calls for overlapping keys share a single batch retrieval for the shared keys.
+//
+// Duplicate keys are allowed, and share a single retrieval.
+//
+// ## Error semantics
+//
+// The returned map is never nil, and contains the values for all keys that were successfully retrieved.
+// Keys not found by the batch function (or for which replaceFn returned ErrNotFound) are omitted from the map,
+// and are not reported as errors.
+// If retrieval of any key fails, GetMulti still waits for all other keys, and returns the error of the first failed key
+// in the order of keys, together with the values that succeeded.
+// Failed keys are omitted from the map, and are not cached unless WithNegativeCache is used, just like Get.
+func (c *cache[K, V]) GetMulti(ctx context.Context, keys []K) (map[K]V, error) {
+	keys = c.normalizeKeys(keys)
+	// Record time as soon as GetMulti is called *before acquiring the lock* - this maximizes the reuse of values
+	calledAt := c.now()
+	values := make(map[K]V, len(keys))
+	waits := make(map[K]*call[V])
+	failed := make(map[K]error)
+	var loadKeys []K
+	var loadCalls []*call[V]
+	var events []hitEvent[K]
+
+	c.mu.Lock()
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if _, ok := waits[key]; ok {
+			continue
+		}
+		if _, ok := failed[key]; ok {
+			continue
+		}
+
+		val, ok := c.values.Get(key)
+		// value exists and is fresh
+		if ok && val.isFresh(calledAt, val.freshFor) {
+			if c.isAging(val, calledAt) {
+				c.refreshInBackground(ctx, key, getOptions{})
+			}
+			c.stats.hits.Add(1)
+			if c.metrics != nil {
+				events = append(events, hitEvent[K]{key, hitEventHit})
+			}
+			values[key] = val.v
+			continue
+		}
+		// value exists and is stale - serve it stale while updating in the background
+		if ok && !c.noGracefulRefresh && !val.isExpired(calledAt, val.ttl) {
+			c.refreshInBackground(ctx, key, getOptions{})
+			c.stats.graceHits.Add(1)
+			if c.metrics != nil {
+				events = append(events, hitEvent[K]{key, hitEventGraceHit})
+			}
+			values[key] = val.v
+			continue
+		}
+
+		// retrieval of value recently failed - use the cached error
+		if err, ok := c.cachedError(key, calledAt); ok {
+			c.stats.errorHits.Add(1)
+			failed[key] = err
+			continue
+		}
+
+		// value doesn't exist or is expired - join the ongoing call, or start a new one
+		c.stats.misses.Add(1)
+		if c.metrics != nil {
+			events = append(events, hitEvent[K]{key, hitEventMiss})
+		}
+		if cl, ok := c.calls[key]; ok {
+			if !c.tryJoin(cl) {
+				failed[key] = ErrTooManyWaiters
+				continue
+			}
+			waits[key] = cl
+			continue
+		}
+		cl := c.newCall(key, getOptions{})
+		c.calls[key] = cl
+		waits[key] = cl
+		if c.batching() {
+			c.enqueueBatch(ctx, key, cl)
+			continue
+		}
+		loadKeys = append(loadKeys, key)
+		loadCalls = append(loadCalls, cl)
+	}
+	c.mu.Unlock()
+	c.notifyHitEvents(events)
+
+	// Make sure not to hold lock while waiting for values.
+	// Use context.WithoutCancel to match the behavior with Get.
+	if len(loadKeys) > 0 {
+		if c.batchFn != nil {
+			go c.setMulti(context.WithoutCancel(ctx), loadKeys, loadCalls)
+		} else {
+			for i := range loadKeys {
+				go c.set(context.WithoutCancel(ctx), loadCalls[i], loadKeys[i])
+			}
+		}
+	}
+
+	var firstErr error
+	for _, key := range keys {
+		if err, ok := failed[key]; ok {
+			if !errors.Is(err, ErrNotFound) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		cl, ok := waits[key]
+		if !ok {
+			continue
+		}
+		delete(waits, key) // duplicate keys
+		cl.Wait()
+
+		if cl.err == nil && c.strictCoalescing && !cl.val.isFresh(calledAt, cl.val.freshFor) {
+			// Strict request coalescing: the joined call is too old to be served - delegate to Get
+			v, err := c.Get(ctx, key)
+			if err == nil {
+				values[key] = v
+			} else if !errors.Is(err, ErrNotFound) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		switch {
+		case cl.err == nil:
+			values[key] = cl.val.v
+		case errors.Is(cl.err, ErrNotFound):
+		case firstErr == nil:
+			firstErr = cl.err
+		}
+	}
+	return values, firstErr
+}
+
+// GetMultiIfExists retrieves items for multiple keys at once, without triggering value replacements.
+// The returned map contains only the keys whose items exist and are not expired (stale items are included).
+//
+// Unlike calling GetIfExists for each key, GetMultiIfExists takes the lock only once, so that the returned items
+// are a consistent point-in-time snapshot. Stats are affected in the same way as GetIfExists for each distinct key.
+func (c *cache[K, V]) GetMultiIfExists(keys []K) map[K]V {
+	keys = c.normalizeKeys(keys)
+	// Record time as soon as GetMultiIfExists is called *before acquiring the lock* - this maximizes the reuse of values
+	calledAt := c.now()
+	values := make(map[K]V, len(keys))
+	seen := make(map[K]struct{}, len(keys))
+	var events []hitEvent[K]
+
+	c.mu.Lock()
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		val, ok := c.values.Get(key)
+		kind := hitEventMiss
+		switch {
+		case ok && val.isFresh(calledAt, val.freshFor):
+			c.stats.hits.Add(1)
+			kind = hitEventHit
+			values[key] = val.v
+		case ok && !val.isExpired(calledAt, val.ttl):
+			c.stats.graceHits.Add(1)
+			kind = hitEventGraceHit
+			values[key] = val.v
+		default:
+			c.stats.misses.Add(1)
+		}
+		if c.metrics != nil {
+			events = append(events, hitEvent[K]{key, kind})
+		}
+	}
+	c.mu.Unlock()
+	c.notifyHitEvents(events)
+	return values
+}
+
+// LoadAll loads items for all keys into the cache, waiting until all of them are retrieved.
+// Returns the error of the first failed key in the order of keys, or nil if all succeeded.
+// This is useful to warm up the cache before serving traffic, e.g. in readiness probes.
+//
+// Keys are retrieved just like GetMulti: fresh items are not retrieved again, ongoing retrievals are joined,
+// and the limit of WithMaxConcurrentReplacements applies. Keys not found are not reported as errors, either.
+//
+// If ctx is done before all keys are retrieved, LoadAll returns ctx.Err() without waiting for the rest of the
+// retrievals, which continue in the background and are stored in the cache as usual.
+func (c *cache[K, V]) LoadAll(ctx context.Context, keys []K) error {
+	return loadAll(ctx, keys, c.GetMulti)
+}
+
+// loadAll is the implementation of LoadAll, retrieving keys with getMulti.
+func loadAll[K comparable, V any](ctx context.Context, keys []K, getMulti func(ctx context.Context, keys []K) (map[K]V, error)) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := getMulti(ctx, keys)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pendingBatch is a batch collecting keys to be retrieved together. See WithBatchWindow.
+type pendingBatch[K comparable, V any] struct {
+	ctx   context.Context
+	keys  []K
+	calls []*call[V]
+}
+
+// batching reports whether keys should be collected into a pending batch before retrieval.
+func (c *cache[K, V]) batching() bool {
+	return c.batchFn != nil && c.batchWindow > 0
+}
+
+// enqueueBatch adds key to the pending batch, starting a new batch if there is none.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) enqueueBatch(ctx context.Context, key K, cl *call[V]) {
+	b := c.pending
+	if b == nil {
+		// Use context.WithoutCancel to match the behavior with Get.
+		b = &pendingBatch[K, V]{ctx: context.WithoutCancel(ctx)}
+		c.pending = b
+		time.AfterFunc(c.batchWindow, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.pending == b { // The batch may have already been flushed because it was full
+				c.flushBatch()
+			}
+		})
+	}
+	b.keys = append(b.keys, key)
+	b.calls = append(b.calls, cl)
+	if c.maxBatchSize > 0 && len(b.keys) >= c.maxBatchSize {
+		c.flushBatch()
+	}
+}
+
+// flushBatch starts retrieving the pending batch.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) flushBatch() {
+	b := c.pending
+	c.pending = nil
+	go c.setMulti(b.ctx, b.keys, b.calls)
+}
+
+// setMulti retrieves values for keys with a single call to the batch function.
+func (c *cache[K, V]) setMulti(ctx context.Context, keys []K, calls []*call[V]) {
+	// Record time *just before* batchFn() is called - see set for the reason.
+	c.acquireReplacement()
+	ctx, cancel := c.replacementContext(ctx)
+	defer cancel()
+	var created monoTime
+	var values map[K]V
+	var err error
+	start := time.Now()
+	attempts := c.retry(ctx, func() error {
+		created = c.now()
+		err = c.recovered(func() (fnErr error) {
+			values, fnErr = c.batchFn(ctx, keys)
+			return fnErr
+		})
+		return err
+	})
+	c.releaseReplacement()
+	skip := errors.Is(err, ErrSkipCache)
+	if skip {
+		err = nil
+	}
+
+	c.mu.Lock()
+	c.stats.replacements.Add(uint64(attempts))
+	for i, key := range keys {
+		cl := calls[i]
+		cl.val.created = created
+		if err != nil {
+			cl.err = err
+		} else if v, ok := values[key]; ok {
+			cl.val.v = v
+			c.applyTTLFn(key, &cl.val)
+		} else {
+			cl.err = ErrNotFound
+		}
+		if cl.hasDeadline {
+			cl.val.expireBy(cl.deadline)
+		}
+		c.recordLoad(key, created, cl.err)
+		if c.calls[key] == cl {
+			if cl.err == nil && cl.val.ttl >= 0 && !skip {
+				c.store(key, cl.val)
+				c.lendCall(key, cl)
+			} else if cl.err != nil {
+				c.storeError(key, cl.err, created)
+			}
+			delete(c.calls, key)
+		}
+	}
+	c.unlock()
+	for _, cl := range calls {
+		cl.Complete()
+	}
+	if c.metrics != nil || c.replacementCallback != nil {
+		d := time.Since(start)
+		for i, key := range keys {
+			c.onReplacement(key, calls[i].val.v, d, calls[i].err)
+		}
+	}
+}
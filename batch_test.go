@@ -0,0 +1,398 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBatched(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid batchFn", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewBatched[string, string](nil, 0, 0)
+		assert.ErrorIs(t, err, ErrNilBatchFn)
+		assert.ErrorIs(t, err, ErrNilReplaceFn)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		batchFn := func(ctx context.Context, keys []string) (map[string]string, error) { return nil, nil }
+		_, err := NewBatched[string, string](batchFn, 0, 0, WithLRUBackend(0))
+		assert.ErrorIs(t, err, ErrInvalidLRUCapacity)
+		_, err = NewBatched[string, string](batchFn, 0, 0, WithBatchWindow(-time.Second, 0))
+		assert.Error(t, err)
+		_, err = NewBatched[string, string](batchFn, 0, 0, WithBatchWindow(time.Second, -1))
+		assert.Error(t, err)
+	})
+}
+
+// TestCache_Get_Batched ensures (*Cache).Get works with batch function.
+func TestCache_Get_Batched(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+				atomic.AddInt64(&cnt, 1)
+				assert.Len(t, keys, 1)
+				if keys[0] == "missing" {
+					return map[string]string{}, nil
+				}
+				return map[string]string{keys[0]: "value-" + keys[0]}, nil
+			}
+			cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k1", v)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k1", v)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+
+			_, err = cache.Get(context.Background(), "missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = cache.Get(context.Background(), "missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+		})
+	}
+}
+
+// TestCache_Get_BatchWindow ensures concurrent (*Cache).Get calls within the batch window share a batch call.
+func TestCache_Get_BatchWindow(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var batches [][]string
+			batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+				mu.Lock()
+				sorted := append([]string(nil), keys...)
+				sort.Strings(sorted)
+				batches = append(batches, sorted)
+				mu.Unlock()
+
+				time.Sleep(250 * time.Millisecond)
+				values := make(map[string]string, len(keys))
+				for _, key := range keys {
+					if key != "missing" {
+						values[key] = "value-" + key
+					}
+				}
+				return values, nil
+			}
+			cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, append(c.cacheOpts, WithBatchWindow(100*time.Millisecond, 3))...)
+			assert.NoError(t, err)
+
+			t0 := time.Now()
+			var wg sync.WaitGroup
+			get := func(key string) {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), key)
+				if key == "missing" {
+					assert.ErrorIs(t, err, ErrNotFound)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, "value-"+key, v)
+				}
+			}
+			// t=0ms, 1st batch collects k1, k2, and missing (k1 is coalesced)
+			wg.Add(4)
+			go get("k1")
+			go get("k1")
+			go get("k2")
+			go get("missing")
+			wg.Wait()
+			// assert t=350ms
+			assert.InDelta(t, 350*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+
+			// t=350ms, 2nd batch is retrieved as soon as it is full, and 3rd batch after the window
+			t0 = time.Now()
+			wg.Add(4)
+			go get("k3")
+			go get("k4")
+			go get("k5")
+			time.Sleep(50 * time.Millisecond)
+			go get("k6")
+			wg.Wait()
+			// assert t=400ms
+			assert.InDelta(t, 400*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+
+			assert.Equal(t, [][]string{{"k1", "k2", "missing"}, {"k3", "k4", "k5"}, {"k6"}}, batches)
+		})
+	}
+}
+
+// TestCache_GetMulti_Batched ensures concurrent (*Cache).GetMulti calls for overlapping keys share a batch call.
+func TestCache_GetMulti_Batched(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var batches [][]string
+			batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+				mu.Lock()
+				sorted := append([]string(nil), keys...)
+				sort.Strings(sorted)
+				batches = append(batches, sorted)
+				mu.Unlock()
+
+				time.Sleep(500 * time.Millisecond)
+				values := make(map[string]string, len(keys))
+				for _, key := range keys {
+					if key != "missing" {
+						values[key] = "value-" + key
+					}
+				}
+				return values, nil
+			}
+			cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			t0 := time.Now()
+			var wg sync.WaitGroup
+			wg.Add(3)
+			// t=0ms, 1st call loads k1, k2, and k3
+			go func() {
+				defer wg.Done()
+				values, err := cache.GetMulti(context.Background(), []string{"k1", "k2", "k3", "missing"})
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]string{"k1": "value-k1", "k2": "value-k2", "k3": "value-k3"}, values)
+			}()
+			time.Sleep(100 * time.Millisecond)
+			// t=100ms, 2nd call joins the 1st call
+			go func() {
+				defer wg.Done()
+				values, err := cache.GetMulti(context.Background(), []string{"k2", "k3", "k2"})
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]string{"k2": "value-k2", "k3": "value-k3"}, values)
+			}()
+			// t=100ms, 3rd call joins the 1st call, and loads k4
+			go func() {
+				defer wg.Done()
+				values, err := cache.GetMulti(context.Background(), []string{"k3", "k4"})
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]string{"k3": "value-k3", "k4": "value-k4"}, values)
+			}()
+			wg.Wait()
+			// assert t=600ms
+			assert.InDelta(t, 600*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			assert.Equal(t, [][]string{{"k1", "k2", "k3", "missing"}, {"k4"}}, batches)
+
+			// Values are now cached
+			values, err := cache.GetMulti(context.Background(), []string{"k1", "k4"})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"k1": "value-k1", "k4": "value-k4"}, values)
+			assert.Len(t, batches, 2)
+		})
+	}
+}
+
+// TestCache_GetMulti_Error ensures (*Cache).GetMulti returns values that succeeded along with the error.
+func TestCache_GetMulti_Error(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			targetErr := errors.New("test error")
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				if key == "error" {
+					return "", targetErr
+				}
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			values, err := cache.GetMulti(context.Background(), []string{"k1", "error", "k2"})
+			assert.ErrorIs(t, err, targetErr)
+			assert.Equal(t, map[string]string{"k1": "value-k1", "k2": "value-k2"}, values)
+		})
+	}
+}
+
+// TestCache_GetMulti_Concurrent ensures (*Cache).GetMulti on a non-batched cache retrieves missing keys concurrently,
+// coalescing duplicate keys.
+func TestCache_GetMulti_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				time.Sleep(500 * time.Millisecond)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			t0 := time.Now()
+			values, err := cache.GetMulti(context.Background(), []string{"k1", "k2", "k1", "k3", "k2"})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"k1": "value-k1", "k2": "value-k2", "k3": "value-k3"}, values)
+			// assert t=500ms
+			assert.InDelta(t, 500*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			assert.EqualValues(t, 3, cnt)
+			assert.Equal(t, HitStats{0, 0, 3, 3, 0, 0}, cache.Stats().HitStats)
+		})
+	}
+}
+
+// TestCache_GetMulti_FirstError ensures (*Cache).GetMulti returns the error of the first failed key in the order of keys.
+func TestCache_GetMulti_FirstError(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			err1 := errors.New("error 1")
+			err2 := errors.New("error 2")
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				switch key {
+				case "error1":
+					time.Sleep(250 * time.Millisecond) // fails later in time, but earlier in order
+					return "", err1
+				case "error2":
+					return "", err2
+				case "missing":
+					return "", ErrNotFound
+				}
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			values, err := cache.GetMulti(context.Background(), []string{"k1", "missing", "error1", "error2", "k2"})
+			assert.ErrorIs(t, err, err1)
+			assert.NotErrorIs(t, err, err2)
+			assert.Equal(t, map[string]string{"k1": "value-k1", "k2": "value-k2"}, values)
+			assert.ElementsMatch(t, []string{"k1", "k2"}, cache.Keys())
+
+			values, err = cache.GetMulti(context.Background(), nil)
+			assert.NoError(t, err)
+			assert.NotNil(t, values)
+			assert.Empty(t, values)
+		})
+	}
+}
+
+// TestCache_LoadAll ensures (*Cache).LoadAll waits for all keys to be stored, and reports the first error.
+func TestCache_LoadAll(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			targetErr := errors.New("test error")
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				switch key {
+				case "error":
+					return "", targetErr
+				case "slow":
+					time.Sleep(500 * time.Millisecond)
+				}
+				time.Sleep(50 * time.Millisecond)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			err = cache.LoadAll(context.Background(), []string{"k1", "k2", "k1"})
+			assert.NoError(t, err)
+			for _, key := range []string{"k1", "k2"} {
+				v, ok := cache.GetIfExists(key)
+				assert.True(t, ok)
+				assert.Equal(t, "value-"+key, v)
+			}
+
+			err = cache.LoadAll(context.Background(), []string{"k3", "error"})
+			assert.ErrorIs(t, err, targetErr)
+			assert.True(t, cache.Contains("k3"))
+
+			// stops waiting when ctx is done, while the retrieval continues in the background
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err = cache.LoadAll(ctx, []string{"slow"})
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Eventually(t, func() bool { return cache.Contains("slow") }, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+// TestCache_GetMultiIfExists ensures (*Cache).GetMultiIfExists returns existing items without triggering replaceFn.
+func TestCache_GetMultiIfExists(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			cache, err := New[string, string](replaceFn, time.Minute, 2*time.Minute,
+				append(c.cacheOpts, WithClock(now), WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			assert.Empty(t, cache.GetMultiIfExists([]string{"k1", "k2"}))
+			_, _ = cache.Get(context.Background(), "k1")
+			// t=90s, k1 is stale, k2 is fresh
+			elapsed.Add(int64(90 * time.Second))
+			_, _ = cache.Get(context.Background(), "k2")
+			before := cache.Stats().HitStats
+			values := cache.GetMultiIfExists([]string{"k1", "k2", "k3", "k1"})
+			assert.Equal(t, map[string]string{"k1": "value-k1", "k2": "value-k2"}, values)
+			after := cache.Stats().HitStats
+			assert.EqualValues(t, 1, after.Hits-before.Hits)
+			assert.EqualValues(t, 1, after.GraceHits-before.GraceHits)
+			assert.EqualValues(t, 1, after.Misses-before.Misses)
+
+			// t=150s, k1 is expired, k2 is stale
+			elapsed.Add(int64(time.Minute))
+			values = cache.GetMultiIfExists([]string{"k1", "k2", "k3"})
+			assert.Equal(t, map[string]string{"k2": "value-k2"}, values)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+		})
+	}
+}
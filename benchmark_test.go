@@ -143,3 +143,35 @@ func BenchmarkCache_RealWorkLoad(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkCache_Single_Zipfian_Churn benchmarks evicting caches with high insert/evict churn,
+// reporting allocations.
+func BenchmarkCache_Single_Zipfian_Churn(b *testing.B) {
+	const (
+		size = 100
+		s    = 1.001
+		v    = 100
+	)
+
+	for _, c := range evictingCaches(size) {
+		c := c
+		b.Run(c.name, func(b *testing.B) {
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value", nil
+			}
+			cache, err := New[string, string](replaceFn, 1*time.Minute, 1*time.Minute, c.cacheOpts...)
+			if err != nil {
+				b.Error(err)
+			}
+
+			ctx := context.Background()
+			keys := newKeys(newZipfian(s, v, size*40), size*100)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = cache.Get(ctx, keys[i%(size*100)])
+			}
+			b.Log(cache.Stats())
+		})
+	}
+}
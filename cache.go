@@ -3,13 +3,20 @@ package sc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/motoki317/sc/internal/flight"
 )
 
 // replaceFunc is automatically called when value is not present or expired.
 // The cache makes sure that replaceFunc is always called once for the same key at the same time.
-// When replaceFunc returns an error, value will not be cached.
+// When replaceFunc returns an error, value will not be cached, and the error is returned as it is to all callers
+// waiting for the call. This includes context errors, so that a partial value is never cached.
 type replaceFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
 
 // NewMust is similar to New, but panics on error.
@@ -24,56 +31,294 @@ func NewMust[K comparable, V any](replaceFn replaceFunc[K, V], freshFor, ttl tim
 // New creates a new cache instance.
 // You can specify ttl longer than freshFor to achieve 'graceful cache replacement', where stale item is served via Get
 // while a single goroutine is launched in the background to retrieve a fresh item.
+//
+// The returned errors can be distinguished with errors.Is: ErrNilReplaceFn, ErrNegativeDuration, and
+// ErrFreshForExceedsTTL for the arguments, ErrCapacityRequired for bounded backends without capacity,
+// and ErrInvalidOption for the other invalid options.
 func New[K comparable, V any](replaceFn replaceFunc[K, V], freshFor, ttl time.Duration, options ...CacheOption) (*Cache[K, V], error) {
 	if replaceFn == nil {
-		return nil, errors.New("replaceFn cannot be nil")
+		return nil, ErrNilReplaceFn
 	}
 	if freshFor < 0 || ttl < 0 {
-		return nil, errors.New("freshFor and ttl needs to be non-negative")
+		return nil, ErrNegativeDuration
 	}
 	if freshFor > ttl {
-		return nil, errors.New("freshFor cannot be longer than ttl")
+		return nil, ErrFreshForExceedsTTL
 	}
 
 	config := defaultConfig(ttl)
 	for _, option := range options {
 		option(&config)
 	}
+	if config.maxConcurrentReplacements < 0 {
+		return nil, invalidOption("max concurrent replacements needs to be non-negative")
+	}
+	if config.proactiveThreshold < 0 || config.proactiveThreshold > 1 {
+		return nil, invalidOption("proactive threshold needs to be between 0 and 1")
+	}
+	if config.statsHistoryInterval != 0 || config.statsHistorySamples != 0 {
+		if config.statsHistoryInterval <= 0 || config.statsHistorySamples <= 0 {
+			return nil, invalidOption("stats history interval and samples need to be greater than 0")
+		}
+	}
+	if config.adaptiveCleanupMin != 0 || config.adaptiveCleanupMax != 0 {
+		if config.adaptiveCleanupMin <= 0 {
+			return nil, invalidOption("minimum adaptive cleanup interval needs to be greater than 0")
+		}
+		if config.adaptiveCleanupMin > config.adaptiveCleanupMax {
+			return nil, invalidOption("minimum adaptive cleanup interval cannot be longer than the maximum")
+		}
+	}
+	if config.cleanupJitter < 0 || config.cleanupJitter >= 1 {
+		return nil, invalidOption("cleanup jitter needs to be in [0, 1)")
+	}
+	if config.freshnessJitter < 0 || config.freshnessJitter >= 1 {
+		return nil, invalidOption("freshness jitter needs to be in [0, 1)")
+	}
+	if config.cleanupBatchSize < 0 {
+		return nil, invalidOption("cleanup batch size needs to be non-negative")
+	}
+	if config.retryAttempts < 0 {
+		return nil, invalidOption("retry attempts needs to be non-negative")
+	}
+	if config.replacementTimeout < 0 {
+		return nil, invalidOption("replacement timeout needs to be non-negative")
+	}
+	if config.maxWaitersPerKey < 0 {
+		return nil, invalidOption("max waiters per key needs to be non-negative")
+	}
+	if config.errTTL < 0 {
+		return nil, invalidOption("error ttl needs to be non-negative")
+	}
+	if config.batchWindow < 0 || config.maxBatchSize < 0 {
+		return nil, invalidOption("batch window and max batch size need to be non-negative")
+	}
+	if config.sizeThresholdHook != nil && config.sizeThreshold <= 0 {
+		return nil, invalidOption("size threshold needs to be greater than 0")
+	}
+	if config.shards != 0 {
+		return nil, invalidOption("shards can only be specified with NewSharded")
+	}
+
+	if config.backend == cacheBackendAuto {
+		switch {
+		case config.capacity <= 0:
+			config.backend, config.capacity = cacheBackendMap, 0
+		case config.evictionPolicy == EvictionPolicy2Q:
+			config.backend = cacheBackend2Q
+		case config.evictionPolicy == EvictionPolicyLRU:
+			config.backend = cacheBackendLRU
+		default:
+			return nil, invalidOption("unknown eviction policy")
+		}
+	}
 
 	var b backend[K, value[V]]
 	switch config.backend {
 	case cacheBackendMap:
 		if config.capacity < 0 {
-			return nil, errors.New("capacity needs to be non-negative for map cache")
+			return nil, invalidOption("capacity needs to be non-negative for map cache")
 		}
 		b = newMapBackend[K, value[V]](config.capacity)
 	case cacheBackendLRU:
 		if config.capacity <= 0 {
-			return nil, errors.New("capacity needs to be greater than 0 for LRU cache")
+			return nil, ErrInvalidLRUCapacity
 		}
 		b = newLRUBackend[K, value[V]](config.capacity)
 	case cacheBackend2Q:
 		if config.capacity <= 0 {
-			return nil, errors.New("capacity needs to be greater than 0 for 2Q cache")
+			return nil, ErrInvalid2QCapacity
 		}
 		b = new2QBackend[K, value[V]](config.capacity)
+	case cacheBackendFIFO:
+		if config.capacity <= 0 {
+			return nil, ErrInvalidFIFOCapacity
+		}
+		b = newFIFOBackend[K, value[V]](config.capacity)
+	case cacheBackendWeightedLRU:
+		if config.maxWeight <= 0 {
+			return nil, invalidOption("max weight needs to be greater than 0")
+		}
+		weigher, ok := config.weigher.(func(key K, v V) int64)
+		if !ok {
+			return nil, invalidOption("weigher needs to accept the key and value types of the cache")
+		}
+		b = newWeightedLRUBackend[K, V](config.maxWeight, weigher)
 	default:
-		return nil, errors.New("unknown cache backend")
+		return nil, invalidOption("unknown cache backend")
+	}
+
+	var durationFn func(key K) (freshFor, ttl time.Duration)
+	if config.durationFn != nil {
+		var ok bool
+		if durationFn, ok = config.durationFn.(func(key K) (freshFor, ttl time.Duration)); !ok {
+			return nil, invalidOption("duration function needs to accept the key type of the cache")
+		}
+	}
+	var ttlFn func(key K, v V) (freshFor, ttl time.Duration)
+	if config.ttlFn != nil {
+		var ok bool
+		if ttlFn, ok = config.ttlFn.(func(key K, v V) (freshFor, ttl time.Duration)); !ok {
+			return nil, invalidOption("ttl function needs to accept the key and value types of the cache")
+		}
+	}
+	var normalize func(key K) K
+	if config.keyNormalizer != nil {
+		var ok bool
+		if normalize, ok = config.keyNormalizer.(func(key K) K); !ok {
+			return nil, invalidOption("key normalizer needs to accept the key type of the cache")
+		}
+	}
+	var onEvict func(key K, v V)
+	if config.onEvict != nil {
+		var ok bool
+		if onEvict, ok = config.onEvict.(func(key K, v V)); !ok {
+			return nil, invalidOption("eviction callback needs to accept the key and value types of the cache")
+		}
+	}
+	var onExpire func(key K, v V)
+	if config.onExpire != nil {
+		var ok bool
+		if onExpire, ok = config.onExpire.(func(key K, v V)); !ok {
+			return nil, invalidOption("expiration callback needs to accept the key and value types of the cache")
+		}
+	}
+	var onRemove func(key K, v V, reason RemovalReason)
+	if config.onRemove != nil {
+		var ok bool
+		if onRemove, ok = config.onRemove.(func(key K, v V, reason RemovalReason)); !ok {
+			return nil, invalidOption("removal callback needs to accept the key and value types of the cache")
+		}
+	}
+	var onReplacement func(key K, v V, err error, d time.Duration)
+	if config.onReplacement != nil {
+		var ok bool
+		if onReplacement, ok = config.onReplacement.(func(key K, v V, err error, d time.Duration)); !ok {
+			return nil, invalidOption("replacement callback needs to accept the key and value types of the cache")
+		}
+	}
+	var publishInvalidation func(key K)
+	if config.publishInvalidation != nil {
+		var ok bool
+		if publishInvalidation, ok = config.publishInvalidation.(func(key K)); !ok {
+			return nil, invalidOption("invalidation publisher needs to accept the key type of the cache")
+		}
+	}
+	var metrics MetricsHook[K]
+	if config.metrics != nil {
+		var ok bool
+		if metrics, ok = config.metrics.(MetricsHook[K]); !ok {
+			return nil, invalidOption("metrics hook needs to accept the key type of the cache")
+		}
+	}
+	var tier *lowerTier[K, V]
+	if config.lowerTier != nil {
+		t, ok := config.lowerTier.(lowerTier[K, V])
+		if !ok {
+			return nil, invalidOption("lower tier functions need to accept the key and value types of the cache")
+		}
+		if t.get == nil || t.set == nil {
+			return nil, invalidOption("lower tier functions cannot be nil")
+		}
+		tier = &t
+		replaceFn = tier.wrap(replaceFn)
+	}
+	var negatives map[K]negative
+	if config.errTTL > 0 {
+		negatives = make(map[K]negative)
+	}
+	var dispose func(v V)
+	if config.dispose != nil {
+		var ok bool
+		if dispose, ok = config.dispose.(func(v V)); !ok {
+			return nil, invalidOption("dispose function needs to accept the value type of the cache")
+		}
+	}
+
+	var leases map[K]*lease[V]
+	if dispose != nil {
+		leases = make(map[K]*lease[V])
+	}
+
+	var loads map[K]loadCounts
+	if config.trackFailureRates {
+		loads = make(map[K]loadCounts)
+	}
+	var history *statsHistory
+	if config.statsHistorySamples > 0 {
+		history = newStatsHistory(config.statsHistorySamples)
+	}
+
+	var replacements chan struct{}
+	if config.maxConcurrentReplacements > 0 {
+		replacements = make(chan struct{}, config.maxConcurrentReplacements)
 	}
 
 	c := &Cache[K, V]{
 		cache: &cache[K, V]{
-			values:           b,
-			calls:            make(map[K]*call[V]),
-			fn:               replaceFn,
-			freshFor:         freshFor,
-			ttl:              ttl,
-			strictCoalescing: config.enableStrictCoalescing,
+			values:              b,
+			backend:             config.backend,
+			readOnlyGet:         config.backend == cacheBackendMap,
+			calls:               make(map[K]*call[V]),
+			fn:                  replaceFn,
+			freshFor:            freshFor,
+			ttl:                 ttl,
+			proactiveThreshold:  config.proactiveThreshold,
+			strictCoalescing:    config.enableStrictCoalescing,
+			noGracefulRefresh:   config.noGracefulRefresh,
+			noExpiration:        config.noExpiration,
+			replacements:        replacements,
+			maxWaiters:          config.maxWaitersPerKey,
+			retryAttempts:       config.retryAttempts,
+			retryBackoff:        config.retryBackoff,
+			replacementTimeout:  config.replacementTimeout,
+			recoverPanics:       config.recoverPanics,
+			cleanupBatchSize:    config.cleanupBatchSize,
+			freshnessJitter:     config.freshnessJitter,
+			backgroundContext:   config.backgroundContext,
+			clock:               config.clock,
+			history:             history,
+			batchWindow:         config.batchWindow,
+			maxBatchSize:        config.maxBatchSize,
+			durationFn:          durationFn,
+			ttlFn:               ttlFn,
+			normalize:           normalize,
+			negatives:           negatives,
+			errTTL:              config.errTTL,
+			dispose:             dispose,
+			leases:              leases,
+			metrics:             metrics,
+			lowerTier:           tier,
+			onEvict:             onEvict,
+			onExpire:            onExpire,
+			onRemove:            onRemove,
+			replacementCallback: onReplacement,
+			publishInvalidation: publishInvalidation,
+			loads:               loads,
+			sizeThreshold:       config.sizeThreshold,
+			sizeThresholdHook:   config.sizeThresholdHook,
 		},
 	}
 
+	b.SetEvictCallback(c.evicted)
+	if config.statsDisabled {
+		c.stats.disable()
+	}
+	if config.replacementPercentiles {
+		c.timers.histogram = &durationHistogram{}
+	}
+
+	// Background goroutines hold reference to cache, not Cache - see cleaner for the reason.
 	if config.cleanupInterval > 0 {
-		startCleaner(c, config.cleanupInterval)
+		c.stops = append(c.stops, startCleaner(c.cache, config).stop)
+		c.hasCleaner = true
+	}
+	c.done = config.done()
+	if config.statsHistorySamples > 0 {
+		c.stops = append(c.stops, startStatsRecorder(c.cache, config).stop)
+	}
+	if len(c.stops) > 0 {
+		runtime.SetFinalizer(c, (*Cache[K, V]).Close)
 	}
 
 	return c, nil
@@ -82,23 +327,143 @@ func New[K comparable, V any](replaceFn replaceFunc[K, V], freshFor, ttl time.Du
 // Cache represents a single cache instance.
 // All methods are safe to be called from multiple goroutines.
 //
-// Notice that Cache doesn't have Set(key K, value V) method - this is intentional. Users are expected to delegate
-// the cache replacement logic to Cache by simply calling Get.
+// Users are expected to delegate the cache replacement logic to Cache by simply calling Get.
+// Set is provided only for write-through patterns, where the caller already has the latest value at hand.
 type Cache[K comparable, V any] struct {
 	*cache[K, V]
 	// Embedding must be a pointer to cache, otherwise finalizer is not run.
 	// See cleaner doc for the reason Cache and cache is separate.
+
+	hasCleaner bool
+	// done is closed when the context given by WithContext is done. nil if not given.
+	done <-chan struct{}
+	// stops stop the background goroutines.
+	stops     []func()
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+// HasCleaner reports whether a cleaner goroutine is running for the cache. See WithCleanupInterval.
+//
+// The cleaner runs from the creation of the cache until the cache is closed, the context given by WithContext is done,
+// or the cache is garbage collected.
+func (c *Cache[K, V]) HasCleaner() bool {
+	if !c.hasCleaner || c.closed.Load() {
+		return false
+	}
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close stops the background goroutines of the cache, such as the cleaner.
+//
+// These goroutines are stopped anyway when the cache is garbage collected, but Close stops them deterministically,
+// which is useful when caches are created and discarded dynamically.
+// The cache is still usable after Close, just without the background goroutines:
+// expired items are no longer cleaned up, and the stats history is no longer recorded.
+// Calling Close more than once is safe.
+func (c *Cache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		for _, stop := range c.stops {
+			stop()
+		}
+	})
 }
 
 // cache is an internal cache instance.
 type cache[K comparable, V any] struct {
-	values           backend[K, value[V]]
-	calls            map[K]*call[V]
-	mu               sync.Mutex // mu protects values and calls
-	fn               replaceFunc[K, V]
-	freshFor, ttl    time.Duration
-	strictCoalescing bool
-	stats            HitStats
+	values  backend[K, value[V]]
+	backend cacheBackendType // backend is the type of values, never cacheBackendAuto
+	calls   map[K]*call[V]
+	mu      sync.RWMutex // mu protects values and calls
+	fn      replaceFunc[K, V]
+	batchFn batchReplaceFunc[K, V] // batchFn is non-nil if the cache was created with NewBatched
+	// batchWindow and maxBatchSize configure the pending batch. See WithBatchWindow.
+	batchWindow   time.Duration
+	maxBatchSize  int
+	pending       *pendingBatch[K, V] // pending is the batch collecting keys, nil if none
+	freshFor, ttl time.Duration
+	// durationFn determines freshFor and ttl per key, nil if not configured. See WithDurationFn.
+	durationFn func(key K) (freshFor, ttl time.Duration)
+	// ttlFn determines freshFor and ttl per value, nil if not configured. See WithTTLFunc.
+	ttlFn func(key K, v V) (freshFor, ttl time.Duration)
+	// normalize normalizes keys given to the methods, nil if not configured. See WithKeyNormalizer.
+	normalize func(key K) K
+	// proactiveThreshold is the fraction of freshFor after which fresh values are proactively updated
+	// in the background. 0 if proactive update is disabled.
+	proactiveThreshold float64
+	strictCoalescing   bool
+	noGracefulRefresh  bool // noGracefulRefresh is true if stale values are never served. See WithNoGracefulRefresh.
+	noExpiration       bool // noExpiration is true if values never expire. See WithNoExpiration.
+	// replacements is a semaphore limiting the number of concurrent replaceFn calls.
+	// nil if the number is not limited.
+	replacements chan struct{}
+	// maxWaiters is the maximum number of callers joining a single call, 0 if not limited.
+	maxWaiters int
+	// retryAttempts and retryBackoff configure retries of replaceFn. See WithRetry.
+	retryAttempts int
+	retryBackoff  func(attempt int) time.Duration
+	// replacementTimeout is the timeout of a single retrieval, 0 if not limited. See WithReplacementTimeout.
+	replacementTimeout time.Duration
+	// recoverPanics is true if panics in replaceFn are converted into errors. See WithRecoverReplaceFn.
+	recoverPanics bool
+	// cleanupBatchSize is the maximum number of items scanned at once by the cleaner, 0 if not limited.
+	// See WithCleanupBatchSize.
+	cleanupBatchSize int
+	// freshnessJitter is the maximum fraction of freshFor randomly subtracted from stored values, 0 if disabled.
+	// See WithFreshnessJitter.
+	freshnessJitter float64
+	// backgroundContext derives the context of background refreshes, nil if not configured. See WithBackgroundContext.
+	backgroundContext func(parent context.Context) context.Context
+	stats             hitCounters
+	timers            replacementTimers
+	// clock returns the current time, nil if not configured. See WithClock.
+	clock func() time.Time
+	// readOnlyGet is true if the backend's Get does not modify the backend, enabling the read lock fast path in Get.
+	readOnlyGet bool
+	history     *statsHistory // history is nil if stats history is disabled
+	// stored is closed when a value is stored next time, waking up goroutines waiting for it.
+	// nil if no goroutine is waiting.
+	stored chan struct{}
+	// negatives holds cached errors, nil if negative caching is disabled. See WithNegativeCache.
+	negatives map[K]negative
+	errTTL    time.Duration
+	// dispose is called for each value leaving the cache, nil if not configured. See WithDispose.
+	dispose func(v V)
+	// onEvict is called for each value evicted by the backend, nil if not configured. See WithEvictionCallback.
+	onEvict func(key K, v V)
+	// onExpire is called for each expired value removed by the cleaner, nil if not configured.
+	// See WithExpirationCallback.
+	onExpire func(key K, v V)
+	// onRemove is called for each value leaving the cache, nil if not configured. See WithRemovalCallback.
+	onRemove func(key K, v V, reason RemovalReason)
+	// replacementCallback is called after each call to replaceFn, nil if not configured. See WithReplacementCallback.
+	replacementCallback func(key K, v V, err error, d time.Duration)
+	// publishInvalidation is called for each forgotten key, nil if not configured. See WithInvalidationPublisher.
+	publishInvalidation func(key K)
+	// leases holds the borrow counts of stored values, nil if dispose is not configured. See GetRef.
+	leases map[K]*lease[V]
+	// metrics receives events of the cache, nil if not configured. See WithMetricsHook.
+	metrics MetricsHook[K]
+	// lowerTier is consulted before replaceFn (already wrapped in fn), nil if not configured. See WithLowerTier.
+	lowerTier *lowerTier[K, V]
+	// evictedKeys holds keys evicted while holding mu, to be notified to metrics after releasing mu.
+	evictedKeys []K
+	// disposals holds values removed while holding mu, to be disposed after releasing mu.
+	disposals []V
+	// removals holds values removed while holding mu, to be notified to onRemove after releasing mu.
+	removals []removal[K, V]
+	// loads is the number of loads per key. nil if failure rates are not tracked.
+	loads map[K]loadCounts
+	// sizeThresholdHook is called when the size crosses sizeThreshold upward. nil if not configured.
+	sizeThresholdHook func(size int)
+	sizeThreshold     int
+	sizeHookRunning   atomic.Bool
 }
 
 // Get retrieves an item. If an item is not in the cache, it automatically loads a new item into the cache.
@@ -107,129 +472,704 @@ type cache[K comparable, V any] struct {
 //
 // The cache prevents 'cache stampede' problem by coalescing multiple requests to the same key.
 func (c *cache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	v, _, err := c.get(ctx, key, getOptions{}, nil)
+	return v, err
+}
+
+// GetWithStatus is similar to Get, but also reports how the item was served. See Status.
+//
+// Unlike comparing Stats before and after calling Get, the status is accurate even when the cache is accessed
+// concurrently.
+func (c *cache[K, V]) GetWithStatus(ctx context.Context, key K) (V, Status, error) {
+	return c.get(ctx, key, getOptions{}, nil)
+}
+
+// GetFresh is similar to Get, but never returns a stale item, applying the semantics of EnableStrictCoalescing to this
+// call regardless of the cache-wide setting.
+//
+// If the item is stale, GetFresh waits for a new item instead of serving the stale one.
+// If GetFresh joins an ongoing replaceFn call which started too early to retrieve a fresh item, GetFresh retries.
+// This is useful for consistency-critical reads in a cache which otherwise allows graceful replacement.
+func (c *cache[K, V]) GetFresh(ctx context.Context, key K) (V, error) {
+	v, _, err := c.get(ctx, key, getOptions{strict: true}, nil)
+	return v, err
+}
+
+// TryGetOrError is similar to Get, but fails fast instead of queueing when the cache is saturated.
+//
+// If the item needs to be loaded synchronously and the number of ongoing replaceFn calls has reached the limit set by
+// WithMaxConcurrentReplacements, TryGetOrError immediately returns ErrBusy.
+// Ongoing calls for the same key are joined just like Get, since they do not require another replaceFn call.
+// Without WithMaxConcurrentReplacements, TryGetOrError behaves exactly like Get.
+func (c *cache[K, V]) TryGetOrError(ctx context.Context, key K) (V, error) {
+	v, _, err := c.get(ctx, key, getOptions{failIfBusy: true}, nil)
+	return v, err
+}
+
+// GetWithTTL is similar to Get, but overrides freshFor and ttl of the cache for the item retrieved by this call.
+// This is useful when some keys are much more volatile than others in the same cache.
+//
+// Just like Get, a cached item is returned as long as it is fresh (or stale) by its own freshFor and ttl.
+// The overridden durations apply only when this call retrieves a new item, either synchronously or in the background.
+// Items later retrieved by other calls such as Get use the durations of the cache again.
+//
+// freshFor and ttl need to be non-negative, and freshFor cannot be longer than ttl.
+// Otherwise, an error matching ErrNegativeDuration or ErrFreshForExceedsTTL is returned, just like New.
+func (c *cache[K, V]) GetWithTTL(ctx context.Context, key K, freshFor, ttl time.Duration) (V, error) {
+	if freshFor < 0 || ttl < 0 {
+		var zero V
+		return zero, fmt.Errorf("%w: got freshFor %v and ttl %v", ErrNegativeDuration, freshFor, ttl)
+	}
+	if freshFor > ttl {
+		var zero V
+		return zero, fmt.Errorf("%w: got freshFor %v and ttl %v", ErrFreshForExceedsTTL, freshFor, ttl)
+	}
+	v, _, err := c.get(ctx, key, getOptions{freshFor: freshFor, ttl: ttl, hasTTL: true}, nil)
+	return v, err
+}
+
+// GetWith is similar to Get, but retrieves the item with fn instead of replaceFn of the cache,
+// if this call retrieves a new item either synchronously or in the background.
+// The item is stored under key just like Get, and concurrent calls for the same key are coalesced as usual.
+// This is useful when an endpoint needs to load items differently (e.g. with extra fields), while sharing the cache.
+//
+// Note that the cache does not distinguish items retrieved by different functions.
+// Concurrent calls for the same key with different functions (including Get) are coalesced onto whichever call
+// started first, and cached items are returned regardless of the function which retrieved them.
+// The lower tier of the cache (see WithLowerTier) is consulted before fn, just like replaceFn.
+func (c *cache[K, V]) GetWith(ctx context.Context, key K, fn replaceFunc[K, V]) (V, error) {
+	if c.lowerTier != nil {
+		fn = c.lowerTier.wrap(fn)
+	}
+	v, _, err := c.get(ctx, key, getOptions{fn: fn}, nil)
+	return v, err
+}
+
+// GetWithOptions is similar to Get, but accepts per-call options.
+func (c *cache[K, V]) GetWithOptions(ctx context.Context, key K, options ...CallOption) (V, error) {
+	var opts getOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	v, _, err := c.get(ctx, key, opts, nil)
+	return v, err
+}
+
+// CallOption represents a single per-call option for (*Cache).GetWithOptions.
+// See other package-level functions which return CallOption for more details.
+type CallOption func(o *getOptions)
+
+// getOptions represents per-call options for get.
+type getOptions struct {
+	// failIfBusy makes get return ErrBusy instead of waiting for a replacement slot.
+	failIfBusy bool
+	// validUntil is the time by which values retrieved by the call must expire, if non-zero.
+	validUntil time.Time
+	// freshFor and ttl override the durations of values retrieved by the call, if hasTTL is true.
+	freshFor, ttl time.Duration
+	hasTTL        bool
+	// strict applies strict request coalescing to the call, and disables serving stale values. See GetFresh.
+	strict bool
+	// fn is the replaceFunc[K, V] overriding replaceFn of the cache for the call, if non-nil. See GetWith.
+	fn any
+}
+
+// WithCallValidUntil specifies that values retrieved by the call must not be served after t.
+//
+// If the call retrieves a new value (either synchronously or in the background), freshFor and ttl of the stored value
+// are shortened so that the value expires no later than t.
+// This is useful when the value is retrieved under a permission which is valid only until t,
+// such as request-scoped credentials.
+//
+// Note that the option has no effect if the call is served a cached value, or joins an ongoing call of another caller.
+func WithCallValidUntil(t time.Time) CallOption {
+	return func(o *getOptions) {
+		o.validUntil = t
+	}
+}
+
+// get retrieves an item with the per-call options.
+// If r is non-nil, the returned value is borrowed on behalf of r. See GetRef.
+func (c *cache[K, V]) get(ctx context.Context, key K, opts getOptions, r *ref[V]) (V, Status, error) {
+	key = c.normalizeKey(key)
 	// Record time as soon as Get is called *before acquiring the lock* - this maximizes the reuse of values
-	calledAt := monoTimeNow()
+	calledAt := c.now()
+
+	// Fast path: value exists and is fresh (and not aging) - only read lock is needed, allowing readers to scale
+	if c.readOnlyGet && r == nil {
+		c.mu.RLock()
+		val, ok := c.values.Get(key)
+		if ok && val.isFresh(calledAt, val.freshFor) && !c.isAging(val, calledAt) {
+			c.stats.hits.Add(1)
+			c.mu.RUnlock()
+			c.onHit(key)
+			return val.v, StatusHit, nil
+		}
+		c.mu.RUnlock()
+	}
+
 	c.mu.Lock()
 	val, ok := c.values.Get(key)
+	// waited is true if the caller has waited for an ongoing call before retrying. See strict request coalescing below.
+	var waited bool
 
 retry:
 	// value exists and is fresh - just return
-	if ok && val.isFresh(calledAt, c.freshFor) {
-		c.stats.Hits++
+	if ok && val.isFresh(calledAt, val.freshFor) {
+		// value is aging - proactively update in the background
+		if c.isAging(val, calledAt) {
+			c.refreshInBackground(ctx, key, opts)
+		}
+		c.stats.hits.Add(1)
+		c.lend(key, r)
 		c.mu.Unlock()
-		return val.v, nil
+		c.onHit(key)
+		return val.v, hitStatus(StatusHit, waited), nil
 	}
 
 	// value exists and is stale - serve it stale while updating in the background
-	if ok && !val.isExpired(calledAt, c.ttl) {
-		_, ok := c.calls[key]
-		if !ok {
-			cl := &call[V]{}
-			cl.wg.Add(1)
-			c.calls[key] = cl
-			go c.set(context.WithoutCancel(ctx), cl, key)
-		}
-		c.stats.GraceHits++
+	if ok && !opts.strict && !c.noGracefulRefresh && !val.isExpired(calledAt, val.ttl) {
+		c.refreshInBackground(ctx, key, opts)
+		c.stats.graceHits.Add(1)
+		c.lend(key, r)
 		c.mu.Unlock()
-		return val.v, nil
+		c.onGraceHit(key)
+		return val.v, hitStatus(StatusGraceHit, waited), nil
+	}
+
+	// retrieval of value recently failed - return the cached error
+	if err, ok := c.cachedError(key, calledAt); ok {
+		c.stats.errorHits.Add(1)
+		c.mu.Unlock()
+		var zero V
+		return zero, StatusErrorHit, err
 	}
 
 	// value doesn't exist or is expired, or is stale, and we need it fresh - sync update
-	c.stats.Misses++
+	c.stats.misses.Add(1)
 	cl, ok := c.calls[key]
 	if ok {
+		if !c.tryJoin(cl) {
+			c.mu.Unlock()
+			c.onMiss(key)
+			var zero V
+			return zero, StatusMiss, ErrTooManyWaiters
+		}
+		c.attach(r, cl)
 		c.mu.Unlock()
-		cl.wg.Wait() // make sure not to hold lock while waiting for value
-		if c.strictCoalescing && cl.err == nil {
+		c.onMiss(key)
+		cl.Wait() // make sure not to hold lock while waiting for value
+		if (c.strictCoalescing || opts.strict) && cl.err == nil {
 			// Strict request coalescing: compare with the time replaceFn was executed to make sure we are always
 			// serving fresh values when needed
-			val, ok = cl.val, true // make sure the variables are not shadowed
-			c.mu.Lock()            // careful with goto statement - retry is inside critical section
+			val, ok, waited = cl.val, true, true // make sure the variables are not shadowed
+			c.mu.Lock()                          // careful with goto statement - retry is inside critical section
 			goto retry
 		}
-		return cl.val.v, cl.err
+		return cl.val.v, StatusMiss, cl.err
 	}
 
-	cl = &call[V]{}
-	cl.wg.Add(1)
+	if opts.failIfBusy {
+		if !c.tryAcquireReplacement() {
+			c.mu.Unlock()
+			c.onMiss(key)
+			var zero V
+			return zero, StatusMiss, ErrBusy
+		}
+	}
+	cl = c.newCall(key, opts)
 	c.calls[key] = cl
+	c.attach(r, cl)
+	if c.batching() && !opts.failIfBusy && opts.fn == nil {
+		c.enqueueBatch(ctx, key, cl)
+		c.mu.Unlock()
+		c.onMiss(key)
+		cl.Wait()
+		return cl.val.v, StatusMiss, cl.err
+	}
 	c.mu.Unlock()
+	c.onMiss(key)
 
 	// Make sure not to hold lock while waiting for value.
 	// Use context.WithoutCancel to match the behavior with background fetching.
-	c.set(context.WithoutCancel(ctx), cl, key)
-	return cl.val.v, cl.err
+	if opts.failIfBusy {
+		c.setAcquired(context.WithoutCancel(ctx), cl, key)
+	} else if err := c.setCancelable(ctx, cl, key); err != nil {
+		var zero V
+		return zero, StatusMiss, err
+	}
+	return cl.val.v, StatusMiss, cl.err
 }
 
 // GetIfExists retrieves an item without triggering value replacements.
 //
 // This method doesn't wait for value replacement to finish, even if there is an ongoing one.
+// Just like Get, absent keys are counted as misses in Stats - use Peek instead to probe the cache
+// (e.g. for speculative prefetching) without skewing the hit ratio.
 func (c *cache[K, V]) GetIfExists(key K) (v V, ok bool) {
+	v, state := c.GetIfExistsDetailed(key)
+	return v, state != EntryMissing
+}
+
+// GetIfExistsDetailed is similar to GetIfExists, but also reports whether the item is fresh or stale.
+// This is useful to tell the users that the shown data may be outdated.
+//
+// Just like GetIfExists, this method never triggers value replacements, and affects Stats in the same way.
+func (c *cache[K, V]) GetIfExistsDetailed(key K) (v V, state EntryState) {
+	key = c.normalizeKey(key)
 	// Record time as soon as Get is called *before acquiring the lock* - this maximizes the reuse of values
-	calledAt := monoTimeNow()
+	calledAt := c.now()
 	c.mu.Lock()
-	defer c.mu.Unlock()
 	val, ok := c.values.Get(key)
+	c.mu.Unlock()
 
 	// value exists (includes stale values)
-	if ok && !val.isExpired(calledAt, c.ttl) {
-		if val.isFresh(calledAt, c.freshFor) {
-			c.stats.Hits++
-		} else {
-			c.stats.GraceHits++
+	if ok && !val.isExpired(calledAt, val.ttl) {
+		if val.isFresh(calledAt, val.freshFor) {
+			c.stats.hits.Add(1)
+			c.onHit(key)
+			return val.v, EntryFresh
 		}
-		return val.v, true
+		c.stats.graceHits.Add(1)
+		c.onGraceHit(key)
+		return val.v, EntryStale
 	}
 
 	// value doesn't exist, or is expired
-	c.stats.Misses++
-	return val.v, false
+	c.stats.misses.Add(1)
+	c.onMiss(key)
+	return val.v, EntryMissing
+}
+
+// TryGet retrieves an item without waiting for value replacements, just like GetIfExists.
+// Unlike GetIfExists, TryGet also triggers a value replacement in the background just like Notify,
+// if the item does not exist or is stale, so that subsequent calls are likely to find a fresh item.
+//
+// This is useful for latency-critical paths which can tolerate a miss, but never block on replaceFn.
+// TryGet affects Stats in the same way as GetIfExists.
+func (c *cache[K, V]) TryGet(ctx context.Context, key K) (v V, ok bool) {
+	key = c.normalizeKey(key)
+	// Record time as soon as TryGet is called *before acquiring the lock* - this maximizes the reuse of values
+	calledAt := c.now()
+	c.mu.Lock()
+	val, ok := c.values.Get(key)
+
+	// value exists and is fresh
+	if ok && val.isFresh(calledAt, val.freshFor) {
+		if c.isAging(val, calledAt) {
+			c.refreshInBackground(ctx, key, getOptions{})
+		}
+		c.mu.Unlock()
+		c.stats.hits.Add(1)
+		c.onHit(key)
+		return val.v, true
+	}
+	// value exists and is stale
+	if ok && !c.noGracefulRefresh && !val.isExpired(calledAt, val.ttl) {
+		c.refreshInBackground(ctx, key, getOptions{})
+		c.mu.Unlock()
+		c.stats.graceHits.Add(1)
+		c.onGraceHit(key)
+		return val.v, true
+	}
+
+	// value doesn't exist or is expired (or is stale, and we never serve it) - retrieve it in the background,
+	// unless retrieval of value recently failed
+	if _, failed := c.cachedError(key, calledAt); !failed {
+		c.refreshInBackground(ctx, key, getOptions{})
+	}
+	c.mu.Unlock()
+	c.stats.misses.Add(1)
+	c.onMiss(key)
+	return v, false
+}
+
+// GetOrDefault retrieves an item without triggering value replacements, returning def if the item does not exist
+// or is expired.
+//
+// Just like GetIfExists, this method never waits for value replacement, and affects Stats in the same way.
+func (c *cache[K, V]) GetOrDefault(key K, def V) V {
+	if v, ok := c.GetIfExists(key); ok {
+		return v
+	}
+	return def
+}
+
+// Peek retrieves an item if it exists in the cache and is not expired, without triggering value replacements.
+//
+// Unlike GetIfExists, Peek does not affect Stats or the recent usage of the item, and is not reported to the
+// MetricsHook given by WithMetricsHook.
+func (c *cache[K, V]) Peek(key K) (v V, ok bool) {
+	key = c.normalizeKey(key)
+	c.mu.RLock()
+	val, ok := c.values.Peek(key)
+	c.mu.RUnlock()
+	if !ok || val.isExpired(c.now(), val.ttl) {
+		return v, false
+	}
+	return val.v, true
+}
+
+// Contains reports whether an item for key exists in the cache and is not expired.
+// Stale items are also reported, since they can still be served by Get.
+// Expired items are not reported even if they have not been cleaned up yet.
+//
+// Just like Peek, Contains never triggers value replacements, and does not affect Stats or the recent usage of the item.
+func (c *cache[K, V]) Contains(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// GetMostRecent retrieves the item stored in the cache regardless of its freshness, together with its age.
+// Even expired items are returned, as long as they have not been cleaned up or evicted yet.
+//
+// GetMostRecent never triggers value replacements, and does not affect Stats or the recent usage of the item.
+// This is useful to show the last known value, e.g. while the data source is unavailable.
+func (c *cache[K, V]) GetMostRecent(key K) (v V, age time.Duration, ok bool) {
+	key = c.normalizeKey(key)
+	c.mu.RLock()
+	val, ok := c.values.Peek(key)
+	c.mu.RUnlock()
+	if !ok {
+		return v, 0, false
+	}
+	return val.v, time.Duration(c.now() - val.created), true
 }
 
 // Notify instructs the cache to retrieve value for key if value does not exist or is stale, in a non-blocking manner.
+// Values which are aging (see WithProactiveThreshold) are also retrieved.
 func (c *cache[K, V]) Notify(ctx context.Context, key K) {
-	// Record time as soon as Get is called *before acquiring the lock* - this maximizes the reuse of values
-	calledAt := monoTimeNow()
+	// Record time as soon as Notify is called *before acquiring the lock* - this maximizes the reuse of values
+	calledAt := c.now()
 	c.mu.Lock()
+	c.notify(ctx, key, calledAt)
+	c.mu.Unlock()
+}
+
+// NotifyAll is the same as calling Notify for each key, but acquires the lock only once.
+// This is useful to prefetch the keys a request is going to need, so that subsequent Get calls are likely to hit.
+//
+// Limits on the number of concurrent replacements (see WithMaxConcurrentReplacements) still apply,
+// and values for the same key are retrieved only once, even if keys contain duplicates.
+func (c *cache[K, V]) NotifyAll(ctx context.Context, keys []K) {
+	calledAt := c.now()
+	c.mu.Lock()
+	for _, key := range keys {
+		c.notify(ctx, key, calledAt)
+	}
+	c.mu.Unlock()
+}
+
+// NotifyChan is the same as Notify, but returns a channel which is closed when the triggered cache replacement,
+// or the already ongoing one for key, completes regardless of its result.
+// If no replacement is needed (e.g. the value is fresh), the returned channel is already closed.
+//
+// This is useful to trigger replacements for several keys at once, then wait for all of them.
+func (c *cache[K, V]) NotifyChan(ctx context.Context, key K) <-chan struct{} {
+	calledAt := c.now()
+	ch := make(chan struct{})
+	c.mu.Lock()
+	cl := c.notify(ctx, key, calledAt)
+	c.mu.Unlock()
+	if cl == nil {
+		close(ch)
+		return ch
+	}
+	go func() {
+		cl.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
+// notify is the implementation of Notify. Returns the triggered or ongoing call, or nil if no replacement is needed.
+// c.mu must be held.
+func (c *cache[K, V]) notify(ctx context.Context, key K, calledAt monoTime) *call[V] {
+	key = c.normalizeKey(key)
 	val, ok := c.values.Get(key)
 
-	// value exists and is fresh - do nothing
-	if ok && val.isFresh(calledAt, c.freshFor) {
-		c.mu.Unlock()
-		return
+	// value exists and is fresh (and not aging) - do nothing
+	if ok && val.isFresh(calledAt, val.freshFor) && !c.isAging(val, calledAt) {
+		return nil
+	}
+	// value doesn't exist or is expired, and retrieval of value recently failed - do nothing
+	if _, failed := c.cachedError(key, calledAt); failed && (!ok || val.isExpired(calledAt, val.ttl)) {
+		return nil
 	}
 
 	// value exists and is stale, or value doesn't exist - launch goroutine to update in the background
-	_, ok = c.calls[key]
-	if !ok {
-		cl := &call[V]{}
-		cl.wg.Add(1)
-		c.calls[key] = cl
-		go c.set(context.WithoutCancel(ctx), cl, key)
+	c.refreshInBackground(ctx, key, getOptions{})
+	return c.calls[key]
+}
+
+// WaitFresh waits until a fresh value for key is in the cache, or until ctx is done.
+// Returns ctx.Err() if ctx is done before that.
+//
+// WaitFresh itself never triggers value replacements - it is useful to wait for replacements triggered by Notify,
+// or by another goroutine. Failed replacements are ignored, and WaitFresh continues to wait for a next one.
+func (c *cache[K, V]) WaitFresh(ctx context.Context, key K) error {
+	key = c.normalizeKey(key)
+	for {
+		c.mu.Lock()
+		val, ok := c.values.Get(key)
+		if ok && val.isFresh(c.now(), val.freshFor) {
+			c.mu.Unlock()
+			return nil
+		}
+		if c.stored == nil {
+			c.stored = make(chan struct{})
+		}
+		stored := c.stored
+		c.mu.Unlock()
+
+		select {
+		case <-stored:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Refresh retrieves a new value for key and stores it, regardless of the freshness of the current value.
+// Returns the new value, or an error as it is if replaceFn returns an error.
+//
+// Unlike Forget followed by Get, the current value continues to be served to other callers until the new value is
+// stored. If there is an ongoing cache replacement for key, Refresh waits for it instead of calling replaceFn again.
+// Note that the ongoing replacement may have started before Refresh was called.
+func (c *cache[K, V]) Refresh(ctx context.Context, key K) (V, error) {
+	key = c.normalizeKey(key)
+	c.mu.Lock()
+	if cl, ok := c.calls[key]; ok {
+		if !c.tryJoin(cl) {
+			c.mu.Unlock()
+			var zero V
+			return zero, ErrTooManyWaiters
+		}
+		c.mu.Unlock()
+		cl.Wait()
+		return cl.val.v, cl.err
+	}
+
+	cl := c.newCall(key, getOptions{})
+	c.calls[key] = cl
+	if c.batching() {
+		c.enqueueBatch(ctx, key, cl)
+		c.mu.Unlock()
+		cl.Wait()
+		return cl.val.v, cl.err
 	}
 	c.mu.Unlock()
+
+	if err := c.setCancelable(ctx, cl, key); err != nil {
+		var zero V
+		return zero, err
+	}
+	return cl.val.v, cl.err
+}
+
+// GetAndForget retrieves an item and deletes it from the cache at once, so that the item is served to a single caller.
+// This is useful to consume one-shot items, such as tokens.
+//
+// If a fresh item is in the cache, it is deleted and returned while holding the lock, so that no other caller is
+// served the same item. Otherwise, a stale item (if any) is deleted, and a new item is retrieved with replaceFn.
+// The new item is returned only to this caller, and is never stored in the cache.
+// Therefore, GetAndForget never joins ongoing cache replacements of other callers such as Get, and other callers
+// never join the replacement of GetAndForget. Ongoing replacements of other callers are kept intact: their results
+// are stored in the cache as usual, and may be consumed by a later GetAndForget.
+//
+// Just like Get, a cached error (see WithNegativeCache) is returned as it is.
+// Unlike Forget, the invalidation is not published (see WithInvalidationPublisher).
+//
+// The ownership of the returned item passes to the caller: a fresh item consumed from the cache is never disposed
+// (see WithDispose), even if it is still borrowed by GetRef. A stale item deleted by GetAndForget is disposed as usual.
+func (c *cache[K, V]) GetAndForget(ctx context.Context, key K) (V, error) {
+	key = c.normalizeKey(key)
+	// Record time as soon as GetAndForget is called *before acquiring the lock* - this maximizes the reuse of values
+	calledAt := c.now()
+	c.mu.Lock()
+	val, ok := c.values.Get(key)
+
+	// value exists and is fresh - consume it
+	if ok && val.isFresh(calledAt, val.freshFor) {
+		c.consume(key)
+		c.stats.hits.Add(1)
+		c.unlock()
+		c.onHit(key)
+		return val.v, nil
+	}
+
+	// retrieval of value recently failed - return the cached error
+	if err, failed := c.cachedError(key, calledAt); failed {
+		c.stats.errorHits.Add(1)
+		c.mu.Unlock()
+		var zero V
+		return zero, err
+	}
+
+	// value doesn't exist, or is stale - retrieve a new value only for this caller, without registering the call
+	if ok {
+		c.delete(key)
+	}
+	c.stats.misses.Add(1)
+	cl := c.newCall(key, getOptions{})
+	c.unlock()
+	c.onMiss(key)
+
+	if err := c.setCancelable(ctx, cl, key); err != nil {
+		var zero V
+		return zero, err
+	}
+	return cl.val.v, cl.err
+}
+
+// Set stores the value for key, as if it was just retrieved by replaceFn.
+//
+// Any ongoing cache replacement for key is detached from the cache: its result will not overwrite the value set by
+// this method, although callers already waiting for it still receive its result.
+// Prefer Get (and Forget on update) when possible - Set is intended for write-through patterns, where the caller
+// has just written the value to the data-store and already has the latest value at hand.
+//
+// With strict coalescing enabled, callers waiting for a detached replacement may find its result too old, and initiate
+// another replacement instead of returning the value set by this method.
+func (c *cache[K, V]) Set(key K, v V) {
+	key = c.normalizeKey(key)
+	val := value[V]{
+		v:       v,
+		created: c.now(),
+	}
+	val.freshFor, val.ttl = c.durations(key)
+	c.applyTTLFn(key, &val)
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.store(key, val)
+	c.unlock()
+}
+
+// RegisterExternalLoad registers that a value for key is being retrieved outside the cache,
+// such as by another process holding a distributed lock for key.
+//
+// Until commit is called, Get calls which need to retrieve the value wait for the external retrieval,
+// instead of calling replaceFn themselves. Calling commit with the result of the external retrieval stores the value
+// (or the error if WithNegativeCache is used) just like replaceFn returned it, and wakes up the waiting callers.
+// The value is considered to be retrieved at the time RegisterExternalLoad is called.
+//
+// Any ongoing cache replacement for key is detached from the cache, just like Set.
+// The caller must call commit exactly once, otherwise the waiting callers wait forever. Later calls to commit are ignored.
+func (c *cache[K, V]) RegisterExternalLoad(key K) (commit func(v V, err error)) {
+	key = c.normalizeKey(key)
+	calledAt := c.now()
+	c.mu.Lock()
+	cl := c.newCall(key, getOptions{})
+	cl.val.created = calledAt
+	c.calls[key] = cl
+	c.mu.Unlock()
+
+	var once sync.Once
+	return func(v V, err error) {
+		once.Do(func() {
+			cl.val.v, cl.err = v, err
+			if cl.err == nil {
+				c.applyTTLFn(key, &cl.val)
+			}
+			c.mu.Lock()
+			if c.calls[key] == cl {
+				if cl.err == nil {
+					c.store(key, cl.val)
+					c.lendCall(key, cl)
+				} else {
+					c.storeError(key, cl.err, cl.val.created)
+				}
+				delete(c.calls, key)
+			}
+			c.unlock()
+			cl.Complete()
+		})
+	}
 }
 
 // Forget instructs the cache to forget about the key.
 // Corresponding item will be deleted, ongoing cache replacement results (if any) will not be added to the cache,
 // and any future Get calls will immediately retrieve a new item.
 func (c *cache[K, V]) Forget(key K) {
+	key = c.normalizeKey(key)
+	c.forget(key)
+	if c.publishInvalidation != nil {
+		c.publishInvalidation(key)
+	}
+}
+
+// forget forgets about the key without publishing the invalidation.
+func (c *cache[K, V]) forget(key K) {
 	c.mu.Lock()
 	delete(c.calls, key)
-	c.values.Delete(key)
-	c.mu.Unlock()
+	delete(c.negatives, key)
+	c.delete(key)
+	c.unlock()
+}
+
+// ForgetAll instructs the cache to Forget about all the given keys.
+//
+// Unlike calling Forget for each key, ForgetAll takes the lock only once, so that concurrent Get calls never observe
+// only a part of the keys forgotten.
+func (c *cache[K, V]) ForgetAll(keys []K) {
+	keys = c.normalizeKeys(keys)
+	c.mu.Lock()
+	for _, key := range keys {
+		delete(c.calls, key)
+		delete(c.negatives, key)
+		c.delete(key)
+	}
+	c.unlock()
+	if c.publishInvalidation != nil {
+		for _, key := range keys {
+			c.publishInvalidation(key)
+		}
+	}
+}
+
+// ForgetIfOlderThan instructs the cache to Forget about the key, only if the stored item was retrieved before t.
+// Reports whether the item was forgotten.
+//
+// This is useful to invalidate an item based on a possibly outdated observation made at t,
+// without deleting a newer item which may have been stored in the meantime.
+// If the item is forgotten, any ongoing cache replacement and cached error (see WithNegativeCache) are also forgotten.
+func (c *cache[K, V]) ForgetIfOlderThan(key K, t time.Time) bool {
+	key = c.normalizeKey(key)
+	threshold := monoTime(t.Sub(t0))
+	c.mu.Lock()
+	defer c.unlock()
+	val, ok := c.values.Peek(key)
+	if !ok || val.created >= threshold {
+		return false
+	}
+	delete(c.calls, key)
+	delete(c.negatives, key)
+	c.delete(key)
+	return true
 }
 
 // ForgetIf instructs the cache to Forget about all keys that match the predicate.
+//
+// Just like Forget, results of ongoing cache replacements for the matched keys will not be added to the cache,
+// even if the replacements finish after ForgetIf returns. Callers waiting for these replacements still receive
+// their results. Note that the predicate is evaluated only once for each key at the time of the call:
+// replacements started after ForgetIf returns are stored as usual.
 func (c *cache[K, V]) ForgetIf(predicate func(key K) bool) {
+	predicate, publish := c.invalidations(predicate)
+	defer publish()
 	c.mu.Lock()
 	for key := range c.calls {
 		if predicate(key) {
 			delete(c.calls, key)
 		}
 	}
-	c.values.DeleteIf(func(key K, _ value[V]) bool { return predicate(key) })
-	c.mu.Unlock()
+	for key := range c.negatives {
+		if predicate(key) {
+			delete(c.negatives, key)
+		}
+	}
+	c.deleteIf(RemovalDeleted, func(key K, _ value[V]) bool { return predicate(key) })
+	c.unlock()
 }
 
 // Purge instructs the cache to Forget about all keys.
@@ -241,34 +1181,496 @@ func (c *cache[K, V]) Purge() {
 	for key := range c.calls {
 		delete(c.calls, key)
 	}
-	c.values.Purge()
-	c.mu.Unlock()
+	c.purge()
+	clear(c.negatives)
+	if c.loads != nil {
+		clear(c.loads)
+	}
+	c.unlock()
 }
 
-func (c *cache[K, V]) set(ctx context.Context, cl *call[V], key K) {
-	// Record time *just before* fn() is called - this maximizes the reuse of values.
-	// It is a mistake to set created after fn finishes, otherwise Get may incorrectly return expired values as fresh.
-	cl.val.created = monoTimeNow()
-	cl.val.v, cl.err = c.fn(ctx, key)
+// Flush deletes all items stored in the cache, while keeping ongoing cache replacements.
+//
+// Unlike Purge, ongoing cache replacements are not interrupted: their results are stored in the cache as usual.
+// This is useful to force retrieval of new items when the stored items are known to be stale,
+// without wasting the retrievals already in progress.
+// Cached errors (see WithNegativeCache) are also deleted, while Stats and FailureRates are kept intact.
+func (c *cache[K, V]) Flush() {
+	c.mu.Lock()
+	c.purge()
+	clear(c.negatives)
+	c.unlock()
+}
+
+// newCall creates a new call, configured with the per-call options.
+func (c *cache[K, V]) newCall(key K, opts getOptions) *call[V] {
+	cl := &call[V]{Call: flight.NewCall()}
+	cl.val.freshFor, cl.val.ttl = c.durations(key)
+	if opts.hasTTL {
+		cl.val.freshFor, cl.val.ttl = opts.freshFor, opts.ttl
+	}
+	if !opts.validUntil.IsZero() {
+		cl.deadline = monoTime(opts.validUntil.Sub(t0))
+		cl.hasDeadline = true
+	}
+	cl.fn = opts.fn
+	return cl
+}
+
+// durations returns freshFor and ttl of values for key. See WithDurationFn.
+func (c *cache[K, V]) durations(key K) (freshFor, ttl time.Duration) {
+	if c.noExpiration {
+		return forever, forever
+	}
+	if c.durationFn != nil {
+		freshFor, ttl = c.durationFn(key)
+		if (freshFor != 0 || ttl != 0) && 0 <= freshFor && freshFor <= ttl {
+			return freshFor, ttl
+		}
+	}
+	return c.freshFor, c.ttl
+}
+
+// refreshInBackground launches a goroutine to update the value for key, if there is no ongoing call for key.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) refreshInBackground(ctx context.Context, key K, opts getOptions) {
+	if _, ok := c.calls[key]; ok {
+		return
+	}
+	cl := c.newCall(key, opts)
+	c.calls[key] = cl
+	if c.batching() && opts.fn == nil {
+		c.enqueueBatch(ctx, key, cl)
+		return
+	}
+	go c.set(c.detach(ctx), cl, key)
+}
+
+// detach derives the context of a background refresh from ctx. See WithBackgroundContext.
+func (c *cache[K, V]) detach(ctx context.Context) context.Context {
+	if c.backgroundContext != nil {
+		return c.backgroundContext(ctx)
+	}
+	return context.WithoutCancel(ctx)
+}
+
+// now returns the current time of the cache. See WithClock.
+func (c *cache[K, V]) now() monoTime {
+	if c.clock == nil {
+		return monoTimeNow()
+	}
+	return monoTime(c.clock().Sub(t0))
+}
+
+// applyTTLFn overrides the durations of val with the ones determined from the value itself. See WithTTLFunc.
+func (c *cache[K, V]) applyTTLFn(key K, val *value[V]) {
+	if c.ttlFn == nil {
+		return
+	}
+	freshFor, ttl := c.ttlFn(key, val.v)
+	if (freshFor != 0 || ttl != 0) && 0 <= freshFor && freshFor <= ttl {
+		val.freshFor, val.ttl = freshFor, ttl
+	}
+}
+
+// normalizeKey returns the key under which the item for key is stored. See WithKeyNormalizer.
+func (c *cache[K, V]) normalizeKey(key K) K {
+	if c.normalize == nil {
+		return key
+	}
+	return c.normalize(key)
+}
+
+// normalizeKeys is similar to normalizeKey, but for multiple keys. The given slice is not modified.
+func (c *cache[K, V]) normalizeKeys(keys []K) []K {
+	if c.normalize == nil {
+		return keys
+	}
+	normalized := make([]K, len(keys))
+	for i, key := range keys {
+		normalized[i] = c.normalize(key)
+	}
+	return normalized
+}
+
+// isAging reports whether the fresh value should be proactively updated. See WithProactiveThreshold.
+func (c *cache[K, V]) isAging(val value[V], now monoTime) bool {
+	return c.proactiveThreshold > 0 && val.freshFor != forever && !val.isFresh(now, time.Duration(c.proactiveThreshold*float64(val.freshFor)))
+}
+
+// Keys returns the keys of the items currently stored in the cache. Expired items are excluded.
+//
+// The order of the keys depends on the backend: unspecified for the map backend, from the most recently used to
+// the least recently used for the LRU backend, and frequently used keys first then recently used keys for 2Q backend.
+func (c *cache[K, V]) Keys() []K {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.now() // Record time after acquiring the lock to exclude as many expired items as possible
+	keys := make([]K, 0, c.values.Size())
+	c.values.Range(func(key K, value value[V]) bool {
+		if !value.isExpired(now, value.ttl) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys
+}
 
+// Range calls f sequentially for each item currently stored in the cache. Expired items are skipped.
+// If f returns false, Range stops the iteration.
+//
+// The iteration order is the same as Keys.
+//
+// Range holds the read lock of the cache during the iteration, so f must not call back into the cache.
+// Use RangeSorted if f needs to call other methods of the cache.
+func (c *cache[K, V]) Range(f func(key K, value V) bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.now() // Record time after acquiring the lock to exclude as many expired items as possible
+	c.values.Range(func(key K, value value[V]) bool {
+		if value.isExpired(now, value.ttl) {
+			return true
+		}
+		return f(key, value.v)
+	})
+}
+
+// Len returns the number of items currently stored in the cache.
+// This is a cheaper alternative to Stats().Size.
+//
+// Note that expired items are also counted until they are cleaned up.
+func (c *cache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.values.Size()
+}
+
+// Resize changes the capacity of the cache at runtime, evicting items if the cache is larger than the new capacity.
+// Evicted items are treated just like items evicted to make room for new items, e.g. counted in Stats.Evictions.
+//
+// Resize has no effect on the map backend, which has no capacity, and returns an error for the weighted backend
+// (see WithMaxWeight). For other backends, newCapacity needs to be greater than 0.
+func (c *cache[K, V]) Resize(newCapacity int) error {
+	switch c.backend {
+	case cacheBackendLRU:
+		if newCapacity <= 0 {
+			return ErrInvalidLRUCapacity
+		}
+	case cacheBackend2Q:
+		if newCapacity <= 0 {
+			return ErrInvalid2QCapacity
+		}
+	case cacheBackendFIFO:
+		if newCapacity <= 0 {
+			return ErrInvalidFIFOCapacity
+		}
+	case cacheBackendWeightedLRU:
+		return errors.New("weighted cache cannot be resized by the number of items")
+	}
 	c.mu.Lock()
-	c.stats.Replacements++
-	if c.calls[key] == cl {
+	c.values.Resize(newCapacity)
+	c.unlock()
+	return nil
+}
+
+func (c *cache[K, V]) set(ctx context.Context, cl *call[V], key K) {
+	c.acquireReplacement()
+	c.setAcquired(ctx, cl, key)
+}
+
+// setAcquired is similar to set, but expects the caller to have acquired a replacement slot.
+func (c *cache[K, V]) setAcquired(ctx context.Context, cl *call[V], key K) {
+	ctx, cancel := c.replacementContext(ctx)
+	defer cancel()
+	fn := c.fn
+	if cl.fn != nil {
+		fn = cl.fn.(replaceFunc[K, V])
+	}
+	start := time.Now()
+	var attempts int
+	cl.Run(func() {
+		attempts = c.retry(ctx, func() error {
+			// Record time *just before* fn() is called - this maximizes the reuse of values.
+			// It is a mistake to set created after fn finishes, otherwise Get may incorrectly return expired values as fresh.
+			cl.val.created = c.now()
+			cl.err = c.recovered(func() (err error) {
+				cl.val.v, err = fn(ctx, key)
+				return err
+			})
+			return cl.err
+		})
+	}, func(aborted error) {
+		c.releaseReplacement()
+		if aborted != nil {
+			// replaceFn panicked (without WithRecoverReplaceFn) or called runtime.Goexit - fail the waiting callers
+			var pe *flight.PanicError
+			if errors.As(aborted, &pe) {
+				aborted = &PanicError{Value: pe.Value, Stack: pe.Stack}
+			}
+			cl.err = aborted
+		}
+		skip := errors.Is(cl.err, ErrSkipCache)
+		if skip {
+			cl.err = nil
+		}
 		if cl.err == nil {
-			c.values.Set(key, cl.val)
+			c.applyTTLFn(key, &cl.val)
+		}
+		if cl.hasDeadline {
+			cl.val.expireBy(cl.deadline)
 		}
-		delete(c.calls, key) // this deletion needs to be inside 'if c.calls[key] == cl' block, because there may be a new ongoing call
+
+		c.mu.Lock()
+		c.stats.replacements.Add(uint64(attempts))
+		c.recordLoad(key, cl.val.created, cl.err)
+		if c.calls[key] == cl {
+			if cl.err == nil && cl.val.ttl >= 0 && !skip {
+				c.store(key, cl.val)
+				c.lendCall(key, cl)
+			} else if cl.err != nil && aborted == nil {
+				c.storeError(key, cl.err, cl.val.created)
+			}
+			delete(c.calls, key) // this deletion needs to be inside 'if c.calls[key] == cl' block, because there may be a new ongoing call
+		}
+		c.unlock()
+	})
+	c.onReplacement(key, cl.val.v, time.Since(start), cl.err)
+}
+
+// replacementContext returns the context passed to replaceFn, applying the timeout of WithReplacementTimeout.
+func (c *cache[K, V]) replacementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.replacementTimeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.replacementTimeout)
+}
+
+// setCancelable is similar to set, but stops waiting for a replacement slot when ctx is done, returning ctx.Err().
+// See WithMaxConcurrentReplacements.
+//
+// If other callers have joined cl in the meantime, cl is handed over to a background goroutine so that they still
+// receive the value. Otherwise, cl is abandoned without calling replaceFn.
+// Once a slot is acquired, replaceFn is called with context.WithoutCancel(ctx) to match the behavior with background
+// fetching.
+func (c *cache[K, V]) setCancelable(ctx context.Context, cl *call[V], key K) error {
+	if c.replacements == nil {
+		c.setAcquired(context.WithoutCancel(ctx), cl, key)
+		return nil
+	}
+	select {
+	case c.replacements <- struct{}{}:
+		c.setAcquired(context.WithoutCancel(ctx), cl, key)
+		return nil
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	if cl.waiters > 0 {
+		c.mu.Unlock()
+		go c.set(context.WithoutCancel(ctx), cl, key)
+		return ctx.Err()
+	}
+	cl.err = ctx.Err()
+	if c.calls[key] == cl {
+		delete(c.calls, key)
 	}
 	c.mu.Unlock()
-	cl.wg.Done()
+	cl.Complete()
+	return ctx.Err()
+}
+
+// store stores the value for key, waking up goroutines waiting for a value to be stored.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) store(key K, val value[V]) {
+	if c.negatives != nil {
+		delete(c.negatives, key)
+	}
+	if c.freshnessJitter > 0 && val.freshFor != forever {
+		val.freshFor -= time.Duration(rand.Float64() * c.freshnessJitter * float64(val.freshFor))
+	}
+	if c.tracksRemovals() {
+		if old, ok := c.values.Peek(key); ok {
+			c.removed(key, old, RemovalReplaced)
+		}
+	}
+	if c.sizeThresholdHook != nil {
+		before := c.values.Size()
+		c.values.Set(key, val)
+		if after := c.values.Size(); before < c.sizeThreshold && after >= c.sizeThreshold {
+			c.fireSizeThresholdHook(after)
+		}
+	} else {
+		c.values.Set(key, val)
+	}
+	if c.stored != nil {
+		close(c.stored)
+		c.stored = nil
+	}
+}
+
+// fireSizeThresholdHook calls the size threshold hook in the background, unless the previous call is still running.
+func (c *cache[K, V]) fireSizeThresholdHook(size int) {
+	if !c.sizeHookRunning.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.sizeHookRunning.Store(false)
+		c.sizeThresholdHook(size)
+	}()
+}
+
+// tryJoin counts a caller waiting for cl, reporting false if the number of waiters has reached the limit.
+// See WithMaxWaitersPerKey. c.mu must be held by the caller.
+func (c *cache[K, V]) tryJoin(cl *call[V]) bool {
+	if c.maxWaiters > 0 && cl.waiters >= c.maxWaiters {
+		return false
+	}
+	cl.waiters++
+	return true
+}
+
+// acquireReplacement acquires a replacement slot, waiting for one to be available if necessary.
+func (c *cache[K, V]) acquireReplacement() {
+	if c.replacements != nil {
+		c.replacements <- struct{}{}
+	}
+}
+
+// tryAcquireReplacement acquires a replacement slot if one is available without waiting.
+// Reports whether the slot was acquired.
+func (c *cache[K, V]) tryAcquireReplacement() bool {
+	if c.replacements == nil {
+		return true
+	}
+	select {
+	case c.replacements <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseReplacement releases a replacement slot.
+func (c *cache[K, V]) releaseReplacement() {
+	if c.replacements != nil {
+		<-c.replacements
+	}
+}
+
+// DeleteExpired deletes all expired items from the cache immediately, freeing memory.
+// Returns the number of deleted items.
+//
+// This performs the same O(n) scan as the background cleaner (see WithCleanupInterval), at a time of the caller's
+// choice. The scan is never incremental even with WithCleanupBatchSize.
+// This is useful for caches without the background cleaner, e.g. to reclaim memory after a big batch job.
+func (c *cache[K, V]) DeleteExpired() int {
+	removed, _ := c.cleanupAll()
+	return removed
+}
+
+// ForgetExpired is similar to DeleteExpired, but also forgets ongoing cache replacements whose results would be
+// expired as soon as they are stored, i.e. the calls started with WithCallValidUntil whose deadline has passed.
+// Returns the total number of deleted items and forgotten calls.
+//
+// Just like Forget, callers waiting for the forgotten calls still receive their results,
+// and subsequent calls for the keys start new replacements instead of joining them.
+// Completed calls never remain in the cache, so the other ongoing calls are kept intact.
+func (c *cache[K, V]) ForgetExpired() int {
+	var removed, scanned int
+	var expiredItems []expiredItem[K, V]
+
+	c.mu.Lock()
+	now := c.now()
+	for key, cl := range c.calls {
+		if cl.hasDeadline && cl.deadline < now {
+			delete(c.calls, key)
+			removed++
+		}
+	}
+	c.deleteIf(RemovalExpired, c.expiredPredicate(now, &removed, &scanned, &expiredItems))
+	c.pruneErrors(now)
+	c.pruneLoads(now)
+	c.unlock()
+
+	for _, item := range expiredItems {
+		c.onExpire(item.key, item.v)
+	}
+	return removed
 }
 
 // cleanup cleans up expired items from the cache, freeing memory.
-func (c *cache[K, V]) cleanup() {
+// Returns the number of removed items and the number of scanned items.
+func (c *cache[K, V]) cleanup() (removed, scanned int) {
+	if c.cleanupBatchSize > 0 {
+		return c.cleanupIncremental()
+	}
+	return c.cleanupAll()
+}
+
+// expiredItem is an item removed by cleanup, to be passed to the expiration callback after releasing the lock.
+type expiredItem[K comparable, V any] struct {
+	key K
+	v   V
+}
+
+// expiredPredicate returns the predicate which reports whether the item is expired at now.
+// Removed and scanned items are counted, and removed items are collected to expiredItems if needed.
+func (c *cache[K, V]) expiredPredicate(now monoTime, removed, scanned *int, expiredItems *[]expiredItem[K, V]) func(key K, value value[V]) bool {
+	return func(key K, value value[V]) bool {
+		*scanned++
+		if value.isExpired(now, value.ttl) {
+			*removed++
+			if c.onExpire != nil {
+				*expiredItems = append(*expiredItems, expiredItem[K, V]{key, value.v})
+			}
+			return true
+		}
+		return false
+	}
+}
+
+// cleanupAll cleans up all expired items at once while holding the lock.
+func (c *cache[K, V]) cleanupAll() (removed, scanned int) {
+	var expiredItems []expiredItem[K, V]
+
 	c.mu.Lock()
-	now := monoTimeNow() // Record time after acquiring the lock to maximize freeing of expired items
-	c.values.DeleteIf(func(key K, value value[V]) bool {
-		return value.isExpired(now, c.ttl)
-	})
-	c.mu.Unlock()
+	now := c.now() // Record time after acquiring the lock to maximize freeing of expired items
+	c.deleteIf(RemovalExpired, c.expiredPredicate(now, &removed, &scanned, &expiredItems))
+	c.pruneErrors(now)
+	c.pruneLoads(now)
+	c.unlock()
+
+	// Make sure not to hold lock while calling the callback
+	for _, item := range expiredItems {
+		c.onExpire(item.key, item.v)
+	}
+	return
+}
+
+// cleanupIncremental cleans up expired items in rounds of at most cleanupBatchSize items, releasing the lock
+// between the rounds. See WithCleanupBatchSize.
+func (c *cache[K, V]) cleanupIncremental() (removed, scanned int) {
+	c.mu.RLock()
+	size := c.values.Size()
+	c.mu.RUnlock()
+
+	for {
+		var expiredItems []expiredItem[K, V]
+		c.mu.Lock()
+		now := c.now()
+		_, wrapped := c.deleteIfN(c.cleanupBatchSize, RemovalExpired, c.expiredPredicate(now, &removed, &scanned, &expiredItems))
+		done := wrapped || scanned >= size
+		if done {
+			c.pruneErrors(now)
+			c.pruneLoads(now)
+		}
+		c.unlock()
+
+		for _, item := range expiredItems {
+			c.onExpire(item.key, item.v)
+		}
+		if done {
+			return
+		}
+	}
 }
@@ -3,8 +3,10 @@ package sc
 import (
 	"context"
 	"errors"
+	"fmt"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -61,35 +63,86 @@ func TestNew(t *testing.T) {
 		// A test case just to increase coverage to 100%
 		// Normal users should not be able to reach the "unknown cache backend" path
 		_, err := New[string, string](fn, 0, 0, func(c *cacheConfig) { c.backend = -1 })
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidOption)
 	})
 
 	t.Run("invalid replaceFn", func(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](nil, 0, 0)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrNilReplaceFn)
 	})
 
 	t.Run("invalid freshFor", func(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, -1, 0)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrNegativeDuration)
 	})
 
 	t.Run("invalid ttl", func(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 0, -1)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrNegativeDuration)
 	})
 
 	t.Run("invalid freshFor and ttl configuration", func(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 2*time.Minute, 1*time.Minute)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrFreshForExceedsTTL)
+	})
+
+	t.Run("invalid max concurrent replacements", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithMaxConcurrentReplacements(-1))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+	})
+
+	t.Run("invalid max waiters per key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithMaxWaitersPerKey(-1))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+	})
+
+	t.Run("invalid replacement timeout", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithReplacementTimeout(-1))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+	})
+
+	t.Run("invalid proactive threshold", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithProactiveThreshold(-0.1))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+		_, err = New[string, string](fn, 0, 0, WithProactiveThreshold(1.1))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+	})
+
+	t.Run("invalid adaptive cleanup min interval", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithAdaptiveCleanup(0, time.Second))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+	})
+
+	t.Run("invalid adaptive cleanup intervals", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithAdaptiveCleanup(2*time.Second, time.Second))
+		assert.ErrorIs(t, err, ErrInvalidOption)
+	})
+
+	t.Run("invalid size threshold", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithSizeThresholdHook(0, func(int) {}))
+		assert.ErrorIs(t, err, ErrInvalidOption)
 	})
 
 	t.Run("map cache", func(t *testing.T) {
@@ -106,7 +159,7 @@ func TestNew(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 0, 0, WithMapBackend(-1))
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidOption)
 	})
 
 	t.Run("map cache with capacity", func(t *testing.T) {
@@ -143,21 +196,22 @@ func TestNew(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 0, 0, WithLRUBackend(0))
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidLRUCapacity)
+		assert.ErrorIs(t, err, ErrCapacityRequired)
 	})
 
 	t.Run("LRU cache with invalid capacity", func(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 0, 0, WithLRUBackend(-1))
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidLRUCapacity)
 	})
 
 	t.Run("struct LRU needs capacity set", func(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 0, 0, WithLRUBackend(-1), EnableStrictCoalescing())
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidLRUCapacity)
 	})
 
 	t.Run("LRU cache", func(t *testing.T) {
@@ -184,21 +238,21 @@ func TestNew(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 0, 0, With2QBackend(0))
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalid2QCapacity)
 	})
 
 	t.Run("2Q cache with invalid capacity", func(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 0, 0, With2QBackend(-1))
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalid2QCapacity)
 	})
 
 	t.Run("struct 2Q needs capacity set", func(t *testing.T) {
 		t.Parallel()
 
 		_, err := New[string, string](fn, 0, 0, With2QBackend(0), EnableStrictCoalescing())
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalid2QCapacity)
 	})
 
 	t.Run("2Q cache", func(t *testing.T) {
@@ -220,6 +274,119 @@ func TestNew(t *testing.T) {
 		assert.IsType(t, &tq.Cache[string, value[string]]{}, c.values)
 		assert.True(t, c.strictCoalescing)
 	})
+
+	t.Run("capacity unbounded", func(t *testing.T) {
+		t.Parallel()
+
+		for _, capacity := range []int{0, -1} {
+			c, err := New[string, string](fn, 0, 0, WithCapacity(capacity))
+			assert.NoError(t, err)
+			assert.IsType(t, mapBackend[string, value[string]]{}, c.values)
+			assert.Equal(t, "map", c.BackendType())
+		}
+	})
+
+	t.Run("capacity bounded", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := New[string, string](fn, 0, 0, WithCapacity(10))
+		assert.NoError(t, err)
+		assert.IsType(t, &tq.Cache[string, value[string]]{}, c.values)
+		assert.Equal(t, 10, c.values.Capacity())
+		assert.Equal(t, "2q", c.BackendType())
+	})
+
+	t.Run("capacity bounded with policy", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := New[string, string](fn, 0, 0, WithEvictionPolicy(EvictionPolicyLRU), WithCapacity(10))
+		assert.NoError(t, err)
+		assert.IsType(t, &lru.Cache[string, value[string]]{}, c.values)
+		assert.Equal(t, 10, c.values.Capacity())
+		assert.Equal(t, "lru", c.BackendType())
+
+		c, err = New[string, string](fn, 0, 0, WithCapacity(10), WithEvictionPolicy(EvictionPolicy2Q))
+		assert.NoError(t, err)
+		assert.IsType(t, &tq.Cache[string, value[string]]{}, c.values)
+	})
+
+	t.Run("capacity with unknown policy", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithCapacity(10), WithEvictionPolicy(-1))
+		assert.Error(t, err)
+	})
+
+	t.Run("FIFO needs capacity set", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithFIFOBackend(0))
+		assert.ErrorIs(t, err, ErrInvalidFIFOCapacity)
+	})
+
+	t.Run("FIFO cache", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := New[string, string](fn, 0, 0, WithFIFOBackend(10))
+		assert.NoError(t, err)
+		assert.IsType(t, &lru.Cache[string, value[string]]{}, c.values)
+		assert.Equal(t, 10, c.values.Capacity())
+		assert.Equal(t, "fifo", c.BackendType())
+		assert.NoError(t, c.Resize(20))
+		assert.ErrorIs(t, c.Resize(0), ErrInvalidFIFOCapacity)
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := New[string, string](fn, 0, 0, WithMaxWeight(100, func(key string, v string) int64 { return int64(len(v)) }))
+		assert.NoError(t, err)
+		assert.IsType(t, &lru.Cache[string, value[string]]{}, c.values)
+		assert.Equal(t, "weighted-lru", c.BackendType())
+
+		_, err = New[string, string](fn, 0, 0, WithMaxWeight(0, func(key string, v string) int64 { return 0 }))
+		assert.Error(t, err)
+		_, err = New[string, string](fn, 0, 0, WithMaxWeight(100, func(key string, v int) int64 { return 0 }))
+		assert.Error(t, err)
+	})
+
+	t.Run("explicit backend overrides capacity", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := New[string, string](fn, 0, 0, WithCapacity(10), WithLRUBackend(20))
+		assert.NoError(t, err)
+		assert.IsType(t, &lru.Cache[string, value[string]]{}, c.values)
+		assert.Equal(t, 20, c.values.Capacity())
+	})
+}
+
+// TestCache_CapacityOne ensures that evicting caches with capacity of exactly 1 work correctly.
+func TestCache_CapacityOne(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range evictingCaches(1) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			for i, key := range []string{"k1", "k1", "k2", "k2", "k1", "k2"} {
+				v, err := cache.Get(context.Background(), key)
+				assert.NoError(t, err)
+				assert.Equal(t, "value-"+key, v)
+				assert.Equal(t, SizeStats{1, 1, 0}, cache.Stats().SizeStats, "i = %d", i)
+			}
+			// k1 and k2 are evicted by each other
+			assert.EqualValues(t, 4, atomic.LoadInt64(&cnt))
+		})
+	}
 }
 
 // TestCache_Get calls (*Cache).Get multiple times and ensures a value is reused.
@@ -324,8 +491,8 @@ func TestCache_Get_Async(t *testing.T) {
 	}
 }
 
-// TestCache_Get_Error ensures (*Cache).Get returns an error if replaceFn returns an error.
-func TestCache_Get_Error(t *testing.T) {
+// TestCache_Get_Proactive ensures that (*Cache).Get will trigger background fetch if an aging value is found.
+func TestCache_Get_Proactive(t *testing.T) {
 	t.Parallel()
 
 	for _, c := range allCaches(10) {
@@ -333,23 +500,58 @@ func TestCache_Get_Error(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
 
-			targetErr := errors.New("test error")
+			var cnt int64
 			replaceFn := func(ctx context.Context, key string) (string, error) {
-				assert.Equal(t, "k1", key)
-				return "", targetErr
+				n := atomic.AddInt64(&cnt, 1)
+				time.Sleep(100 * time.Millisecond)
+				return "result" + strconv.Itoa(int(n)), nil
 			}
-			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			cache, err := New[string, string](replaceFn, 500*time.Millisecond, 1*time.Second, append(c.cacheOpts, WithProactiveThreshold(0.5))...)
 			assert.NoError(t, err)
 
-			val, err := cache.Get(context.Background(), "k1")
-			assert.Zero(t, val)
-			assert.Error(t, err)
-			assert.Equal(t, targetErr, err)
+			// t=0ms, miss -> sync fetch
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result1", v)
+			assert.Equal(t, HitStats{0, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
+
+			// t=100ms, fresh (value created at t=0ms)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result1", v)
+			time.Sleep(50 * time.Millisecond)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			assert.Equal(t, HitStats{1, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
+
+			// t=400ms, aging -> fresh hit, and background fetch
+			time.Sleep(250 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result1", v)
+			time.Sleep(150 * time.Millisecond)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+			assert.Equal(t, HitStats{2, 0, 1, 2, 0, 0}, cache.Stats().HitStats)
+
+			// t=550ms, fresh (value created at t=400ms)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
+			assert.Equal(t, HitStats{3, 0, 1, 2, 0, 0}, cache.Stats().HitStats)
+
+			// t=1000ms, stale -> grace hit, and background fetch
+			time.Sleep(450 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
+			time.Sleep(150 * time.Millisecond)
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+			assert.Equal(t, HitStats{3, 1, 1, 3, 0, 0}, cache.Stats().HitStats)
 		})
 	}
 }
 
-func TestCache_GetIfExists(t *testing.T) {
+// TestCache_GetWithOptions_ValidUntil ensures values retrieved with WithCallValidUntil are not served after the deadline.
+func TestCache_GetWithOptions_ValidUntil(t *testing.T) {
 	t.Parallel()
 
 	for _, c := range allCaches(10) {
@@ -359,82 +561,42 @@ func TestCache_GetIfExists(t *testing.T) {
 
 			var cnt int64
 			replaceFn := func(ctx context.Context, key string) (string, error) {
-				t.Log("replaceFn triggered")
-				atomic.AddInt64(&cnt, 1)
-				return "result-" + key, nil
+				n := atomic.AddInt64(&cnt, 1)
+				return "result" + strconv.Itoa(int(n)), nil
 			}
-			cache, err := New[string, string](replaceFn, 500*time.Millisecond, 1*time.Second, c.cacheOpts...)
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
 			assert.NoError(t, err)
 
-			// Check empty
-			_, ok := cache.GetIfExists("k1")
-			assert.False(t, ok)
-			_, ok = cache.GetIfExists("k2")
-			assert.False(t, ok)
-			_, ok = cache.GetIfExists("k3")
-			assert.False(t, ok)
-			assert.EqualValues(t, 0, cnt)
-
-			// trigger value replacement
-			val, err := cache.Get(context.Background(), "k1")
-			assert.NoError(t, err)
-			assert.Equal(t, "result-k1", val)
-			assert.EqualValues(t, 1, cnt)
-			val, err = cache.Get(context.Background(), "k2")
+			// t=0ms, value retrieved under the deadline
+			v, err := cache.GetWithOptions(context.Background(), "k1", WithCallValidUntil(time.Now().Add(300*time.Millisecond)))
 			assert.NoError(t, err)
-			assert.Equal(t, "result-k2", val)
-			assert.EqualValues(t, 2, cnt)
-
-			// Check k1 and k2 are present
-			val, ok = cache.GetIfExists("k1")
-			assert.True(t, ok)
-			assert.Equal(t, "result-k1", val)
-			val, ok = cache.GetIfExists("k2")
-			assert.True(t, ok)
-			assert.Equal(t, "result-k2", val)
-			_, ok = cache.GetIfExists("k3")
-			assert.False(t, ok)
-			assert.EqualValues(t, 2, cnt)
-
-			// test graceful hit
-			time.Sleep(750 * time.Millisecond)
-			val, ok = cache.GetIfExists("k1")
-			assert.True(t, ok)
-			assert.Equal(t, "result-k1", val)
-			val, ok = cache.GetIfExists("k2")
-			assert.True(t, ok)
-			assert.Equal(t, "result-k2", val)
-			_, ok = cache.GetIfExists("k3")
-			assert.False(t, ok)
-			assert.EqualValues(t, 2, cnt)
+			assert.Equal(t, "result1", v)
 
-			// test forget
-			cache.Forget("k2")
+			// t=100ms, value is cached
+			time.Sleep(100 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result1", v)
 
-			val, ok = cache.GetIfExists("k1")
-			assert.True(t, ok)
-			assert.Equal(t, "result-k1", val)
-			_, ok = cache.GetIfExists("k2")
-			assert.False(t, ok)
-			_, ok = cache.GetIfExists("k3")
-			assert.False(t, ok)
-			assert.EqualValues(t, 2, cnt)
+			// t=400ms, value is expired
+			time.Sleep(300 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
 
-			// test expiration
-			time.Sleep(500 * time.Millisecond)
-			_, ok = cache.GetIfExists("k1")
-			assert.False(t, ok)
-			_, ok = cache.GetIfExists("k2")
-			assert.False(t, ok)
-			_, ok = cache.GetIfExists("k3")
-			assert.False(t, ok)
-			assert.EqualValues(t, 2, cnt)
+			// value retrieved under past deadline is not cached
+			v, err = cache.GetWithOptions(context.Background(), "k2", WithCallValidUntil(time.Now().Add(-time.Second)))
+			assert.NoError(t, err)
+			assert.Equal(t, "result3", v)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "result4", v)
 		})
 	}
 }
 
-// TestCache_Notify tests that (*Cache).Notify will replace the value in background.
-func TestCache_Notify(t *testing.T) {
+// TestCache_GetWithTTL ensures (*Cache).GetWithTTL overrides freshFor and ttl for the retrieved item.
+func TestCache_GetWithTTL(t *testing.T) {
 	t.Parallel()
 
 	for _, c := range allCaches(10) {
@@ -444,44 +606,1447 @@ func TestCache_Notify(t *testing.T) {
 
 			var cnt int64
 			replaceFn := func(ctx context.Context, key string) (string, error) {
-				assert.Equal(t, "k1", key)
-				atomic.AddInt64(&cnt, 1)
-				time.Sleep(500 * time.Millisecond)
-				return "result1", nil
+				n := atomic.AddInt64(&cnt, 1)
+				return "result" + strconv.Itoa(int(n)), nil
 			}
-			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
 			assert.NoError(t, err)
 
-			// Start test t=0ms
-			t0 := time.Now()
-
-			// Notify value retrieval - this should launch goroutine in background
-			cache.Notify(context.Background(), "k1")
-			// Test that value is still not here
-			_, ok := cache.GetIfExists("k1")
-			assert.False(t, ok)
+			_, err = cache.GetWithTTL(context.Background(), "k1", 2*time.Second, time.Second)
+			assert.ErrorIs(t, err, ErrFreshForExceedsTTL)
+			_, err = cache.GetWithTTL(context.Background(), "k1", -time.Second, time.Second)
+			assert.ErrorIs(t, err, ErrNegativeDuration)
 
-			time.Sleep(750 * time.Millisecond)
-			// t=750ms, value should be cached
-			// Check that both GetIfExists and Get returns value immediately
-			v, ok := cache.GetIfExists("k1")
-			assert.True(t, ok)
+			// t=0ms, k1 is retrieved with short durations, k2 with the cache defaults
+			v, err := cache.GetWithTTL(context.Background(), "k1", 100*time.Millisecond, 300*time.Millisecond)
+			assert.NoError(t, err)
 			assert.Equal(t, "result1", v)
-			assert.InDelta(t, 750*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
-			assert.EqualValues(t, 1, cnt)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
 
-			v, err = cache.Get(context.Background(), "k1")
+			// t=200ms, k1 is stale - serve stale while updating in the background with the overridden durations
+			time.Sleep(200 * time.Millisecond)
+			v, err = cache.GetWithTTL(context.Background(), "k1", 100*time.Millisecond, 300*time.Millisecond)
 			assert.NoError(t, err)
 			assert.Equal(t, "result1", v)
-			assert.InDelta(t, 750*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
-			assert.EqualValues(t, 1, cnt)
+			time.Sleep(50 * time.Millisecond)
+			assert.Equal(t, HitStats{0, 1, 2, 3, 0, 0}, cache.Stats().HitStats)
 
-			// t=750ms, notify once again - this should do *nothing*
-			cache.Notify(context.Background(), "k1")
+			// t=650ms, k1 is expired, k2 is still fresh
+			time.Sleep(400 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result4", v)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
 
-			time.Sleep(750 * time.Millisecond)
-			// t=1500ms, assert that value was replaced only once
-			assert.EqualValues(t, 1, cnt)
+			// k1 is now retrieved with the cache defaults
+			time.Sleep(400 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result4", v)
+		})
+	}
+}
+
+// TestCache_GetWith ensures (*Cache).GetWith retrieves items with the given function, storing them under the same key.
+func TestCache_GetWith(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt, withCnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			withFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&withCnt, 1)
+				time.Sleep(100 * time.Millisecond)
+				return "extra-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// Get joins the ongoing call of GetWith
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := cache.GetWith(context.Background(), "k1", withFn)
+				assert.NoError(t, err)
+				assert.Equal(t, "extra-k1", v)
+			}()
+			time.Sleep(50 * time.Millisecond)
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "extra-k1", v)
+			wg.Wait()
+
+			// cached items are returned regardless of the function
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "extra-k1", v)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k2", v)
+			v, err = cache.GetWith(context.Background(), "k2", withFn)
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k2", v)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			assert.EqualValues(t, 1, atomic.LoadInt64(&withCnt))
+		})
+	}
+}
+
+func TestCache_RecoverReplaceFn(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				if key == "panic" {
+					var m map[string]int
+					m[key]++ // nil map write
+				}
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, append(c.cacheOpts,
+				WithRecoverReplaceFn(), WithRetry(3, nil), WithNegativeCache(time.Minute))...)
+			assert.NoError(t, err)
+
+			_, err = cache.Get(context.Background(), "panic")
+			assert.ErrorIs(t, err, ErrReplacePanic)
+			var pe *PanicError
+			assert.ErrorAs(t, err, &pe)
+			assert.Contains(t, string(pe.Stack), "TestCache_RecoverReplaceFn")
+			assert.Contains(t, err.Error(), "assignment to entry in nil map")
+			// neither retried nor cached
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			assert.Equal(t, 0, cache.Len())
+			_, err = cache.Get(context.Background(), "panic")
+			assert.ErrorIs(t, err, ErrReplacePanic)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k1", v)
+		})
+	}
+
+	t.Run("panicked error is unwrapped", func(t *testing.T) {
+		t.Parallel()
+
+		targetErr := errors.New("test error")
+		replaceFn := func(ctx context.Context, key string) (string, error) {
+			panic(targetErr)
+		}
+		cache, err := New[string, string](replaceFn, time.Minute, time.Minute, WithRecoverReplaceFn())
+		assert.NoError(t, err)
+
+		_, err = cache.Get(context.Background(), "k1")
+		assert.ErrorIs(t, err, ErrReplacePanic)
+		assert.ErrorIs(t, err, targetErr)
+	})
+
+	t.Run("batched", func(t *testing.T) {
+		t.Parallel()
+
+		batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+			panic("test panic")
+		}
+		cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, WithRecoverReplaceFn())
+		assert.NoError(t, err)
+
+		_, err = cache.GetMulti(context.Background(), []string{"k1", "k2"})
+		assert.ErrorIs(t, err, ErrReplacePanic)
+		assert.Contains(t, err.Error(), "test panic")
+	})
+
+	t.Run("not recovered", func(t *testing.T) {
+		t.Parallel()
+
+		var cnt int64
+		release := make(chan struct{})
+		replaceFn := func(ctx context.Context, key string) (string, error) {
+			if atomic.AddInt64(&cnt, 1) == 1 {
+				<-release
+				panic("test panic")
+			}
+			return "value-" + key, nil
+		}
+		cache, err := New[string, string](replaceFn, time.Minute, time.Minute)
+		assert.NoError(t, err)
+
+		// the panic propagates to the caller which called replaceFn
+		panicked := make(chan any)
+		go func() {
+			defer func() {
+				panicked <- recover()
+			}()
+			_, _ = cache.Get(context.Background(), "k1")
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		// the waiting callers receive an error instead of waiting forever
+		errc := make(chan error)
+		go func() {
+			_, err := cache.Get(context.Background(), "k1")
+			errc <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		assert.Equal(t, "test panic", <-panicked)
+		err = <-errc
+		assert.ErrorIs(t, err, ErrReplacePanic)
+		assert.Contains(t, err.Error(), "test panic")
+
+		// the key is not stuck with the panicked call
+		v, err := cache.Get(context.Background(), "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, "value-k1", v)
+	})
+}
+
+func TestCache_SkipCache(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			var degraded atomic.Bool
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				if degraded.Load() {
+					return "fallback" + strconv.Itoa(int(n)), fmt.Errorf("degraded: %w", ErrSkipCache)
+				}
+				return "result" + strconv.Itoa(int(n)), nil
+			}
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, time.Minute, append(c.cacheOpts, WithRetry(3, nil), WithNegativeCache(time.Minute))...)
+			assert.NoError(t, err)
+
+			// the value is returned, but not cached
+			degraded.Store(true)
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "fallback1", v)
+			assert.False(t, cache.Contains("k1"))
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "fallback2", v)
+
+			degraded.Store(false)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result3", v)
+
+			// the stale value is kept if the background refresh skips caching
+			degraded.Store(true)
+			time.Sleep(75 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result3", v)
+			time.Sleep(25 * time.Millisecond)
+			v, _ = cache.Peek("k1")
+			assert.Equal(t, "result3", v)
+			assert.EqualValues(t, 4, cnt)
+		})
+	}
+
+	t.Run("batched", func(t *testing.T) {
+		t.Parallel()
+
+		batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+			values := make(map[string]string, len(keys))
+			for _, key := range keys {
+				values[key] = "fallback-" + key
+			}
+			return values, ErrSkipCache
+		}
+		cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute)
+		assert.NoError(t, err)
+
+		values, err := cache.GetMulti(context.Background(), []string{"k1", "k2"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"k1": "fallback-k1", "k2": "fallback-k2"}, values)
+		v, err := cache.Get(context.Background(), "k3")
+		assert.NoError(t, err)
+		assert.Equal(t, "fallback-k3", v)
+		assert.Empty(t, cache.Keys())
+	})
+}
+
+// TestCache_DurationFn ensures WithDurationFn determines freshFor and ttl per key.
+func TestCache_DurationFn(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				return "result" + strconv.Itoa(int(n)), nil
+			}
+			durationFn := func(key string) (freshFor, ttl time.Duration) {
+				switch key {
+				case "short":
+					return 100 * time.Millisecond, 100 * time.Millisecond
+				case "invalid":
+					return 200 * time.Millisecond, 100 * time.Millisecond
+				}
+				return 0, 0
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, append(c.cacheOpts, WithDurationFn(durationFn))...)
+			assert.NoError(t, err)
+
+			for _, key := range []string{"short", "invalid", "default"} {
+				_, err := cache.Get(context.Background(), key)
+				assert.NoError(t, err)
+			}
+			time.Sleep(200 * time.Millisecond)
+
+			// only the "short" key is expired
+			v, err := cache.Get(context.Background(), "short")
+			assert.NoError(t, err)
+			assert.Equal(t, "result4", v)
+			v, err = cache.Get(context.Background(), "invalid")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
+			v, err = cache.Get(context.Background(), "default")
+			assert.NoError(t, err)
+			assert.Equal(t, "result3", v)
+		})
+	}
+
+	t.Run("invalid type", func(t *testing.T) {
+		t.Parallel()
+
+		replaceFn := func(ctx context.Context, key string) (string, error) { return key, nil }
+		_, err := New[string, string](replaceFn, time.Minute, time.Minute, WithDurationFn(func(key int) (time.Duration, time.Duration) { return 0, 0 }))
+		assert.Error(t, err)
+	})
+}
+
+func TestCache_TTLFunc(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return key, nil // key is the max-age of the value in seconds
+			}
+			ttlFn := func(key string, v string) (freshFor, ttl time.Duration) {
+				maxAge, _ := strconv.Atoi(v)
+				return time.Duration(maxAge) * time.Second, 2 * time.Duration(maxAge) * time.Second
+			}
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithTTLFunc(ttlFn), WithClock(now), WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			for _, key := range []string{"10", "100", "0", "invalid"} {
+				_, err := cache.Get(context.Background(), key)
+				assert.NoError(t, err)
+			}
+			cache.Set("30", "30")
+
+			// t=15s, only "10" is stale
+			elapsed.Add(int64(15 * time.Second))
+			for key, want := range map[string]Status{"10": StatusGraceHit, "100": StatusHit, "0": StatusHit, "invalid": StatusHit, "30": StatusHit} {
+				_, status, err := cache.GetWithStatus(context.Background(), key)
+				assert.NoError(t, err)
+				assert.Equal(t, want, status, key)
+			}
+
+			// "10" is refreshed in the background
+			assert.Eventually(t, func() bool { return atomic.LoadInt64(&cnt) == 5 }, time.Second, 10*time.Millisecond)
+
+			// t=75s, values without valid max-age expire with the default ttl
+			elapsed.Add(int64(60 * time.Second))
+			assert.Equal(t, []string{"100"}, Filter(cache.Keys(), func(key string, _ int) bool { return cache.Contains(key) }))
+		})
+	}
+
+	t.Run("invalid type", func(t *testing.T) {
+		t.Parallel()
+
+		replaceFn := func(ctx context.Context, key string) (string, error) { return key, nil }
+		_, err := New[string, string](replaceFn, time.Minute, time.Minute, WithTTLFunc(func(key string, v int) (time.Duration, time.Duration) { return 0, 0 }))
+		assert.Error(t, err)
+	})
+}
+func TestCache_NoExpiration(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 100*time.Millisecond, 200*time.Millisecond,
+				append(c.cacheOpts, WithNoExpiration(), WithProactiveThreshold(0.5), WithCleanupInterval(50*time.Millisecond))...)
+			assert.NoError(t, err)
+
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", v)
+			cache.Set("k2", "set-k2")
+
+			// t=300ms, values are still fresh
+			time.Sleep(300 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", v)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "set-k2", v)
+			time.Sleep(50 * time.Millisecond)
+			assert.EqualValues(t, 1, cnt)
+			assert.Equal(t, HitStats{2, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
+			assert.ElementsMatch(t, []string{"k1", "k2"}, cache.Keys())
+
+			// durations given per call still apply
+			v, err = cache.GetWithTTL(context.Background(), "k3", 0, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k3", v)
+			time.Sleep(10 * time.Millisecond)
+			_, ok := cache.Peek("k3")
+			assert.False(t, ok)
+
+			// until forgotten
+			cache.Forget("k1")
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", v)
+			assert.EqualValues(t, 3, cnt)
+		})
+	}
+}
+
+// TestCache_Get_Error ensures (*Cache).Get returns an error if replaceFn returns an error.
+func TestCache_Get_Error(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			targetErr := errors.New("test error")
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				assert.Equal(t, "k1", key)
+				return "", targetErr
+			}
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			val, err := cache.Get(context.Background(), "k1")
+			assert.Zero(t, val)
+			assert.Error(t, err)
+			assert.Equal(t, targetErr, err)
+		})
+	}
+}
+
+func TestCache_GetIfExists(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				t.Log("replaceFn triggered")
+				atomic.AddInt64(&cnt, 1)
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 500*time.Millisecond, 1*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// Check empty
+			_, ok := cache.GetIfExists("k1")
+			assert.False(t, ok)
+			_, ok = cache.GetIfExists("k2")
+			assert.False(t, ok)
+			_, ok = cache.GetIfExists("k3")
+			assert.False(t, ok)
+			assert.EqualValues(t, 0, cnt)
+
+			// trigger value replacement
+			val, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", val)
+			assert.EqualValues(t, 1, cnt)
+			val, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k2", val)
+			assert.EqualValues(t, 2, cnt)
+
+			// Check k1 and k2 are present
+			val, ok = cache.GetIfExists("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "result-k1", val)
+			val, ok = cache.GetIfExists("k2")
+			assert.True(t, ok)
+			assert.Equal(t, "result-k2", val)
+			_, ok = cache.GetIfExists("k3")
+			assert.False(t, ok)
+			assert.EqualValues(t, 2, cnt)
+
+			// test graceful hit
+			time.Sleep(750 * time.Millisecond)
+			val, ok = cache.GetIfExists("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "result-k1", val)
+			val, ok = cache.GetIfExists("k2")
+			assert.True(t, ok)
+			assert.Equal(t, "result-k2", val)
+			_, ok = cache.GetIfExists("k3")
+			assert.False(t, ok)
+			assert.EqualValues(t, 2, cnt)
+
+			// test forget
+			cache.Forget("k2")
+
+			val, ok = cache.GetIfExists("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "result-k1", val)
+			_, ok = cache.GetIfExists("k2")
+			assert.False(t, ok)
+			_, ok = cache.GetIfExists("k3")
+			assert.False(t, ok)
+			assert.EqualValues(t, 2, cnt)
+
+			// test expiration
+			time.Sleep(500 * time.Millisecond)
+			_, ok = cache.GetIfExists("k1")
+			assert.False(t, ok)
+			_, ok = cache.GetIfExists("k2")
+			assert.False(t, ok)
+			_, ok = cache.GetIfExists("k3")
+			assert.False(t, ok)
+			assert.EqualValues(t, 2, cnt)
+		})
+	}
+}
+
+func TestCache_KeyNormalizer(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var requested []string
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				mu.Lock()
+				requested = append(requested, key)
+				mu.Unlock()
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, append(c.cacheOpts, WithKeyNormalizer(strings.ToLower))...)
+			assert.NoError(t, err)
+
+			v, err := cache.Get(context.Background(), "Foo")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-foo", v)
+			v, err = cache.Get(context.Background(), "FOO")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-foo", v)
+			v, ok := cache.GetIfExists("fOo")
+			assert.True(t, ok)
+			assert.Equal(t, "value-foo", v)
+			assert.Equal(t, []string{"foo"}, requested)
+			assert.Equal(t, []string{"foo"}, cache.Keys())
+
+			cache.Set("Bar", "bar")
+			assert.True(t, cache.Contains("BAR"))
+			values, err := cache.GetMulti(context.Background(), []string{"FOO", "bar", "Baz"})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"foo": "value-foo", "bar": "bar", "baz": "value-baz"}, values)
+
+			cache.Forget("FOO")
+			assert.False(t, cache.Contains("foo"))
+			cache.ForgetAll([]string{"BAR", "BAZ"})
+			assert.Equal(t, 0, cache.Len())
+		})
+	}
+
+	t.Run("sharded", func(t *testing.T) {
+		t.Parallel()
+
+		replaceFn := func(ctx context.Context, key string) (string, error) {
+			return "value-" + key, nil
+		}
+		cache, err := NewSharded[string, string](replaceFn, time.Minute, time.Minute, WithShards(16), WithKeyNormalizer(strings.ToLower))
+		assert.NoError(t, err)
+
+		for _, key := range []string{"a", "B", "cD", "Ef"} {
+			_, _ = cache.Get(context.Background(), key)
+			v, ok := cache.GetIfExists(strings.ToUpper(key))
+			assert.True(t, ok)
+			assert.Equal(t, "value-"+strings.ToLower(key), v)
+		}
+		assert.Equal(t, 4, cache.Len())
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		t.Parallel()
+
+		fn := func(ctx context.Context, s string) (string, error) { return "", nil }
+		_, err := New[string, string](fn, 0, 0, WithKeyNormalizer(func(key int) int { return key }))
+		assert.Error(t, err)
+		_, err = NewSharded[string, string](fn, 0, 0, WithKeyNormalizer(func(key int) int { return key }))
+		assert.Error(t, err)
+	})
+}
+
+func TestCache_TryGet(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				time.Sleep(100 * time.Millisecond)
+				return "value-" + strconv.FormatInt(n, 10), nil
+			}
+			cache, err := New[string, string](replaceFn, 250*time.Millisecond, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// t=0ms, miss - returns immediately, while retrieving the value in the background
+			t0 := time.Now()
+			_, ok := cache.TryGet(context.Background(), "k1")
+			assert.False(t, ok)
+			_, ok = cache.TryGet(context.Background(), "k1")
+			assert.False(t, ok)
+			assert.Less(t, time.Since(t0), 50*time.Millisecond)
+
+			// t=150ms, hit
+			time.Sleep(150 * time.Millisecond)
+			v, ok := cache.TryGet(context.Background(), "k1")
+			assert.True(t, ok)
+			assert.Equal(t, "value-1", v)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+
+			// t=400ms, grace hit - the stale value is returned, while retrieving a new value in the background
+			time.Sleep(250 * time.Millisecond)
+			v, ok = cache.TryGet(context.Background(), "k1")
+			assert.True(t, ok)
+			assert.Equal(t, "value-1", v)
+			time.Sleep(150 * time.Millisecond)
+			v, ok = cache.TryGet(context.Background(), "k1")
+			assert.True(t, ok)
+			assert.Equal(t, "value-2", v)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+			assert.Equal(t, HitStats{Hits: 2, GraceHits: 1, Misses: 2, Replacements: 2}, cache.Stats().HitStats)
+		})
+	}
+}
+
+func TestCache_GetIfExistsDetailed(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Second, 2*time.Second, append(c.cacheOpts, WithClock(now), WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			_, state := cache.GetIfExistsDetailed("k1")
+			assert.Equal(t, EntryMissing, state)
+
+			cache.Set("k1", "value")
+			v, state := cache.GetIfExistsDetailed("k1")
+			assert.Equal(t, EntryFresh, state)
+			assert.Equal(t, "value", v)
+
+			elapsed.Add(int64(1500 * time.Millisecond))
+			v, state = cache.GetIfExistsDetailed("k1")
+			assert.Equal(t, EntryStale, state)
+			assert.Equal(t, "value", v)
+
+			elapsed.Add(int64(time.Second))
+			_, state = cache.GetIfExistsDetailed("k1")
+			assert.Equal(t, EntryMissing, state)
+			assert.Equal(t, HitStats{Hits: 1, GraceHits: 1, Misses: 2}, cache.Stats().HitStats)
+		})
+	}
+}
+
+func TestCache_GetOrDefault(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 500*time.Millisecond, 1*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "default", cache.GetOrDefault("k1", "default"))
+			assert.EqualValues(t, 0, cnt)
+
+			_, _ = cache.Get(context.Background(), "k1")
+			assert.Equal(t, "result-k1", cache.GetOrDefault("k1", "default"))
+			// stale values are returned as well
+			time.Sleep(750 * time.Millisecond)
+			assert.Equal(t, "result-k1", cache.GetOrDefault("k1", "default"))
+			// expired
+			time.Sleep(500 * time.Millisecond)
+			assert.Equal(t, "default", cache.GetOrDefault("k1", "default"))
+			assert.EqualValues(t, 1, cnt)
+			assert.Equal(t, HitStats{1, 1, 3, 1, 0, 0}, cache.Stats().HitStats)
+		})
+	}
+}
+
+func TestCache_Peek(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, 100*time.Millisecond, append(c.cacheOpts, WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			_, ok := cache.Peek("k1")
+			assert.False(t, ok)
+
+			_, _ = cache.Get(context.Background(), "k1")
+			v, ok := cache.Peek("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "value-k1", v)
+
+			// stale value is returned without triggering replacements
+			time.Sleep(75 * time.Millisecond)
+			v, ok = cache.Peek("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "value-k1", v)
+
+			// expired value is not returned
+			time.Sleep(50 * time.Millisecond)
+			_, ok = cache.Peek("k1")
+			assert.False(t, ok)
+
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			assert.Equal(t, HitStats{0, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
+		})
+	}
+}
+
+func TestCache_Contains(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, 100*time.Millisecond, append(c.cacheOpts, WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			assert.False(t, cache.Contains("k1"))
+			_, _ = cache.Get(context.Background(), "k1")
+			assert.True(t, cache.Contains("k1"))
+			// stale
+			time.Sleep(75 * time.Millisecond)
+			assert.True(t, cache.Contains("k1"))
+			// expired, but not cleaned up yet
+			time.Sleep(50 * time.Millisecond)
+			assert.False(t, cache.Contains("k1"))
+			assert.Equal(t, 1, cache.Len())
+
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			assert.Equal(t, HitStats{0, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
+		})
+	}
+
+	t.Run("does not update recent usage", func(t *testing.T) {
+		t.Parallel()
+
+		replaceFn := func(ctx context.Context, key string) (string, error) {
+			return "value-" + key, nil
+		}
+		cache, err := New[string, string](replaceFn, time.Minute, time.Minute, WithLRUBackend(2))
+		assert.NoError(t, err)
+
+		_, _ = cache.Get(context.Background(), "k1")
+		_, _ = cache.Get(context.Background(), "k2")
+		assert.True(t, cache.Contains("k1"))
+		_, _ = cache.Get(context.Background(), "k3") // evicts the least recently used k1
+		assert.False(t, cache.Contains("k1"))
+		assert.True(t, cache.Contains("k2"))
+	})
+}
+
+func TestCache_GetMostRecent(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, 100*time.Millisecond, append(c.cacheOpts, WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			_, _, ok := cache.GetMostRecent("k1")
+			assert.False(t, ok)
+
+			_, _ = cache.Get(context.Background(), "k1")
+			time.Sleep(200 * time.Millisecond)
+
+			// expired value is returned with its age
+			v, age, ok := cache.GetMostRecent("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "value-k1", v)
+			assert.InDelta(t, 200*time.Millisecond, age, float64(50*time.Millisecond))
+
+			// no replacement is triggered, and stats are not affected
+			time.Sleep(50 * time.Millisecond)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			assert.Equal(t, HitStats{0, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
+		})
+	}
+}
+
+// TestCache_WaitFresh tests that (*Cache).WaitFresh waits until a fresh value is stored.
+func TestCache_WaitFresh(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				time.Sleep(200 * time.Millisecond)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// t=0ms, no replacement is ongoing
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err = cache.WaitFresh(ctx, "k1")
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+			// t=100ms, wait for the replacement triggered by Notify
+			t0 := time.Now()
+			cache.Notify(context.Background(), "k1")
+			err = cache.WaitFresh(context.Background(), "k1")
+			assert.NoError(t, err)
+			// assert t=300ms
+			assert.InDelta(t, 200*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			v, ok := cache.GetIfExists("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "value-k1", v)
+
+			// t=300ms, value is already fresh
+			t0 = time.Now()
+			err = cache.WaitFresh(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Less(t, time.Since(t0), 50*time.Millisecond)
+
+			// t=300ms, wait for the value set by another goroutine
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				cache.Set("k2", "set-k2")
+			}()
+			err = cache.WaitFresh(context.Background(), "k2")
+			assert.NoError(t, err)
+			v, ok = cache.GetIfExists("k2")
+			assert.True(t, ok)
+			assert.Equal(t, "set-k2", v)
+		})
+	}
+}
+
+// TestCache_Notify tests that (*Cache).Notify will replace the value in background.
+func TestCache_Notify(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				assert.Equal(t, "k1", key)
+				atomic.AddInt64(&cnt, 1)
+				time.Sleep(500 * time.Millisecond)
+				return "result1", nil
+			}
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// Start test t=0ms
+			t0 := time.Now()
+
+			// Notify value retrieval - this should launch goroutine in background
+			cache.Notify(context.Background(), "k1")
+			// Test that value is still not here
+			_, ok := cache.GetIfExists("k1")
+			assert.False(t, ok)
+
+			time.Sleep(750 * time.Millisecond)
+			// t=750ms, value should be cached
+			// Check that both GetIfExists and Get returns value immediately
+			v, ok := cache.GetIfExists("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "result1", v)
+			assert.InDelta(t, 750*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			assert.EqualValues(t, 1, cnt)
+
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result1", v)
+			assert.InDelta(t, 750*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			assert.EqualValues(t, 1, cnt)
+
+			// t=750ms, notify once again - this should do *nothing*
+			cache.Notify(context.Background(), "k1")
+
+			time.Sleep(750 * time.Millisecond)
+			// t=1500ms, assert that value was replaced only once
+			assert.EqualValues(t, 1, cnt)
+		})
+	}
+}
+
+func TestCache_NotifyChan(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				time.Sleep(100 * time.Millisecond)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			keys := []string{"k1", "k2", "k3"}
+			var chans []<-chan struct{}
+			for _, key := range keys {
+				chans = append(chans, cache.NotifyChan(context.Background(), key))
+			}
+			// joins the ongoing call
+			chans = append(chans, cache.NotifyChan(context.Background(), "k1"))
+			for _, ch := range chans {
+				select {
+				case <-ch:
+				case <-time.After(time.Second):
+					t.Fatal("channel was not closed")
+				}
+			}
+			for _, key := range keys {
+				v, ok := cache.GetIfExists(key)
+				assert.True(t, ok)
+				assert.Equal(t, "value-"+key, v)
+			}
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+
+			// value is fresh - already closed
+			select {
+			case <-cache.NotifyChan(context.Background(), "k1"):
+			default:
+				t.Fatal("channel should be closed")
+			}
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+		})
+	}
+}
+
+func TestCache_NotifyAll(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k1", v)
+
+			// k1 is fresh, and k2 is duplicated
+			cache.NotifyAll(context.Background(), []string{"k1", "k2", "k3", "k2"})
+			assert.Eventually(t, func() bool {
+				return cache.Contains("k2") && cache.Contains("k3")
+			}, time.Second, time.Millisecond)
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+		})
+	}
+}
+
+func TestCache_Refresh(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				time.Sleep(200 * time.Millisecond)
+				return "result-" + key + "-" + strconv.Itoa(int(n)), nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			v, err := cache.Refresh(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1-1", v)
+
+			// the value is fresh, but retrieved again
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Refresh(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "result-k1-2", v)
+			}()
+			go func() {
+				defer wg.Done()
+				time.Sleep(50 * time.Millisecond)
+				// joins the ongoing call
+				v, err := cache.Refresh(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "result-k1-2", v)
+			}()
+			// the current value is served while refreshing
+			time.Sleep(100 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1-1", v)
+
+			wg.Wait()
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1-2", v)
+			assert.EqualValues(t, 2, cnt)
+			assert.Equal(t, HitStats{2, 0, 0, 2, 0, 0}, cache.HitStats())
+		})
+	}
+}
+
+func TestCache_GetAndForget(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				if key == "slow" {
+					time.Sleep(200 * time.Millisecond)
+				}
+				return "value-" + strconv.FormatInt(n, 10), nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// consumes the cached value
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-1", v)
+			v, err = cache.GetAndForget(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-1", v)
+			assert.False(t, cache.Contains("k1"))
+
+			// retrieves a new value, which is not stored
+			v, err = cache.GetAndForget(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-2", v)
+			assert.False(t, cache.Contains("k1"))
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-3", v)
+
+			// never joins the ongoing call, which is stored as usual
+			done := make(chan string)
+			go func() {
+				v, _ := cache.Get(context.Background(), "slow")
+				done <- v
+			}()
+			time.Sleep(50 * time.Millisecond)
+			v, err = cache.GetAndForget(context.Background(), "slow")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-5", v)
+			assert.Equal(t, "value-4", <-done)
+			v, ok := cache.GetIfExists("slow")
+			assert.True(t, ok)
+			assert.Equal(t, "value-4", v)
+		})
+	}
+}
+
+// TestCache_Set ensures (*Cache).Set stores value without calling replaceFn.
+func TestCache_Set(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 250*time.Millisecond, 250*time.Millisecond, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			cache.Set("k1", "set-k1")
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "set-k1", v)
+			assert.EqualValues(t, 0, atomic.LoadInt64(&cnt))
+
+			// t=300ms, set value is expired
+			time.Sleep(300 * time.Millisecond)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k1", v)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+		})
+	}
+}
+
+// TestCache_Set_Interrupt ensures (*Cache).Set value is not overwritten by an ongoing replacement.
+func TestCache_Set_Interrupt(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range nonStrictCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				time.Sleep(200 * time.Millisecond)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// t=0ms, start replacement
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "value-k1", v) // waiting caller receives the result of the replacement
+			}()
+
+			// t=100ms, set value while replacement is ongoing
+			time.Sleep(100 * time.Millisecond)
+			cache.Set("k1", "set-k1")
+			wg.Wait()
+
+			// t=200ms, replacement result does not overwrite set value
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "set-k1", v)
+		})
+	}
+}
+
+func TestCache_RegisterExternalLoad(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// t=0ms, external load is registered, and Get calls wait for it
+			commit := cache.RegisterExternalLoad("k1")
+			var wg sync.WaitGroup
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					v, err := cache.Get(context.Background(), "k1")
+					assert.NoError(t, err)
+					assert.Equal(t, "external-k1", v)
+				}()
+			}
+
+			// t=100ms, external load completes
+			time.Sleep(100 * time.Millisecond)
+			commit("external-k1", nil)
+			commit("ignored", nil)
+			wg.Wait()
+
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "external-k1", v)
+			assert.EqualValues(t, 0, atomic.LoadInt64(&cnt))
+
+			// error is returned to the waiting callers, and is not cached
+			commit = cache.RegisterExternalLoad("k2")
+			targetErr := errors.New("test error")
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				commit("", targetErr)
+			}()
+			_, err = cache.Get(context.Background(), "k2")
+			assert.ErrorIs(t, err, targetErr)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k2", v)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+		})
+	}
+}
+
+func TestCache_Keys(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 200*time.Millisecond, 200*time.Millisecond, append(c.cacheOpts, WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			assert.Empty(t, cache.Keys())
+			_, _ = cache.Get(context.Background(), "k1")
+			_, _ = cache.Get(context.Background(), "k2")
+			assert.ElementsMatch(t, []string{"k1", "k2"}, cache.Keys())
+
+			// t=300ms, expired keys are excluded
+			time.Sleep(300 * time.Millisecond)
+			_, _ = cache.Get(context.Background(), "k3")
+			assert.Equal(t, []string{"k3"}, cache.Keys())
+			assert.Equal(t, 3, cache.Len())
+		})
+	}
+
+	t.Run("LRU order", func(t *testing.T) {
+		t.Parallel()
+
+		replaceFn := func(ctx context.Context, key string) (string, error) {
+			return "value-" + key, nil
+		}
+		cache, err := New[string, string](replaceFn, time.Minute, time.Minute, WithLRUBackend(10))
+		assert.NoError(t, err)
+
+		_, _ = cache.Get(context.Background(), "k1")
+		_, _ = cache.Get(context.Background(), "k2")
+		_, _ = cache.Get(context.Background(), "k3")
+		_, _ = cache.Get(context.Background(), "k1")
+		assert.Equal(t, []string{"k1", "k3", "k2"}, cache.Keys())
+	})
+}
+
+func TestCache_Range(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, append(c.cacheOpts, WithClock(now), WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			collect := func(limit int) map[string]string {
+				m := make(map[string]string)
+				cache.Range(func(key string, value string) bool {
+					m[key] = value
+					return len(m) < limit
+				})
+				return m
+			}
+
+			assert.Empty(t, collect(10))
+			_, _ = cache.Get(context.Background(), "k1")
+			_, _ = cache.Get(context.Background(), "k2")
+			assert.Equal(t, map[string]string{"k1": "value-k1", "k2": "value-k2"}, collect(10))
+			// stops early
+			assert.Len(t, collect(1), 1)
+
+			// expired items are skipped
+			elapsed.Add(int64(2 * time.Minute))
+			_, _ = cache.Get(context.Background(), "k3")
+			assert.Equal(t, map[string]string{"k3": "value-k3"}, collect(10))
+			assert.Equal(t, 3, cache.Len())
+		})
+	}
+}
+
+func TestCache_Len(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(2) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			assert.Equal(t, 0, cache.Len())
+			_, _ = cache.Get(context.Background(), "k1")
+			assert.Equal(t, 1, cache.Len())
+			_, _ = cache.Get(context.Background(), "k2")
+			assert.Equal(t, 2, cache.Len())
+			_, _ = cache.Get(context.Background(), "k3")
+			assert.Equal(t, cache.Stats().Size, cache.Len())
+			cache.Forget("k3")
+			assert.Equal(t, cache.Stats().Size, cache.Len())
+		})
+	}
+}
+
+func TestCache_SizeThresholdHook(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			sizes := make(chan int, 10)
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithSizeThresholdHook(3, func(size int) { sizes <- size }))...)
+			assert.NoError(t, err)
+
+			_, _ = cache.Get(context.Background(), "k1")
+			cache.Set("k2", "value-k2")
+			assert.Len(t, sizes, 0)
+
+			_, _ = cache.Get(context.Background(), "k3") // crosses threshold
+			assert.Equal(t, 3, <-sizes)
+			_, _ = cache.Get(context.Background(), "k4")
+			cache.Set("k5", "value-k5")
+			time.Sleep(50 * time.Millisecond)
+			assert.Len(t, sizes, 0)
+
+			// go below threshold, and cross it again
+			cache.Purge()
+			for _, key := range []string{"k1", "k2", "k3"} {
+				cache.Set(key, "value-"+key)
+			}
+			assert.Equal(t, 3, <-sizes)
 		})
 	}
 }
@@ -497,44 +2062,495 @@ func TestCache_Forget_Interrupt(t *testing.T) {
 
 			var cnt int64
 			replaceFn := func(ctx context.Context, key string) (string, error) {
-				assert.Equal(t, "k1", key)
+				assert.Equal(t, "k1", key)
+				atomic.AddInt64(&cnt, 1)
+				time.Sleep(750 * time.Millisecond)
+				return "result1", nil
+			}
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			t0 := time.Now()
+			var wg sync.WaitGroup
+			// t=0ms, 1st call
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "result1", v)
+			}()
+			time.Sleep(500 * time.Millisecond)
+			// t=500ms, Forget, then 2nd call
+			cache.Forget("k1")
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "result1", v)
+			}()
+			wg.Wait()
+			// t=1250ms, assert replaceFn was triggered twice
+			assert.EqualValues(t, 2, cnt)
+			assert.InDelta(t, 1250*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+		})
+	}
+}
+
+// TestCache_Forget_NoInterrupt is similar to TestCache_Forget_Interrupt, but there are no ongoing calls of replaceFn.
+func TestCache_Forget_NoInterrupt(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				assert.Equal(t, "k1", key)
+				atomic.AddInt64(&cnt, 1)
+				time.Sleep(250 * time.Millisecond)
+				return "result1", nil
+			}
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			t0 := time.Now()
+			var wg sync.WaitGroup
+			// t=0ms, 1st call
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "result1", v)
+			}()
+			time.Sleep(500 * time.Millisecond)
+			// t=500ms, Forget, then 2nd call
+			cache.Forget("k1")
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "result1", v)
+			}()
+			wg.Wait()
+			// t=750ms, assert replaceFn was triggered twice
+			assert.EqualValues(t, 2, cnt)
+			assert.InDelta(t, 750*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+		})
+	}
+}
+
+func TestCache_ForgetIfOlderThan(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				return "result" + strconv.Itoa(int(n)), nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			assert.False(t, cache.ForgetIfOlderThan("k1", time.Now()))
+
+			// t=0ms, value is retrieved
+			_, _ = cache.Get(context.Background(), "k1")
+			t0 := time.Now()
+
+			// t=50ms, observation made before retrieval does not forget the value
+			time.Sleep(50 * time.Millisecond)
+			assert.False(t, cache.ForgetIfOlderThan("k1", t0.Add(-50*time.Millisecond)))
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result1", v)
+
+			// observation made after retrieval forgets the value
+			assert.True(t, cache.ForgetIfOlderThan("k1", t0))
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
+		})
+	}
+}
+
+func TestCache_ForgetAll(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				if key == "k4" {
+					time.Sleep(100 * time.Millisecond)
+				}
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			for _, key := range []string{"k1", "k2", "k3"} {
+				_, _ = cache.Get(context.Background(), key)
+			}
+			// ongoing replacement of k4 is also forgotten
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = cache.Get(context.Background(), "k4")
+			}()
+			time.Sleep(50 * time.Millisecond)
+
+			cache.ForgetAll([]string{"k1", "k3", "k4", "k5"})
+			wg.Wait()
+			assert.ElementsMatch(t, []string{"k2"}, cache.Keys())
+			assert.EqualValues(t, 4, cnt)
+
+			cache.ForgetAll(nil)
+			assert.ElementsMatch(t, []string{"k2"}, cache.Keys())
+		})
+	}
+}
+
+// TestCache_ForgetIf ensures that calling (*Cache).ForgetIf will make later Get calls trigger replaceFn.
+func TestCache_ForgetIf(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
 				atomic.AddInt64(&cnt, 1)
 				time.Sleep(750 * time.Millisecond)
-				return "result1", nil
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 5*time.Second, 5*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			var wg sync.WaitGroup
+
+			callAndAssert := func(key string) {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), key)
+				assert.NoError(t, err)
+				assert.Equal(t, "result-"+key, v)
+			}
+			assertInCache := func(key string) {
+				v, err := cache.Get(context.Background(), key)
+				assert.NoError(t, err)
+				assert.Equal(t, "result-"+key, v)
+			}
+
+			// k1: Do not forget
+			// k2: Forget, no interrupt
+			// k3: Do not forget
+			// k4: Forget, interrupt
+			t0 := time.Now()
+			// t=0ms, call to k1, k2
+			wg.Add(2)
+			go callAndAssert("k1")
+			go callAndAssert("k2")
+			wg.Wait()
+			// t=750ms, assert k1 and k2 are in cache, and replaceFn is called twice
+			assert.InDelta(t, 750*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			assert.EqualValues(t, 2, cnt)
+			assertInCache("k1")
+			assertInCache("k2")
+			assert.EqualValues(t, 2, cnt)
+
+			// t=750ms, call to k3, k4
+			wg.Add(2)
+			go callAndAssert("k3")
+			go callAndAssert("k4")
+			time.Sleep(500 * time.Millisecond)
+
+			// t=1250ms, Forget k2, k4 then 2nd call to k2, k4
+			cache.ForgetIf(func(key string) bool { return key == "k2" || key == "k4" })
+			wg.Add(2)
+			go callAndAssert("k2")
+			go callAndAssert("k4")
+			wg.Wait()
+
+			// t=2000ms, assert replaceFn was triggered 6 times
+			assert.InDelta(t, 2000*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			assert.EqualValues(t, 6, cnt)
+			assertInCache("k1")
+			assertInCache("k2")
+			assertInCache("k3")
+			assertInCache("k4")
+			assert.EqualValues(t, 6, cnt)
+		})
+	}
+}
+
+// TestCache_ForgetIf_Interrupt ensures that results of ongoing replacements forgotten by Cache.ForgetIf
+// are not added to the cache.
+func TestCache_ForgetIf_Interrupt(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				time.Sleep(200 * time.Millisecond)
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// t=0ms, replacements of k1 and k2 start
+			var wg sync.WaitGroup
+			for _, key := range []string{"k1", "k2"} {
+				key := key
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					v, err := cache.Get(context.Background(), key)
+					assert.NoError(t, err)
+					assert.Equal(t, "result-"+key, v)
+				}()
+			}
+			time.Sleep(100 * time.Millisecond)
+
+			// t=100ms, forget k1 while its replacement is ongoing
+			cache.ForgetIf(func(key string) bool { return key == "k1" })
+			wg.Wait()
+
+			// t=200ms, the replacement of k1 finished, but its result is not stored
+			_, ok := cache.Peek("k1")
+			assert.False(t, ok)
+			assert.Equal(t, []string{"k2"}, cache.Keys())
+		})
+	}
+}
+
+// TestCache_Purge_Interrupt ensures that calling Cache.Purge will make all later Get calls trigger replaceFn.
+func TestCache_Purge_Interrupt(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				time.Sleep(750 * time.Millisecond)
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			t0 := time.Now()
+			var wg sync.WaitGroup
+			// t=0ms, 1st call
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "result-k1", v)
+			}()
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k2")
+				assert.NoError(t, err)
+				assert.Equal(t, "result-k2", v)
+			}()
+			time.Sleep(500 * time.Millisecond)
+			// t=500ms, Purge, then 2nd call
+			cache.Purge()
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "result-k1", v)
+			}()
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "k2")
+				assert.NoError(t, err)
+				assert.Equal(t, "result-k2", v)
+			}()
+			wg.Wait()
+			// t=1250ms, assert replaceFn was triggered 4 times
+			assert.EqualValues(t, 4, cnt)
+			assert.InDelta(t, 1250*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+		})
+	}
+}
+
+// TestCache_Purge_NoInterrupt is similar to TestCache_Purge_Interrupt, but there are no ongoing calls of replaceFn.
+func TestCache_Purge_NoInterrupt(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// 1st call group
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", v)
+			assert.EqualValues(t, cnt, 1)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k2", v)
+			assert.EqualValues(t, cnt, 2)
+
+			// 2nd call group - values are reused
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", v)
+			assert.EqualValues(t, cnt, 2)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k2", v)
+			assert.EqualValues(t, cnt, 2)
+
+			cache.Purge()
+
+			// 3rd call group - all values are forgotten
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", v)
+			assert.EqualValues(t, cnt, 3)
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k2", v)
+			assert.EqualValues(t, cnt, 4)
+		})
+	}
+}
+
+// TestCache_Flush ensures that calling (*Cache).Flush deletes stored values, but does not interrupt ongoing calls.
+func TestCache_Flush(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range nonStrictCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				if key == "slow" {
+					time.Sleep(200 * time.Millisecond)
+				}
+				return "result" + strconv.Itoa(int(n)), nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			// t=0ms, k1 is stored, and slow is being retrieved
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result1", v)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := cache.Get(context.Background(), "slow")
+				assert.NoError(t, err)
+				assert.Equal(t, "result2", v)
+			}()
+
+			// t=100ms, flush
+			time.Sleep(100 * time.Millisecond)
+			cache.Flush()
+			assert.Equal(t, 0, cache.Len())
+			assert.Equal(t, HitStats{0, 0, 2, 1, 0, 0}, cache.Stats().HitStats)
+
+			// t=100ms, Get call joins the ongoing call instead of retrieving again
+			v, err = cache.Get(context.Background(), "slow")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
+			wg.Wait()
+
+			// t=200ms, ongoing call result is stored, while flushed value is retrieved again
+			v, err = cache.Get(context.Background(), "slow")
+			assert.NoError(t, err)
+			assert.Equal(t, "result2", v)
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result3", v)
+		})
+	}
+}
+
+// TestCache_ParallelReplacement ensures parallel call to replaceFn per key, not per cache instance.
+func TestCache_ParallelReplacement(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				time.Sleep(500 * time.Millisecond)
+				return "result-" + key, nil
 			}
 			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
 			assert.NoError(t, err)
 
 			t0 := time.Now()
 			var wg sync.WaitGroup
-			// t=0ms, 1st call
-			wg.Add(1)
+			wg.Add(2)
 			go func() {
 				defer wg.Done()
 				v, err := cache.Get(context.Background(), "k1")
 				assert.NoError(t, err)
-				assert.Equal(t, "result1", v)
+				assert.Equal(t, "result-k1", v)
 			}()
-			time.Sleep(500 * time.Millisecond)
-			// t=500ms, Forget, then 2nd call
-			cache.Forget("k1")
-			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				v, err := cache.Get(context.Background(), "k1")
+				v, err := cache.Get(context.Background(), "k2")
 				assert.NoError(t, err)
-				assert.Equal(t, "result1", v)
+				assert.Equal(t, "result-k2", v)
 			}()
 			wg.Wait()
-			// t=1250ms, assert replaceFn was triggered twice
+			// t=500ms, assert replaceFn was triggered twice
 			assert.EqualValues(t, 2, cnt)
-			assert.InDelta(t, 1250*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			// assert t=500ms
+			assert.InDelta(t, 500*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
 		})
 	}
 }
 
-// TestCache_Forget_NoInterrupt is similar to TestCache_Forget_Interrupt, but there are no ongoing calls of replaceFn.
-func TestCache_Forget_NoInterrupt(t *testing.T) {
+func TestCache_MaxWaitersPerKey(t *testing.T) {
 	t.Parallel()
 
 	for _, c := range allCaches(10) {
@@ -544,44 +2560,121 @@ func TestCache_Forget_NoInterrupt(t *testing.T) {
 
 			var cnt int64
 			replaceFn := func(ctx context.Context, key string) (string, error) {
-				assert.Equal(t, "k1", key)
 				atomic.AddInt64(&cnt, 1)
-				time.Sleep(250 * time.Millisecond)
-				return "result1", nil
+				time.Sleep(500 * time.Millisecond)
+				return "result-" + key, nil
 			}
-			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, append(c.cacheOpts, WithMaxWaitersPerKey(2))...)
 			assert.NoError(t, err)
 
+			var wg sync.WaitGroup
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					v, err := cache.Get(context.Background(), "k1")
+					assert.NoError(t, err)
+					assert.Equal(t, "result-k1", v)
+				}()
+				time.Sleep(50 * time.Millisecond)
+			}
+			// t=150ms, the leader and 2 waiters are blocked - further callers fail immediately
 			t0 := time.Now()
+			_, err = cache.Get(context.Background(), "k1")
+			assert.ErrorIs(t, err, ErrTooManyWaiters)
+			assert.Less(t, time.Since(t0), 50*time.Millisecond)
+			values, err := cache.GetMulti(context.Background(), []string{"k1"})
+			assert.ErrorIs(t, err, ErrTooManyWaiters)
+			assert.Empty(t, values)
+			// other keys are not affected
+			v, err := cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k2", v)
+
+			wg.Wait()
+			// the limit applies per call
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", v)
+			assert.EqualValues(t, 2, cnt)
+		})
+	}
+}
+
+func TestCache_MaxConcurrentReplacements(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt, running, maxRunning int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				r := atomic.AddInt64(&running, 1)
+				defer atomic.AddInt64(&running, -1)
+				for {
+					m := atomic.LoadInt64(&maxRunning)
+					if r <= m || atomic.CompareAndSwapInt64(&maxRunning, m, r) {
+						break
+					}
+				}
+				time.Sleep(200 * time.Millisecond)
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, append(c.cacheOpts, WithMaxConcurrentReplacements(1))...)
+			assert.NoError(t, err)
+
 			var wg sync.WaitGroup
-			// t=0ms, 1st call
-			wg.Add(1)
+			wg.Add(3)
 			go func() {
 				defer wg.Done()
 				v, err := cache.Get(context.Background(), "k1")
 				assert.NoError(t, err)
-				assert.Equal(t, "result1", v)
+				assert.Equal(t, "result-k1", v)
 			}()
-			time.Sleep(500 * time.Millisecond)
-			// t=500ms, Forget, then 2nd call
-			cache.Forget("k1")
-			wg.Add(1)
+			time.Sleep(20 * time.Millisecond)
 			go func() {
 				defer wg.Done()
-				v, err := cache.Get(context.Background(), "k1")
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				_, err := cache.Get(ctx, "k2")
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+			}()
+			time.Sleep(10 * time.Millisecond)
+			go func() {
+				defer wg.Done()
+				// joins the call of the canceled caller above
+				v, err := cache.Get(context.Background(), "k2")
 				assert.NoError(t, err)
-				assert.Equal(t, "result1", v)
+				assert.Equal(t, "result-k2", v)
 			}()
+
+			// t=30ms, the only slot is taken by k1 - the caller gives up waiting when ctx is done
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			t0 := time.Now()
+			_, err = cache.Get(ctx, "k3")
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.InDelta(t, 50*time.Millisecond, time.Since(t0), float64(30*time.Millisecond))
+			_, err = cache.TryGetOrError(context.Background(), "k4")
+			assert.ErrorIs(t, err, ErrBusy)
+
 			wg.Wait()
-			// t=750ms, assert replaceFn was triggered twice
+			// k3 was abandoned without calling replaceFn, while k2 was retrieved for the remaining caller
 			assert.EqualValues(t, 2, cnt)
-			assert.InDelta(t, 750*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			assert.EqualValues(t, 1, maxRunning)
+			_, ok := cache.Peek("k3")
+			assert.False(t, ok)
+			v, err := cache.Get(context.Background(), "k3")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k3", v)
 		})
 	}
 }
 
-// TestCache_ForgetIf ensures that calling (*Cache).ForgetIf will make later Get calls trigger replaceFn.
-func TestCache_ForgetIf(t *testing.T) {
+func TestCache_ReplacementTimeout(t *testing.T) {
 	t.Parallel()
 
 	for _, c := range allCaches(10) {
@@ -589,73 +2682,143 @@ func TestCache_ForgetIf(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
 
-			var cnt int64
+			type ctxKey struct{}
 			replaceFn := func(ctx context.Context, key string) (string, error) {
-				atomic.AddInt64(&cnt, 1)
-				time.Sleep(750 * time.Millisecond)
+				assert.Equal(t, "value", ctx.Value(ctxKey{}))
+				if key == "stuck" {
+					<-ctx.Done()
+					return "", ctx.Err()
+				}
 				return "result-" + key, nil
 			}
-			cache, err := New[string, string](replaceFn, 5*time.Second, 5*time.Second, c.cacheOpts...)
+			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, append(c.cacheOpts, WithReplacementTimeout(100*time.Millisecond))...)
 			assert.NoError(t, err)
+			ctx := context.WithValue(context.Background(), ctxKey{}, "value")
 
 			var wg sync.WaitGroup
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					t0 := time.Now()
+					_, err := cache.Get(ctx, "stuck")
+					assert.ErrorIs(t, err, context.DeadlineExceeded)
+					assert.Less(t, time.Since(t0), 200*time.Millisecond)
+				}()
+			}
+			wg.Wait()
 
-			callAndAssert := func(key string) {
-				defer wg.Done()
-				v, err := cache.Get(context.Background(), key)
-				assert.NoError(t, err)
-				assert.Equal(t, "result-"+key, v)
+			// the timeout does not affect successful retrievals
+			v, err := cache.Get(ctx, "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k1", v)
+		})
+	}
+}
+
+func TestCache_BackgroundContext(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			type ctxKey string
+			var mu sync.Mutex
+			var traces []any
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				mu.Lock()
+				traces = append(traces, ctx.Value(ctxKey("trace")), ctx.Value(ctxKey("background")))
+				mu.Unlock()
+				return "result-" + key, nil
 			}
-			assertInCache := func(key string) {
-				v, err := cache.Get(context.Background(), key)
-				assert.NoError(t, err)
-				assert.Equal(t, "result-"+key, v)
+			background := func(parent context.Context) context.Context {
+				return context.WithValue(context.WithoutCancel(parent), ctxKey("background"), true)
 			}
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, time.Minute, append(c.cacheOpts, WithBackgroundContext(background))...)
+			assert.NoError(t, err)
 
-			// k1: Do not forget
-			// k2: Forget, no interrupt
-			// k3: Do not forget
-			// k4: Forget, interrupt
-			t0 := time.Now()
-			// t=0ms, call to k1, k2
-			wg.Add(2)
-			go callAndAssert("k1")
-			go callAndAssert("k2")
-			wg.Wait()
-			// t=750ms, assert k1 and k2 are in cache, and replaceFn is called twice
-			assert.InDelta(t, 750*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
-			assert.EqualValues(t, 2, cnt)
-			assertInCache("k1")
-			assertInCache("k2")
-			assert.EqualValues(t, 2, cnt)
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey("trace"), "t1"))
+			_, _ = cache.Get(ctx, "k1") // sync replacement
+			time.Sleep(75 * time.Millisecond)
+			ctx = context.WithValue(ctx, ctxKey("trace"), "t2")
+			_, _ = cache.Get(ctx, "k1") // background replacement
+			cancel()
+			time.Sleep(25 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, []any{"t1", nil, "t2", true}, traces)
+		})
+	}
+}
 
-			// t=750ms, call to k3, k4
-			wg.Add(2)
-			go callAndAssert("k3")
-			go callAndAssert("k4")
-			time.Sleep(500 * time.Millisecond)
+func TestCache_GetFresh(t *testing.T) {
+	t.Parallel()
 
-			// t=1250ms, Forget k2, k4 then 2nd call to k2, k4
-			cache.ForgetIf(func(key string) bool { return key == "k2" || key == "k4" })
-			wg.Add(2)
-			go callAndAssert("k2")
-			go callAndAssert("k4")
-			wg.Wait()
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
 
-			// t=2000ms, assert replaceFn was triggered 6 times
-			assert.InDelta(t, 2000*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
-			assert.EqualValues(t, 6, cnt)
-			assertInCache("k1")
-			assertInCache("k2")
-			assertInCache("k3")
-			assertInCache("k4")
-			assert.EqualValues(t, 6, cnt)
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				return "value-" + strconv.FormatInt(n, 10), nil
+			}
+			cache, err := New[string, string](replaceFn, time.Second, time.Minute, append(c.cacheOpts, WithClock(now), WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			v, err := cache.GetFresh(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-1", v)
+			// fresh value is served as is
+			v, err = cache.GetFresh(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-1", v)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+
+			// stale value is not served, unlike Get
+			elapsed.Add(int64(2 * time.Second))
+			v, err = cache.GetFresh(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-2", v)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+
+			elapsed.Add(int64(2 * time.Second))
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-2", v)
 		})
 	}
+
+	t.Run("joins ongoing call", func(t *testing.T) {
+		t.Parallel()
+
+		var cnt int64
+		replaceFn := func(ctx context.Context, key string) (string, error) {
+			n := atomic.AddInt64(&cnt, 1)
+			time.Sleep(500 * time.Millisecond)
+			return "value-" + strconv.FormatInt(n, 10), nil
+		}
+		cache, err := New[string, string](replaceFn, 200*time.Millisecond, time.Minute)
+		assert.NoError(t, err)
+
+		go func() { _, _ = cache.Get(context.Background(), "k1") }()
+		time.Sleep(300 * time.Millisecond)
+		// t=300ms, the ongoing call started at t=0ms, so its value is no longer fresh - GetFresh retries
+		v, err := cache.GetFresh(context.Background(), "k1")
+		assert.NoError(t, err)
+		assert.Equal(t, "value-2", v)
+		assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+	})
 }
 
-// TestCache_Purge_Interrupt ensures that calling Cache.Purge will make all later Get calls trigger replaceFn.
-func TestCache_Purge_Interrupt(t *testing.T) {
+func TestCache_NoGracefulRefresh(t *testing.T) {
 	t.Parallel()
 
 	for _, c := range allCaches(10) {
@@ -663,57 +2826,149 @@ func TestCache_Purge_Interrupt(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
 
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
 			var cnt int64
 			replaceFn := func(ctx context.Context, key string) (string, error) {
-				atomic.AddInt64(&cnt, 1)
-				time.Sleep(750 * time.Millisecond)
+				n := atomic.AddInt64(&cnt, 1)
+				return "value-" + strconv.FormatInt(n, 10), nil
+			}
+			cache, err := New[string, string](replaceFn, time.Second, time.Minute,
+				append(c.cacheOpts, WithNoGracefulRefresh(), WithClock(now), WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-1", v)
+
+			// stale value is not served by Get
+			elapsed.Add(int64(2 * time.Second))
+			v, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-2", v)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+
+			// nor by GetMulti
+			elapsed.Add(int64(2 * time.Second))
+			values, err := cache.GetMulti(context.Background(), []string{"k1"})
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"k1": "value-3"}, values)
+
+			// but is still kept until ttl passes
+			elapsed.Add(int64(2 * time.Second))
+			v, ok := cache.Peek("k1")
+			assert.True(t, ok)
+			assert.Equal(t, "value-3", v)
+
+			// TryGet reports a miss, and retrieves the value in the background
+			_, ok = cache.TryGet(context.Background(), "k1")
+			assert.False(t, ok)
+			assert.Eventually(t, func() bool {
+				v, ok := cache.TryGet(context.Background(), "k1")
+				return ok && v == "value-4"
+			}, time.Second, time.Millisecond)
+
+			stats := cache.Stats()
+			assert.EqualValues(t, 0, stats.GraceHits)
+		})
+	}
+}
+
+func TestCache_FreshnessJitter(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	var elapsed atomic.Int64
+	now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+	cache, err := New[string, string](replaceFn, time.Minute, 2*time.Minute,
+		WithFreshnessJitter(0.5), WithClock(now), WithCleanupInterval(0))
+	assert.NoError(t, err)
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		cache.Set("k"+strconv.Itoa(i), "v")
+	}
+	countStale := func() int {
+		var stale int
+		for i := 0; i < n; i++ {
+			_, state := cache.GetIfExistsDetailed("k" + strconv.Itoa(i))
+			if state == EntryStale {
+				stale++
+			}
+		}
+		return stale
+	}
+
+	// t=29s, all items are still fresh
+	elapsed.Add(int64(29 * time.Second))
+	assert.Equal(t, 0, countStale())
+	// t=45s, items become stale at different times
+	elapsed.Add(int64(16 * time.Second))
+	stale := countStale()
+	assert.Greater(t, stale, 0)
+	assert.Less(t, stale, n)
+	// t=61s, all items are stale, but none is expired
+	elapsed.Add(int64(16 * time.Second))
+	assert.Equal(t, n, countStale())
+
+	_, err = New[string, string](replaceFn, 0, 0, WithFreshnessJitter(1))
+	assert.Error(t, err)
+	_, err = New[string, string](replaceFn, 0, 0, WithFreshnessJitter(-0.1))
+	assert.Error(t, err)
+}
+
+func TestCache_GetWithStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				time.Sleep(50 * time.Millisecond)
+				if key == "fail" {
+					return "", errors.New("error")
+				}
 				return "result-" + key, nil
 			}
-			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			cache, err := New[string, string](replaceFn, 100*time.Millisecond, time.Minute, append(c.cacheOpts, WithNegativeCache(time.Minute))...)
 			assert.NoError(t, err)
 
-			t0 := time.Now()
-			var wg sync.WaitGroup
-			// t=0ms, 1st call
-			wg.Add(2)
-			go func() {
-				defer wg.Done()
-				v, err := cache.Get(context.Background(), "k1")
-				assert.NoError(t, err)
-				assert.Equal(t, "result-k1", v)
-			}()
-			go func() {
-				defer wg.Done()
-				v, err := cache.Get(context.Background(), "k2")
-				assert.NoError(t, err)
-				assert.Equal(t, "result-k2", v)
-			}()
-			time.Sleep(500 * time.Millisecond)
-			// t=500ms, Purge, then 2nd call
-			cache.Purge()
-			wg.Add(2)
-			go func() {
-				defer wg.Done()
-				v, err := cache.Get(context.Background(), "k1")
-				assert.NoError(t, err)
-				assert.Equal(t, "result-k1", v)
-			}()
+			assertStatus := func(key string, want Status) {
+				t.Helper()
+				_, status, _ := cache.GetWithStatus(context.Background(), key)
+				assert.Equal(t, want, status, key)
+			}
+
+			// t=0ms, a caller joining the ongoing call also misses
+			var wg sync.WaitGroup
+			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				v, err := cache.Get(context.Background(), "k2")
-				assert.NoError(t, err)
-				assert.Equal(t, "result-k2", v)
+				assertStatus("k1", StatusMiss)
 			}()
+			time.Sleep(10 * time.Millisecond)
+			assertStatus("k1", StatusMiss)
 			wg.Wait()
-			// t=1250ms, assert replaceFn was triggered 4 times
-			assert.EqualValues(t, 4, cnt)
-			assert.InDelta(t, 1250*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			// t=50ms
+			assertStatus("k1", StatusHit)
+			assertStatus("fail", StatusMiss)
+			assertStatus("fail", StatusErrorHit)
+			// t=100ms
+			time.Sleep(60 * time.Millisecond)
+			assertStatus("k1", StatusGraceHit)
+			time.Sleep(60 * time.Millisecond)
+			assertStatus("k1", StatusHit)
 		})
 	}
 }
 
-// TestCache_Purge_NoInterrupt is similar to TestCache_Purge_Interrupt, but there are no ongoing calls of replaceFn.
-func TestCache_Purge_NoInterrupt(t *testing.T) {
+func TestCache_Resize(t *testing.T) {
 	t.Parallel()
 
 	for _, c := range allCaches(10) {
@@ -721,51 +2976,116 @@ func TestCache_Purge_NoInterrupt(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
 
-			var cnt int64
 			replaceFn := func(ctx context.Context, key string) (string, error) {
-				atomic.AddInt64(&cnt, 1)
 				return "result-" + key, nil
 			}
-			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
 			assert.NoError(t, err)
 
-			// 1st call group
-			v, err := cache.Get(context.Background(), "k1")
-			assert.NoError(t, err)
-			assert.Equal(t, "result-k1", v)
-			assert.EqualValues(t, cnt, 1)
-			v, err = cache.Get(context.Background(), "k2")
-			assert.NoError(t, err)
-			assert.Equal(t, "result-k2", v)
-			assert.EqualValues(t, cnt, 2)
+			for i := 0; i < 10; i++ {
+				_, _ = cache.Get(context.Background(), "k"+strconv.Itoa(i))
+			}
 
-			// 2nd call group - values are reused
-			v, err = cache.Get(context.Background(), "k1")
-			assert.NoError(t, err)
-			assert.Equal(t, "result-k1", v)
-			assert.EqualValues(t, cnt, 2)
-			v, err = cache.Get(context.Background(), "k2")
-			assert.NoError(t, err)
-			assert.Equal(t, "result-k2", v)
-			assert.EqualValues(t, cnt, 2)
+			if cache.BackendType() == "map" {
+				assert.NoError(t, cache.Resize(0))
+				assert.NoError(t, cache.Resize(5))
+				assert.Equal(t, SizeStats{10, -1, 0}, cache.Stats().SizeStats)
+				return
+			}
 
-			cache.Purge()
+			assert.Error(t, cache.Resize(0))
+			assert.NoError(t, cache.Resize(5))
+			assert.Equal(t, SizeStats{5, 5, 0}, cache.Stats().SizeStats)
+			assert.EqualValues(t, 5, cache.Stats().Evictions)
+			assert.NoError(t, cache.Resize(20))
+			for i := 10; i < 30; i++ {
+				_, _ = cache.Get(context.Background(), "k"+strconv.Itoa(i))
+			}
+			assert.Equal(t, SizeStats{20, 20, 0}, cache.Stats().SizeStats)
+		})
+	}
+}
 
-			// 3rd call group - all values are forgotten
-			v, err = cache.Get(context.Background(), "k1")
-			assert.NoError(t, err)
-			assert.Equal(t, "result-k1", v)
-			assert.EqualValues(t, cnt, 3)
-			v, err = cache.Get(context.Background(), "k2")
+func TestCache_Clock(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				return "result" + strconv.Itoa(int(n)), nil
+			}
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			advance := func(d time.Duration) { elapsed.Add(int64(d)) }
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			cache, err := New[string, string](replaceFn, time.Minute, 2*time.Minute, append(c.cacheOpts, WithClock(now), WithCleanupInterval(0))...)
 			assert.NoError(t, err)
-			assert.Equal(t, "result-k2", v)
-			assert.EqualValues(t, cnt, 4)
+
+			assertGet := func(want string, wantStatus Status) {
+				t.Helper()
+				v, status, err := cache.GetWithStatus(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, want, v)
+				assert.Equal(t, wantStatus, status)
+			}
+
+			assertGet("result1", StatusMiss)
+			advance(30 * time.Second)
+			assertGet("result1", StatusHit)
+			_, age, ok := cache.GetMostRecent("k1")
+			assert.True(t, ok)
+			assert.Equal(t, 30*time.Second, age)
+
+			// t=75s, stale
+			advance(45 * time.Second)
+			assertGet("result1", StatusGraceHit)
+			assert.Eventually(t, func() bool { return cache.Contains("k1") && atomic.LoadInt64(&cnt) == 2 }, time.Second, 10*time.Millisecond)
+			assertGet("result2", StatusHit)
+
+			// t=300s, expired
+			advance(225 * time.Second)
+			_, ok = cache.Peek("k1")
+			assert.False(t, ok)
+			assertGet("result3", StatusMiss)
 		})
 	}
 }
 
-// TestCache_ParallelReplacement ensures parallel call to replaceFn per key, not per cache instance.
-func TestCache_ParallelReplacement(t *testing.T) {
+func TestCache_MaxWeight(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return strings.Repeat("x", len(key)), nil
+	}
+	weigher := func(key string, v string) int64 { return int64(len(v)) }
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute, WithMaxWeight(10, weigher))
+	assert.NoError(t, err)
+
+	for _, key := range []string{"a", "bb", "ccc", "dddd"} {
+		_, _ = cache.Get(context.Background(), key)
+	}
+	assert.Equal(t, SizeStats{4, -1, 10}, cache.Stats().SizeStats)
+
+	_, _ = cache.Get(context.Background(), "eeeee")
+	assert.ElementsMatch(t, []string{"dddd", "eeeee"}, cache.Keys())
+	assert.Equal(t, SizeStats{2, -1, 9}, cache.Stats().SizeStats)
+	assert.EqualValues(t, 3, cache.Stats().Evictions)
+
+	// an item heavier than maxWeight is evicted immediately, without evicting the others
+	_, _ = cache.Get(context.Background(), "fffffffffff")
+	assert.ElementsMatch(t, []string{"dddd", "eeeee"}, cache.Keys())
+	assert.EqualValues(t, 4, cache.Stats().Evictions)
+	assert.Error(t, cache.Resize(10))
+}
+
+// TestCache_TryGetOrError ensures WithMaxConcurrentReplacements limits concurrent replaceFn calls,
+// and (*Cache).TryGetOrError fails fast with ErrBusy when no replacement slot is available.
+func TestCache_TryGetOrError(t *testing.T) {
 	t.Parallel()
 
 	for _, c := range allCaches(10) {
@@ -773,35 +3093,57 @@ func TestCache_ParallelReplacement(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
 
-			var cnt int64
+			var cnt, running, maxRunning int64
 			replaceFn := func(ctx context.Context, key string) (string, error) {
 				atomic.AddInt64(&cnt, 1)
-				time.Sleep(500 * time.Millisecond)
+				r := atomic.AddInt64(&running, 1)
+				defer atomic.AddInt64(&running, -1)
+				for {
+					m := atomic.LoadInt64(&maxRunning)
+					if r <= m || atomic.CompareAndSwapInt64(&maxRunning, m, r) {
+						break
+					}
+				}
+				time.Sleep(100 * time.Millisecond)
 				return "result-" + key, nil
 			}
-			cache, err := New[string, string](replaceFn, 1*time.Second, 1*time.Second, c.cacheOpts...)
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, append(c.cacheOpts, WithMaxConcurrentReplacements(2))...)
 			assert.NoError(t, err)
 
 			t0 := time.Now()
 			var wg sync.WaitGroup
-			wg.Add(2)
-			go func() {
-				defer wg.Done()
-				v, err := cache.Get(context.Background(), "k1")
-				assert.NoError(t, err)
-				assert.Equal(t, "result-k1", v)
-			}()
-			go func() {
-				defer wg.Done()
-				v, err := cache.Get(context.Background(), "k2")
-				assert.NoError(t, err)
-				assert.Equal(t, "result-k2", v)
-			}()
+			for i := 0; i < 4; i++ {
+				key := "k" + strconv.Itoa(i)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					v, err := cache.Get(context.Background(), key)
+					assert.NoError(t, err)
+					assert.Equal(t, "result-"+key, v)
+				}()
+			}
+
+			// t=20ms, k0 and k1 are being retrieved, while k2 and k3 are waiting for a slot
+			time.Sleep(20 * time.Millisecond)
+			t1 := time.Now()
+			_, err = cache.TryGetOrError(context.Background(), "k4")
+			assert.ErrorIs(t, err, ErrBusy)
+			assert.Less(t, time.Since(t1), 10*time.Millisecond)
+			// ongoing calls are joined without a slot
+			v, err := cache.TryGetOrError(context.Background(), "k0")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k0", v)
+
+			// t=200ms, k2 and k3 are retrieved after k0 and k1
 			wg.Wait()
-			// t=500ms, assert replaceFn was triggered twice
-			assert.EqualValues(t, 2, cnt)
-			// assert t=500ms
-			assert.InDelta(t, 500*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
+			assert.InDelta(t, 200*time.Millisecond, time.Since(t0), float64(50*time.Millisecond))
+			assert.EqualValues(t, 4, cnt)
+			assert.EqualValues(t, 2, maxRunning)
+
+			v, err = cache.TryGetOrError(context.Background(), "k4")
+			assert.NoError(t, err)
+			assert.Equal(t, "result-k4", v)
+			assert.EqualValues(t, 5, cnt)
 		})
 	}
 }
@@ -1165,6 +3507,286 @@ func TestCleaningCache(t *testing.T) {
 	}
 }
 
+func TestCache_DeleteExpired(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			var expired []string
+			onExpire := func(key string, v string) { expired = append(expired, key) }
+			cache, err := New(replaceFn, time.Minute, 2*time.Minute,
+				append(c.cacheOpts, WithCleanupInterval(0), WithClock(now), WithExpirationCallback(onExpire))...)
+			assert.NoError(t, err)
+
+			_, _ = cache.Get(context.Background(), "k1")
+			elapsed.Add(int64(time.Minute))
+			_, _ = cache.Get(context.Background(), "k2")
+			assert.Equal(t, 0, cache.DeleteExpired())
+
+			// t=150s, only k1 is expired
+			elapsed.Add(int64(90 * time.Second))
+			assert.Equal(t, 2, cache.Len())
+			assert.Equal(t, 1, cache.DeleteExpired())
+			assert.Equal(t, []string{"k1"}, expired)
+			assert.Equal(t, []string{"k2"}, cache.Keys())
+		})
+	}
+}
+
+func TestCache_ForgetExpired(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			release := make(chan struct{})
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				if key == "blocking" {
+					<-release
+				}
+				return "value-" + key, nil
+			}
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			cache, err := New(replaceFn, time.Minute, 2*time.Minute,
+				append(c.cacheOpts, WithCleanupInterval(0), WithClock(now))...)
+			assert.NoError(t, err)
+
+			_, _ = cache.Get(context.Background(), "k1")
+			elapsed.Add(int64(time.Minute))
+			_, _ = cache.Get(context.Background(), "k2")
+
+			// The call is valid until t=90s
+			done := make(chan string)
+			go func() {
+				v, _ := cache.GetWithOptions(context.Background(), "blocking", WithCallValidUntil(base.Add(90*time.Second)))
+				done <- v
+			}()
+			assert.Eventually(t, func() bool { return cache.InFlight() == 1 }, time.Second, time.Millisecond)
+			assert.Equal(t, 0, cache.ForgetExpired())
+			assert.Equal(t, 1, cache.InFlight())
+
+			// t=150s, k1 and the call are expired
+			elapsed.Add(int64(90 * time.Second))
+			assert.Equal(t, 2, cache.ForgetExpired())
+			assert.Equal(t, 0, cache.InFlight())
+			assert.Equal(t, []string{"k2"}, cache.Keys())
+
+			// The waiter still receives the result, which is not stored
+			close(release)
+			assert.Equal(t, "value-blocking", <-done)
+			assert.False(t, cache.Contains("blocking"))
+		})
+	}
+}
+
+func TestCache_HasCleaner(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, cache.HasCleaner())
+
+	cache, err = New[string, string](replaceFn, time.Minute, time.Minute, WithCleanupInterval(0))
+	assert.NoError(t, err)
+	assert.False(t, cache.HasCleaner())
+}
+
+// TestCleaningCache_Adaptive tests caches with adaptive cleanup option.
+func TestCleaningCache_Adaptive(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			opts := append(c.cacheOpts, WithCleanupInterval(time.Hour), WithAdaptiveCleanup(100*time.Millisecond, 200*time.Millisecond))
+			cache, err := New(replaceFn, 100*time.Millisecond, 100*time.Millisecond, opts...)
+			assert.NoError(t, err)
+
+			// t=0ms, cache the value
+			_, err = cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, 1, cache.Stats().Size)
+
+			// t=500ms, initial interval (1h) is clamped to max interval, so the expired value is removed
+			time.Sleep(500 * time.Millisecond)
+			assert.Equal(t, 0, cache.Stats().Size)
+		})
+	}
+}
+
+func Test_cleaner_nextInterval(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		interval time.Duration
+		removed  int
+		scanned  int
+	}
+	tests := []struct {
+		name     string
+		min, max time.Duration
+		args     args
+		want     time.Duration
+	}{
+		{"not adaptive", 0, 0, args{time.Second, 10, 10}, time.Second},
+		{"many expired", time.Second, time.Minute, args{10 * time.Second, 5, 10}, 5 * time.Second},
+		{"some expired", time.Second, time.Minute, args{10 * time.Second, 1, 10}, 10 * time.Second},
+		{"none expired", time.Second, time.Minute, args{10 * time.Second, 0, 10}, 20 * time.Second},
+		{"empty", time.Second, time.Minute, args{10 * time.Second, 0, 0}, 20 * time.Second},
+		{"clamp min", time.Second, time.Minute, args{time.Second, 10, 10}, time.Second},
+		{"clamp max", time.Second, time.Minute, args{time.Minute, 0, 10}, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := &cleaner[string, string]{minInterval: tt.min, maxInterval: tt.max}
+			assert.Equalf(t, tt.want, cl.nextInterval(tt.args.interval, tt.args.removed, tt.args.scanned), "nextInterval(%v, %v, %v)", tt.args.interval, tt.args.removed, tt.args.scanned)
+		})
+	}
+}
+
+func Test_cleaner_jittered(t *testing.T) {
+	t.Parallel()
+
+	cl := &cleaner[string, string]{}
+	assert.Equal(t, time.Second, cl.jittered(time.Second))
+
+	cl.jitter = 0.1
+	var varied bool
+	for i := 0; i < 100; i++ {
+		d := cl.jittered(time.Second)
+		assert.GreaterOrEqual(t, d, 900*time.Millisecond)
+		assert.LessOrEqual(t, d, 1100*time.Millisecond)
+		varied = varied || d != time.Second
+	}
+	assert.True(t, varied)
+}
+
+func TestCleaningCache_Jitter(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value", nil
+	}
+	cache, err := New[string, string](replaceFn, 50*time.Millisecond, 50*time.Millisecond,
+		WithCleanupInterval(100*time.Millisecond), WithCleanupJitter(0.5))
+	assert.NoError(t, err)
+
+	_, _ = cache.Get(context.Background(), "k1")
+	assert.Equal(t, 1, cache.Len())
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, 0, cache.Len())
+
+	_, err = New[string, string](replaceFn, 0, 0, WithCleanupJitter(1))
+	assert.Error(t, err)
+	_, err = New[string, string](replaceFn, 0, 0, WithCleanupJitter(-0.1))
+	assert.Error(t, err)
+}
+
+func TestCleaningCache_BatchSize(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(100) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			var elapsed atomic.Int64
+			now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			var expired atomic.Int64
+			cache, err := New[string, string](replaceFn, time.Second, time.Second, append(c.cacheOpts,
+				WithClock(now), WithCleanupInterval(0), WithCleanupBatchSize(7),
+				WithExpirationCallback(func(key string, v string) { expired.Add(1) }))...)
+			assert.NoError(t, err)
+
+			for i := 0; i < 50; i++ {
+				_, _ = cache.Get(context.Background(), strconv.Itoa(i))
+			}
+			elapsed.Add(int64(2 * time.Second))
+			for i := 50; i < 100; i++ {
+				_, _ = cache.Get(context.Background(), strconv.Itoa(i))
+			}
+
+			removed, scanned := cache.cleanup()
+			assert.GreaterOrEqual(t, scanned, 100)
+			assert.Less(t, scanned, 100+7)
+			assert.EqualValues(t, removed, expired.Load())
+			if strings.HasSuffix(c.name, "map cache") {
+				// map backend visits random runs of items - all items are not necessarily visited
+				assert.Positive(t, removed)
+				cache.DeleteExpired()
+			} else {
+				assert.Equal(t, 50, removed)
+			}
+			assert.Equal(t, 50, cache.Len())
+			assert.EqualValues(t, 50, expired.Load())
+		})
+	}
+
+	fn := func(ctx context.Context, key string) (string, error) { return "", nil }
+	_, err := New[string, string](fn, 0, 0, WithCleanupBatchSize(-1))
+	assert.Error(t, err)
+}
+
+func TestCache_WithContext(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, 50*time.Millisecond,
+				append(c.cacheOpts, WithContext(ctx), WithCleanupInterval(100*time.Millisecond), WithStatsHistory(100*time.Millisecond, 10))...)
+			assert.NoError(t, err)
+			assert.True(t, cache.HasCleaner())
+
+			cancel()
+			assert.False(t, cache.HasCleaner())
+			time.Sleep(50 * time.Millisecond) // wait for background goroutines to stop
+
+			// cache is still usable, but expired items are not cleaned up
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k1", v)
+			time.Sleep(250 * time.Millisecond)
+			assert.Equal(t, 1, cache.Len())
+			assert.Empty(t, cache.StatsHistory())
+
+			cache.Close() // does not block
+		})
+	}
+}
+
 // TestCleaningCacheFinalizer tests that cache finalizers to stop cleaner is working.
 // Since there's not really a good way of ensuring call to the finalizer, this just increases the test coverage.
 func TestCleaningCacheFinalizer(t *testing.T) {
@@ -1184,3 +3806,34 @@ func TestCleaningCacheFinalizer(t *testing.T) {
 		})
 	}
 }
+
+func TestCache_Close(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, 50*time.Millisecond,
+				append(c.cacheOpts, WithCleanupInterval(100*time.Millisecond), WithStatsHistory(100*time.Millisecond, 10))...)
+			assert.NoError(t, err)
+			assert.True(t, cache.HasCleaner())
+
+			cache.Close()
+			cache.Close() // does not panic or block
+			assert.False(t, cache.HasCleaner())
+
+			// cache is still usable, but expired items are not cleaned up
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k1", v)
+			time.Sleep(250 * time.Millisecond)
+			assert.Equal(t, 1, cache.Len())
+			assert.Empty(t, cache.StatsHistory())
+		})
+	}
+}
@@ -1,15 +1,30 @@
 package sc
 
 import (
-	"sync"
+	"github.com/motoki317/sc/internal/flight"
 )
 
 // call is an in-flight or completed cache replacement call.
 type call[V any] struct {
-	wg sync.WaitGroup
+	*flight.Call
 
-	// These fields are written once before the WaitGroup is done
-	// and are only read after the WaitGroup is done.
+	// These fields are written once before the call completes
+	// and are only read after the call completes.
 	val value[V]
 	err error
+
+	// deadline is the time by which the retrieved value must expire, if hasDeadline is true.
+	// These fields are written before the call is started.
+	deadline    monoTime
+	hasDeadline bool
+	// fn is the replaceFunc[K, V] overriding replaceFn of the cache, if non-nil. See GetWith.
+	// This field is written before the call is started.
+	fn any
+
+	// waiters is the number of callers which joined the call. Protected by the cache's mu.
+	waiters int
+	// borrowers is the number of GetRef callers waiting for the call, and lease is the borrow of the value
+	// taken on their behalf when the value is stored. Protected by the cache's mu.
+	borrowers int
+	lease     *lease[V]
 }
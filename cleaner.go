@@ -1,7 +1,7 @@
 package sc
 
 import (
-	"runtime"
+	"math/rand"
 	"time"
 )
 
@@ -11,37 +11,98 @@ import (
 // See https://github.com/patrickmn/go-cache/blob/46f407853014144407b6c2ec7ccc76bf67958d93/cache.go#L1115 for more on this design.
 type cleaner[K comparable, V any] struct {
 	closer chan struct{}
-	c      *cache[K, V]
+	// done is closed when the context given by WithContext is done. nil if not given.
+	done <-chan struct{}
+	c    *cache[K, V]
+	// minInterval and maxInterval bound the interval when adaptive cleanup is enabled.
+	// Both are zero if adaptive cleanup is disabled.
+	minInterval, maxInterval time.Duration
+	// jitter is the fraction of the interval to randomly add or subtract on each cleanup. See WithCleanupJitter.
+	jitter float64
 }
 
-func startCleaner[K comparable, V any](c *Cache[K, V], interval time.Duration) {
+func startCleaner[K comparable, V any](c *cache[K, V], config cacheConfig) *cleaner[K, V] {
 	cl := &cleaner[K, V]{
-		closer: make(chan struct{}),
-		c:      c.cache,
+		closer:      make(chan struct{}),
+		done:        config.done(),
+		c:           c,
+		minInterval: config.adaptiveCleanupMin,
+		maxInterval: config.adaptiveCleanupMax,
+		jitter:      config.cleanupJitter,
 	}
-	go cl.run(interval)
-	runtime.SetFinalizer(c, stopCleaner(cl))
+	go cl.run(cl.clamp(config.cleanupInterval))
+	return cl
 }
 
 func (cl *cleaner[K, V]) run(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	timer := time.NewTimer(cl.jittered(interval))
+	defer timer.Stop()
 	for {
 		select {
-		case <-ticker.C:
-			cl.c.cleanup()
+		case <-timer.C:
+			removed, scanned := cl.c.cleanup()
+			interval = cl.nextInterval(interval, removed, scanned)
+			timer.Reset(cl.jittered(interval))
 		case <-cl.closer:
 			return
+		case <-cl.done:
+			return
 		}
 	}
 }
 
-func (cl *cleaner[K, V]) stop() {
-	cl.closer <- struct{}{}
+func (cl *cleaner[K, V]) adaptive() bool {
+	return cl.maxInterval > 0
+}
+
+// nextInterval computes the interval until the next cleanup, given the result of the last cleanup.
+//
+// If adaptive cleanup is enabled, the interval is halved when a large portion of the scanned items were expired,
+// and doubled when almost none were, within [minInterval, maxInterval].
+func (cl *cleaner[K, V]) nextInterval(interval time.Duration, removed, scanned int) time.Duration {
+	if !cl.adaptive() {
+		return interval
+	}
+
+	const (
+		shrinkRatio = 0.25
+		growRatio   = 0.05
+	)
+	var ratio float64
+	if scanned > 0 {
+		ratio = float64(removed) / float64(scanned)
+	}
+	switch {
+	case ratio >= shrinkRatio:
+		interval /= 2
+	case ratio < growRatio:
+		interval *= 2
+	}
+	return cl.clamp(interval)
 }
 
-func stopCleaner[K comparable, V any](cl *cleaner[K, V]) func(*Cache[K, V]) {
-	return func(_ *Cache[K, V]) {
-		cl.stop()
+func (cl *cleaner[K, V]) clamp(interval time.Duration) time.Duration {
+	if !cl.adaptive() {
+		return interval
 	}
+	if interval < cl.minInterval {
+		return cl.minInterval
+	}
+	if interval > cl.maxInterval {
+		return cl.maxInterval
+	}
+	return interval
+}
+
+// jittered returns interval randomly shifted by up to ±jitter * interval, so that cleaners of caches created
+// at the same time do not run in lockstep.
+func (cl *cleaner[K, V]) jittered(interval time.Duration) time.Duration {
+	if cl.jitter == 0 {
+		return interval
+	}
+	return interval + time.Duration((2*rand.Float64()-1)*cl.jitter*float64(interval))
+}
+
+func (cl *cleaner[K, V]) stop() {
+	close(cl.closer)
 }
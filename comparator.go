@@ -0,0 +1,50 @@
+package sc
+
+import (
+	"context"
+)
+
+// Comparator runs two caches side by side on the same accesses, to compare their performance (e.g. hit ratios)
+// on real access patterns before choosing one. See NewComparator.
+type Comparator[K comparable, V any] struct {
+	primary, shadow *Cache[K, V]
+}
+
+// NewComparator creates a new Comparator, which serves values from primary while feeding the same accesses to shadow.
+//
+// replaceFn of shadow is never called: shadow is populated with the values loaded by primary instead,
+// so that the data source is not loaded twice. shadow should therefore be created with the same key and value types,
+// but with a different backend or capacity to be compared, e.g.
+//
+//	primary, _ := sc.New[string, *Data](retrieveData, time.Minute, time.Minute, sc.WithLRUBackend(1000))
+//	shadow, _ := sc.New[string, *Data](retrieveData, time.Minute, time.Minute, sc.With2QBackend(1000))
+//	comparator := sc.NewComparator(primary, shadow)
+//
+// Note that stale values in shadow are not refreshed until they expire, since shadow never calls replaceFn.
+func NewComparator[K comparable, V any](primary, shadow *Cache[K, V]) *Comparator[K, V] {
+	return &Comparator[K, V]{primary: primary, shadow: shadow}
+}
+
+// Get retrieves an item from primary, just like (*Cache).Get.
+//
+// The access is also recorded to shadow: shadow counts a hit if it has the item, and otherwise stores the item
+// retrieved by primary.
+func (c *Comparator[K, V]) Get(ctx context.Context, key K) (V, error) {
+	_, shadowHit := c.shadow.GetIfExists(key)
+	v, err := c.primary.Get(ctx, key)
+	if err == nil && !shadowHit {
+		c.shadow.Set(key, v)
+	}
+	return v, err
+}
+
+// PrimaryStats returns the metrics of primary.
+func (c *Comparator[K, V]) PrimaryStats() Stats {
+	return c.primary.Stats()
+}
+
+// ShadowStats returns the metrics of shadow, as if shadow had served the accesses.
+// Note that Replacements is always 0 for shadow, since shadow never calls replaceFn.
+func (c *Comparator[K, V]) ShadowStats() Stats {
+	return c.shadow.Stats()
+}
@@ -0,0 +1,47 @@
+package sc
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparator(t *testing.T) {
+	t.Parallel()
+
+	var primaryCnt, shadowCnt int64
+	primary, err := New[string, string](func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt64(&primaryCnt, 1)
+		return "value-" + key, nil
+	}, time.Minute, time.Minute, WithMapBackend(0))
+	assert.NoError(t, err)
+	shadow, err := New[string, string](func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt64(&shadowCnt, 1)
+		return "shadow-" + key, nil
+	}, time.Minute, time.Minute, WithLRUBackend(2))
+	assert.NoError(t, err)
+	comparator := NewComparator(primary, shadow)
+
+	// k0, k1, k2, k0, k1, k2: the map holds all keys, while LRU of capacity 2 always misses
+	for i := 0; i < 6; i++ {
+		key := "k" + strconv.Itoa(i%3)
+		v, err := comparator.Get(context.Background(), key)
+		assert.NoError(t, err)
+		assert.Equal(t, "value-"+key, v)
+	}
+	assert.EqualValues(t, 3, primaryCnt)
+	assert.EqualValues(t, 0, shadowCnt)
+
+	assert.Equal(t, HitStats{3, 0, 3, 3, 0, 0}, comparator.PrimaryStats().HitStats)
+	assert.Equal(t, HitStats{0, 0, 6, 0, 0, 4}, comparator.ShadowStats().HitStats)
+	assert.Equal(t, 2, comparator.ShadowStats().Size)
+
+	// shadow is populated with the values of primary
+	v, ok := shadow.Peek("k2")
+	assert.True(t, ok)
+	assert.Equal(t, "value-k2", v)
+}
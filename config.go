@@ -1,6 +1,7 @@
 package sc
 
 import (
+	"context"
 	"time"
 )
 
@@ -9,10 +10,53 @@ import (
 type CacheOption func(c *cacheConfig)
 
 type cacheConfig struct {
-	enableStrictCoalescing bool
-	backend                cacheBackendType
-	capacity               int
-	cleanupInterval        time.Duration
+	ctx                       context.Context
+	enableStrictCoalescing    bool
+	noGracefulRefresh         bool
+	noExpiration              bool
+	backend                   cacheBackendType
+	capacity                  int
+	maxWeight                 int64
+	weigher                   any // func(key K, v V) int64 of the cache's key and value types
+	evictionPolicy            EvictionPolicy
+	cleanupInterval           time.Duration
+	adaptiveCleanupMin        time.Duration
+	adaptiveCleanupMax        time.Duration
+	cleanupJitter             float64
+	statsDisabled             bool
+	replacementPercentiles    bool
+	freshnessJitter           float64
+	cleanupBatchSize          int
+	proactiveThreshold        float64
+	maxConcurrentReplacements int
+	maxWaitersPerKey          int
+	retryAttempts             int
+	retryBackoff              func(attempt int) time.Duration
+	replacementTimeout        time.Duration
+	recoverPanics             bool
+	backgroundContext         func(parent context.Context) context.Context
+	clock                     func() time.Time
+	statsHistoryInterval      time.Duration
+	statsHistorySamples       int
+	batchWindow               time.Duration
+	maxBatchSize              int
+	trackFailureRates         bool
+	errTTL                    time.Duration
+	dispose                   any // func(v V) of the cache's value type
+	durationFn                any // func(key K) (freshFor, ttl time.Duration) of the cache's key type
+	ttlFn                     any // func(key K, v V) (freshFor, ttl time.Duration) of the cache's key and value types
+	onEvict                   any // func(key K, v V) of the cache's key and value types
+	onExpire                  any // func(key K, v V) of the cache's key and value types
+	onRemove                  any // func(key K, v V, reason RemovalReason) of the cache's key and value types
+	onReplacement             any // func(key K, v V, err error, d time.Duration) of the cache's key and value types
+	publishInvalidation       any // func(key K) of the cache's key type
+	metrics                   any // MetricsHook[K] of the cache's key type
+	lowerTier                 any // lowerTier[K, V] of the cache's key and value types
+	sizeThreshold             int
+	sizeThresholdHook         func(size int)
+	shards                    int
+	hasher                    any // func(key K) uint64 of the cache's key type
+	keyNormalizer             any // func(key K) K of the cache's key type
 }
 
 type cacheBackendType int
@@ -21,8 +65,50 @@ const (
 	cacheBackendMap cacheBackendType = iota
 	cacheBackendLRU
 	cacheBackend2Q
+	cacheBackendWeightedLRU
+	cacheBackendFIFO
+	// cacheBackendAuto selects the backend from the capacity and the eviction policy. See WithCapacity.
+	cacheBackendAuto
 )
 
+// String returns the name of the backend, as returned by (*Cache).BackendType.
+func (t cacheBackendType) String() string {
+	switch t {
+	case cacheBackendMap:
+		return "map"
+	case cacheBackendLRU:
+		return "lru"
+	case cacheBackend2Q:
+		return "2q"
+	case cacheBackendWeightedLRU:
+		return "weighted-lru"
+	case cacheBackendFIFO:
+		return "fifo"
+	case cacheBackendAuto:
+		return "auto"
+	default:
+		return "unknown"
+	}
+}
+
+// EvictionPolicy represents a policy to evict items from a cache with bounded capacity.
+type EvictionPolicy int
+
+const (
+	// EvictionPolicy2Q evicts items with 2Q (Two Queue) algorithm. This is the default policy.
+	EvictionPolicy2Q EvictionPolicy = iota
+	// EvictionPolicyLRU evicts the least recently used items.
+	EvictionPolicyLRU
+)
+
+// done returns the channel closed when the context given by WithContext is done, or nil if not given.
+func (c *cacheConfig) done() <-chan struct{} {
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Done()
+}
+
 func defaultConfig(ttl time.Duration) cacheConfig {
 	cleanupInterval := 2 * ttl
 	if ttl == 0 {
@@ -54,7 +140,7 @@ func WithMapBackend(initialCapacity int) CacheOption {
 }
 
 // WithLRUBackend specifies to use LRU for storing cache items.
-// Capacity needs to be greater than 0.
+// Capacity needs to be greater than 0, otherwise New returns ErrInvalidLRUCapacity.
 func WithLRUBackend(capacity int) CacheOption {
 	return func(c *cacheConfig) {
 		c.backend = cacheBackendLRU
@@ -62,8 +148,40 @@ func WithLRUBackend(capacity int) CacheOption {
 	}
 }
 
+// WithFIFOBackend specifies to use FIFO (First In, First Out) for storing cache items.
+// When the cache is full, the oldest inserted item is evicted regardless of how recently it was used.
+//
+// Unlike LRU, accessing items does not update their order, making FIFO cheaper than LRU for workloads where
+// recency matters little, such as write-once-read-many data. Unlike the map backend, the number of items is bounded.
+// Capacity needs to be greater than 0, otherwise New returns ErrInvalidFIFOCapacity.
+func WithFIFOBackend(capacity int) CacheOption {
+	return func(c *cacheConfig) {
+		c.backend = cacheBackendFIFO
+		c.capacity = capacity
+	}
+}
+
+// WithMaxWeight specifies to use LRU bounded by the total weight of the items, instead of the number of items.
+// This is useful when the sizes of values vary a lot, and the number of items is a poor proxy for memory usage.
+//
+// weigher returns the weight of an item, such as the approximate memory size of the value in bytes.
+// weigher is called each time an item is stored, and needs to be fast and return a non-negative weight.
+// When the total weight exceeds maxWeight, the least recently used items are evicted until the total weight fits.
+// An item heavier than maxWeight by itself is evicted immediately after being stored, without evicting the other items.
+// The current total weight is reported by SizeStats.Weight.
+//
+// maxWeight needs to be greater than 0. The type parameters K and V need to be the key and value types of the cache,
+// otherwise New returns an error.
+func WithMaxWeight[K comparable, V any](maxWeight int64, weigher func(key K, v V) int64) CacheOption {
+	return func(c *cacheConfig) {
+		c.backend = cacheBackendWeightedLRU
+		c.maxWeight = maxWeight
+		c.weigher = weigher
+	}
+}
+
 // With2QBackend specifies to use 2Q cache for storing cache items.
-// Capacity needs to be greater than 0.
+// Capacity needs to be greater than 0, otherwise New returns ErrInvalid2QCapacity.
 func With2QBackend(capacity int) CacheOption {
 	return func(c *cacheConfig) {
 		c.backend = cacheBackend2Q
@@ -71,6 +189,28 @@ func With2QBackend(capacity int) CacheOption {
 	}
 }
 
+// WithCapacity specifies the maximum number of items in the cache, selecting the backend accordingly.
+//
+// If capacity is 0 or less, the cache is unbounded, using the built-in map backend (see WithMapBackend).
+// If capacity is greater than 0, the cache is bounded, using the backend of the eviction policy
+// specified by WithEvictionPolicy (2Q by default).
+//
+// This is an alternative to choosing among WithMapBackend, WithLRUBackend, and With2QBackend.
+func WithCapacity(capacity int) CacheOption {
+	return func(c *cacheConfig) {
+		c.backend = cacheBackendAuto
+		c.capacity = capacity
+	}
+}
+
+// WithEvictionPolicy specifies the eviction policy used when the cache is bounded by WithCapacity.
+// The policy has no effect if the cache is unbounded, or if the backend is explicitly specified by other options.
+func WithEvictionPolicy(policy EvictionPolicy) CacheOption {
+	return func(c *cacheConfig) {
+		c.evictionPolicy = policy
+	}
+}
+
 // EnableStrictCoalescing enables 'strict coalescing check' with a slight overhead. The check prevents Get() calls
 // coming later in time to be coalesced with already stale response generated by a Get() call earlier in time.
 //
@@ -141,6 +281,176 @@ func EnableStrictCoalescing() CacheOption {
 	}
 }
 
+// WithNoGracefulRefresh specifies to never serve stale values from Get, GetMulti, and TryGet.
+// When a stored value is stale, Get (and GetMulti) synchronously retrieves a new value just like when the value
+// is expired, instead of serving the stale value while updating it in the background. TryGet reports a miss
+// and triggers the retrieval in the background.
+//
+// Unlike EnableStrictCoalescing, callers joining an ongoing retrieval are served its result as is,
+// without waiting for another retrieval even if the result became stale while they were waiting.
+//
+// Stale values are still kept until ttl passes, even if ttl is longer than freshFor.
+// They can be read by GetIfExists, Peek, and GetMostRecent, e.g. to serve a fallback when Get fails.
+// If you don't need them, specify the same ttl as freshFor to free memory earlier.
+// Proactive updates of aging values (see WithProactiveThreshold) are not affected by this option.
+func WithNoGracefulRefresh() CacheOption {
+	return func(c *cacheConfig) {
+		c.noGracefulRefresh = true
+	}
+}
+
+// WithNoExpiration specifies to keep stored values fresh forever, regardless of freshFor and ttl given to New.
+// replaceFn is called only once per key, until the key is forgotten (by Forget, Purge, and so on) or evicted.
+//
+// This is useful for caching immutable data, such as reference data which never changes once loaded.
+// Durations given to (*Cache).GetWithTTL and deadlines given by WithCallValidUntil still apply to the values
+// retrieved by the call.
+func WithNoExpiration() CacheOption {
+	return func(c *cacheConfig) {
+		c.noExpiration = true
+	}
+}
+
+// WithProactiveThreshold specifies to proactively update values which are about to become stale.
+//
+// Values older than fraction * freshFor (but still fresh) are considered 'aging'.
+// Get on an aging value returns the value as a normal (fresh) hit, and at the same time launches a single goroutine
+// in the background to retrieve a new value, just like it does for stale values.
+// This keeps frequently accessed values perpetually fresh.
+//
+// fraction needs to be between 0 and 1. Setting fraction of 0 (the default) or 1 disables proactive update.
+func WithProactiveThreshold(fraction float64) CacheOption {
+	return func(c *cacheConfig) {
+		c.proactiveThreshold = fraction
+	}
+}
+
+// WithMaxConcurrentReplacements limits the number of concurrent replaceFn calls.
+//
+// Since calls are coalesced per key, this effectively limits the number of distinct keys being retrieved at once.
+// Calls exceeding the limit wait until other calls finish.
+// Callers of (*Cache).Get and (*Cache).Refresh stop waiting and return ctx.Err() when their ctx is done;
+// the value is still retrieved in the background if other callers are waiting for it.
+// Use (*Cache).TryGetOrError to fail fast instead of waiting.
+//
+// Setting n of 0 (the default) means no limit. n needs to be non-negative.
+func WithMaxConcurrentReplacements(n int) CacheOption {
+	return func(c *cacheConfig) {
+		c.maxConcurrentReplacements = n
+	}
+}
+
+// WithContext binds the lifecycle of the background goroutines of the cache, such as the cleaner, to ctx.
+//
+// The background goroutines stop when ctx is done, in addition to when (*Cache).Close is called or the cache is
+// garbage collected. The cache itself is still usable after ctx is done - see (*Cache).Close.
+func WithContext(ctx context.Context) CacheOption {
+	return func(c *cacheConfig) {
+		c.ctx = ctx
+	}
+}
+
+// WithClock sets the time source of the cache, which is time.Now by default.
+//
+// The clock determines the freshness and expiration of items, so that tests of code using the cache can cross
+// freshFor and ttl boundaries by advancing a fake clock instead of sleeping.
+// The clock needs to be safe for concurrent use, and should never go backwards.
+//
+// Note that the background goroutines of the cache, such as the cleaner, are still scheduled by the real time,
+// though the cleaner judges expiration of items by the clock.
+// Disable the cleaner with WithCleanupInterval(0) for fully deterministic tests, and call (*Cache).DeleteExpired
+// to clean up expired items instead.
+// Durations measured for metrics, such as Stats.ReplacementDuration, also use the real time.
+func WithClock(now func() time.Time) CacheOption {
+	return func(c *cacheConfig) {
+		c.clock = now
+	}
+}
+
+// WithRetry retries replaceFn (or the batch function of NewBatched) on error,
+// calling it at most attempts times in total for a single retrieval.
+//
+// Callers coalesced to the retrieval share the retries, and receive the final result or the last error.
+// backoff returns the duration to wait before the next attempt, given the number of failed attempts so far
+// (starting from 1). A nil backoff retries immediately.
+// Retries stop as soon as the context passed to replaceFn is done, and errors wrapping ErrNotFound are never retried.
+//
+// Note that the retrieval keeps its replacement slot (see WithMaxConcurrentReplacements) while waiting for retries.
+// Each attempt counts as a replacement in Stats.
+//
+// Setting attempts of 0 or 1 (the default) disables retries. attempts needs to be non-negative.
+func WithRetry(attempts int, backoff func(attempt int) time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.retryAttempts = attempts
+		c.retryBackoff = backoff
+	}
+}
+
+// WithReplacementTimeout sets the timeout of a single retrieval by replaceFn (or the batch function of NewBatched).
+//
+// The context passed to replaceFn is done after d, so that a stuck upstream fails the retrieval instead of blocking
+// all callers coalesced to it forever. replaceFn needs to respect the context for the timeout to take effect.
+// The timeout covers all attempts of the retrieval if WithRetry is also given.
+//
+// Note that, even on the synchronous path of (*Cache).Get, the context passed to replaceFn is never canceled by
+// the caller-supplied ctx, since other callers may be coalesced to the same retrieval. Its values are still
+// inherited. The timeout is the only way to bound the retrieval.
+//
+// Setting d of 0 (the default) means no timeout. d needs to be non-negative.
+func WithReplacementTimeout(d time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.replacementTimeout = d
+	}
+}
+
+// WithRecoverReplaceFn recovers from panics in replaceFn (or the batch function of NewBatched), instead of crashing
+// the process. This is useful since replaceFn may run in a goroutine launched by the cache, where the caller cannot
+// recover from the panic by itself.
+//
+// A recovered panic is converted into *PanicError holding the panicked value and the stack trace, which is returned to
+// all callers coalesced to the retrieval. errors.Is(err, ErrReplacePanic) reports true for such errors.
+// Nothing is stored in the cache for the retrieval: the retrieval is neither retried (see WithRetry) nor cached as
+// an error (see WithNegativeCache). The error is still reported to MetricsHook.OnReplacement.
+//
+// Without this option, panics in replaceFn are not recovered. The callers waiting for the retrieval still receive
+// *PanicError instead of waiting forever, while the panic propagates in the goroutine which called replaceFn.
+func WithRecoverReplaceFn() CacheOption {
+	return func(c *cacheConfig) {
+		c.recoverPanics = true
+	}
+}
+
+// WithBackgroundContext sets the function deriving the context passed to replaceFn for background refreshes,
+// from the context of the call which triggered the refresh.
+//
+// Background refreshes are triggered by (*Cache).Get serving stale values, proactive updates (see
+// WithProactiveThreshold), and (*Cache).Notify. By default, the context is derived with context.WithoutCancel,
+// inheriting the values (such as trace IDs) of the triggering call but not its cancellation and deadline.
+// Use this option to e.g. start a new trace span linked to the triggering one.
+//
+// fn must not return a context canceled together with parent, since the refresh outlives the triggering call.
+// Consider WithReplacementTimeout to bound the refresh, instead of returning a context with a deadline.
+// Note that fn is not used for batched refreshes of NewBatched, which are shared by multiple triggering calls.
+func WithBackgroundContext(fn func(parent context.Context) context.Context) CacheOption {
+	return func(c *cacheConfig) {
+		c.backgroundContext = fn
+	}
+}
+
+// WithMaxWaitersPerKey limits the number of callers waiting for a single ongoing replacement of a key.
+//
+// When a key is slow to retrieve and many callers pile up waiting for it, the callers exceeding the limit
+// immediately fail with ErrTooManyWaiters instead of waiting, shedding the load.
+// The caller which initiated the replacement is not counted.
+// Stale values are served without waiting as usual, so this affects only callers which need to wait.
+//
+// Setting n of 0 (the default) means no limit. n needs to be non-negative.
+func WithMaxWaitersPerKey(n int) CacheOption {
+	return func(c *cacheConfig) {
+		c.maxWaitersPerKey = n
+	}
+}
+
 // WithCleanupInterval specifies cleanup interval of expired items.
 //
 // Setting interval of 0 (or negative) will disable the cleaner.
@@ -157,3 +467,357 @@ func WithCleanupInterval(interval time.Duration) CacheOption {
 		c.cleanupInterval = interval
 	}
 }
+
+// WithAdaptiveCleanup lets the cleaner adapt its interval to how many items expire, within [min, max].
+//
+// After each cleanup, the interval is shortened if a large portion of the scanned items were expired,
+// and lengthened if almost none were. This keeps memory usage low under churn,
+// while the cleaner idles cheaply when the cache is stable.
+//
+// The cleaner starts with the interval specified by WithCleanupInterval (or the default), clamped to [min, max].
+// This option has no effect if the cleaner is disabled.
+//
+// min needs to be greater than 0 and no longer than max.
+func WithAdaptiveCleanup(min, max time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.adaptiveCleanupMin = min
+		c.adaptiveCleanupMax = max
+	}
+}
+
+// WithCleanupJitter randomly shifts each cleanup interval by up to ±fraction of the interval.
+//
+// This prevents cleaners of many caches created at the same time (e.g. at process start) from running in lockstep,
+// which may otherwise cause periodic latency spikes. The jitter is computed anew for each cleanup.
+// This option has no effect if the cleaner is disabled.
+//
+// fraction needs to be in [0, 1). Setting fraction of 0 (the default) disables jitter.
+func WithCleanupJitter(fraction float64) CacheOption {
+	return func(c *cacheConfig) {
+		c.cleanupJitter = fraction
+	}
+}
+
+// WithFreshnessJitter randomly shortens freshFor of each stored item by up to fraction of freshFor.
+//
+// Without jitter, items stored at the same time (e.g. loaded in a burst at process start) become stale at the same
+// time, and their refreshes hit the data source all at once. With this option, the refreshes are spread over time.
+// The jitter is computed anew each time an item is stored, and does not affect ttl of the item.
+// Items which never become stale (see WithNoExpiration) are not affected.
+//
+// fraction needs to be in [0, 1). Setting fraction of 0 (the default) disables jitter.
+func WithFreshnessJitter(fraction float64) CacheOption {
+	return func(c *cacheConfig) {
+		c.freshnessJitter = fraction
+	}
+}
+
+// WithCleanupBatchSize makes the cleaner incremental, scanning at most n items while holding the lock.
+//
+// By default, the cleaner scans all items at once while holding the lock, which blocks other methods such as Get
+// for a noticeable time in caches with millions of items. With this option, each cleanup scans the items in rounds
+// of at most n items, releasing the lock between the rounds.
+//
+// Each cleanup scans roughly as many items as the cache holds. For the LRU and 2Q backends, the rounds visit
+// the items in turn, from the least recently used ones. For the map backend, each round visits a random run of items
+// since iteration over a map cannot be resumed: expired items are removed over the course of a few cleanups
+// rather than all at once. DeleteExpired always scans all items at once.
+//
+// Setting n of 0 (the default) disables incremental cleanup. n needs to be non-negative.
+func WithCleanupBatchSize(n int) CacheOption {
+	return func(c *cacheConfig) {
+		c.cleanupBatchSize = n
+	}
+}
+
+// WithStatsDisabled disables tracking of HitStats, saving the atomic increments in Get and other methods.
+// This is useful for extremely hot paths which never look at the hit metrics.
+//
+// With this option, HitStats (returned by Stats or HitStats) are always zero, and Stats.HitStatsDisabled is true.
+// Other metrics such as SizeStats and ReplacementStats are still tracked, and hooks such as WithMetricsHook are still
+// called.
+func WithStatsDisabled() CacheOption {
+	return func(c *cacheConfig) {
+		c.statsDisabled = true
+	}
+}
+
+// WithReplacementPercentiles records the time spent in each replaceFn call in a histogram,
+// so that Stats reports the percentiles of the durations (see ReplacementStats) to observe the tail latency.
+//
+// The histogram takes about 4KB of memory per cache (or per shard of ShardedCache), and recording a duration costs
+// a single atomic increment. The reported percentiles have a relative error of at most 12.5%.
+func WithReplacementPercentiles() CacheOption {
+	return func(c *cacheConfig) {
+		c.replacementPercentiles = true
+	}
+}
+
+// WithStatsHistory records a snapshot of Stats every interval, keeping the latest samples snapshots in memory.
+//
+// Recorded snapshots can be retrieved via (*Cache).StatsHistory, which is useful for observing recent trend of
+// the cache metrics without external monitoring systems.
+//
+// Snapshots are recorded by a single goroutine, which is stopped when (*Cache).Close is called,
+// the context given by WithContext is done, or the Cache is garbage collected.
+// Both interval and samples need to be greater than 0.
+func WithStatsHistory(interval time.Duration, samples int) CacheOption {
+	return func(c *cacheConfig) {
+		c.statsHistoryInterval = interval
+		c.statsHistorySamples = samples
+	}
+}
+
+// WithBatchWindow lets a cache created by NewBatched collect keys to be retrieved within window,
+// and retrieve them together with a single call to the batch function.
+//
+// The first key to be retrieved starts a new batch, and the batch is retrieved after window passes,
+// or as soon as it has maxSize keys. Setting maxSize of 0 means no limit.
+// Keys are still coalesced per key - keys already being retrieved are not added to the batch.
+// Note that this adds up to window of latency to every retrieval.
+//
+// The batch function is called with the context of the caller which started the batch.
+// This option has no effect on caches created by New, or on (*Cache).TryGetOrError calls.
+//
+// Both window and maxSize need to be non-negative. Setting window of 0 (the default) disables collecting keys.
+func WithBatchWindow(window time.Duration, maxSize int) CacheOption {
+	return func(c *cacheConfig) {
+		c.batchWindow = window
+		c.maxBatchSize = maxSize
+	}
+}
+
+// WithEvictionCallback calls onEvict whenever the backend evicts an item to make room for a new item,
+// that is, only when the cache is bounded by capacity (see WithCapacity). The map backend never evicts items.
+// Items removed for other reasons, such as expiration or Forget, are not reported. See WithRemovalCallback for those.
+//
+// onEvict is called while holding the internal lock of the cache: onEvict must not call methods of the cache,
+// otherwise it deadlocks. onEvict also needs to be fast, since it blocks all other operations of the cache.
+//
+// The type parameters K and V need to be the key and value types of the cache, otherwise New returns an error.
+func WithEvictionCallback[K comparable, V any](onEvict func(key K, v V)) CacheOption {
+	return func(c *cacheConfig) {
+		c.onEvict = onEvict
+	}
+}
+
+// WithExpirationCallback calls onExpire for each expired item removed by the cleaner (see WithCleanupInterval),
+// or by (*Cache).DeleteExpired.
+// onExpire is called only for items actually removed from the cache, not for items merely inspected by the cleaner.
+// Note that expired items may also be replaced by new items before the cleaner runs, which are not reported.
+//
+// onExpire is called from the cleaner goroutine (or the caller of DeleteExpired) after releasing the internal lock
+// of the cache, so it may be slow, and may call methods of the cache. A slow onExpire only delays the next cleanup.
+//
+// The type parameters K and V need to be the key and value types of the cache, otherwise New returns an error.
+func WithExpirationCallback[K comparable, V any](onExpire func(key K, v V)) CacheOption {
+	return func(c *cacheConfig) {
+		c.onExpire = onExpire
+	}
+}
+
+// WithRemovalCallback calls onRemove for each item leaving the cache, with the reason of the removal.
+// Unlike WithEvictionCallback and WithExpirationCallback, a single callback reports all kinds of removals:
+// evictions (including by Resize), expirations, Forget and its variants, replacement by a new item for the same key,
+// and Purge or Flush. See RemovalReason for details.
+// Note that an expired item is reported as replaced or evicted if that happens before the cleaner removes it.
+//
+// onRemove is called after releasing the internal lock of the cache, by the goroutine which removed the item,
+// so it may call methods of the cache. However, a slow onRemove delays the method which removed the item,
+// such as Get or Set which stored a new item.
+// onRemove is called before the item is disposed (see WithDispose).
+//
+// The type parameters K and V need to be the key and value types of the cache, otherwise New returns an error.
+func WithRemovalCallback[K comparable, V any](onRemove func(key K, v V, reason RemovalReason)) CacheOption {
+	return func(c *cacheConfig) {
+		c.onRemove = onRemove
+	}
+}
+
+// WithReplacementCallback calls onReplacement each time replaceFn (or the batch function of NewBatched) returns,
+// with the retrieved value, the resulting error (nil if succeeded), and the time spent on the retrieval.
+// This is useful to log slow retrievals or errors per key without wrapping replaceFn.
+// When retries are enabled by WithRetry, onReplacement is called once after the last attempt, and d includes all
+// attempts.
+//
+// onReplacement is called after releasing the internal lock of the cache, by the goroutine which called replaceFn.
+// It is called after the value is stored and waiting callers are released, but a slow onReplacement still delays
+// the caller which triggered the retrieval (or the background refresh).
+//
+// The type parameters K and V need to be the key and value types of the cache, otherwise New returns an error.
+func WithReplacementCallback[K comparable, V any](onReplacement func(key K, v V, err error, d time.Duration)) CacheOption {
+	return func(c *cacheConfig) {
+		c.onReplacement = onReplacement
+	}
+}
+
+// WithInvalidationPublisher calls publish for each key forgotten by Forget, ForgetIf, or ForgetIfSorted,
+// so that the invalidation can be propagated to caches in other processes (e.g. via Redis pub/sub or NATS).
+// The other caches should receive the invalidation with (*Cache).ReceiveInvalidation,
+// which forgets the key without publishing it again, preventing invalidation loops.
+//
+// For ForgetIf and ForgetIfSorted, only the keys which matched the predicate in this cache are published.
+// Keys forgotten by other means (e.g. ForgetIfOlderThan, Purge, eviction, or expiration) are not published.
+//
+// publish is called after the cache releases its internal lock, so publish may call methods of the cache.
+// Note that publish is called synchronously by the forgetting method, so it should not block for long.
+//
+// The type parameter K needs to be the key type of the cache, otherwise New returns an error.
+func WithInvalidationPublisher[K comparable](publish func(key K)) CacheOption {
+	return func(c *cacheConfig) {
+		c.publishInvalidation = publish
+	}
+}
+
+// WithDurationFn determines freshFor and ttl of values per key, instead of the durations given to New.
+// This is useful when the lifetime of values is a function of the key, such as keys with a "static" prefix.
+//
+// durationFn is called each time a value for key is retrieved (or stored by Set), and needs to be fast.
+// If durationFn returns zeros, or invalid durations (negative, or freshFor longer than ttl),
+// the durations given to New are used instead.
+// Durations given to (*Cache).GetWithTTL take precedence over durationFn.
+//
+// The type parameter K needs to be the key type of the cache, otherwise New returns an error.
+func WithDurationFn[K comparable](durationFn func(key K) (freshFor, ttl time.Duration)) CacheOption {
+	return func(c *cacheConfig) {
+		c.durationFn = durationFn
+	}
+}
+
+// WithTTLFunc determines freshFor and ttl of each value from the value itself, instead of the durations given to New.
+// This is useful when the lifetime is given by the data source together with the value,
+// such as HTTP responses with Cache-Control max-age.
+//
+// ttlFn is called each time a value for key is successfully retrieved (or stored by Set or Load),
+// and needs to be fast. ttlFn must not call methods of the cache.
+// If ttlFn returns zeros, or invalid durations (negative, or freshFor longer than ttl),
+// the durations determined without ttlFn (see WithDurationFn and (*Cache).GetWithTTL) are used instead.
+// Otherwise, the durations returned by ttlFn take precedence over them.
+//
+// The type parameters K and V need to be the key and value types of the cache, otherwise New returns an error.
+func WithTTLFunc[K comparable, V any](ttlFn func(key K, v V) (freshFor, ttl time.Duration)) CacheOption {
+	return func(c *cacheConfig) {
+		c.ttlFn = ttlFn
+	}
+}
+
+// WithLowerTier puts a lower tier cache, such as a cache shared among processes, between the cache and replaceFn.
+// This allows multi-tier caching without this package depending on the lower tier implementation.
+//
+// When the cache needs to retrieve a value, get is called first. If get reports that the lower tier has the value
+// (ok == true), the value is stored in the cache without calling replaceFn. Otherwise, replaceFn is called, and the
+// successfully retrieved value is written to the lower tier with set before being stored in the cache.
+// If get returns an error, the error is treated as if returned by replaceFn (e.g. retried by WithRetry).
+// Return ok == false instead to fall back to replaceFn on errors of the lower tier.
+//
+// For caches created with NewBatched, get is called for each key, and the batch function is called once for the keys
+// missing in the lower tier. Values stored by Set or Load are not written to the lower tier.
+//
+// The type parameters K and V need to be the key and value types of the cache, otherwise New returns an error.
+func WithLowerTier[K comparable, V any](get func(ctx context.Context, key K) (v V, ok bool, err error), set func(ctx context.Context, key K, v V)) CacheOption {
+	return func(c *cacheConfig) {
+		c.lowerTier = lowerTier[K, V]{get: get, set: set}
+	}
+}
+
+// WithKeyNormalizer normalizes keys given to the methods of the cache, so that keys which normalize to the same key
+// share the same item. For example, strings.ToLower makes the cache case-insensitive.
+//
+// Items are stored under the normalized keys: replaceFn receives the normalized key, and methods returning keys
+// (e.g. Keys, GetMulti, and the predicate of ForgetIf) report the normalized keys.
+// normalize needs to be fast, idempotent, and must not call methods of the cache.
+//
+// The type parameter K needs to be the key type of the cache, otherwise New returns an error.
+func WithKeyNormalizer[K comparable](normalize func(key K) K) CacheOption {
+	return func(c *cacheConfig) {
+		c.keyNormalizer = normalize
+	}
+}
+
+// WithNegativeCache caches errors returned by replaceFn for errTTL.
+//
+// Without this option, errors are never cached, and a key which reliably fails to retrieve (e.g. a key not found
+// in the data-store) triggers replaceFn on every Get call.
+// With this option, Get returns the same error without calling replaceFn until errTTL elapses.
+// These are counted as ErrorHits in Stats.
+//
+// Errors wrapping context.Canceled or context.DeadlineExceeded are never cached,
+// since they are specific to the failed call (e.g. a timeout inside replaceFn), not to the key.
+// A cached error is cleared when a value is stored for the key, or when the key is forgotten.
+// Note that an error does not hide a value which is still in the cache: if a background update of a stale value
+// fails, the stale value continues to be served (and updated) until it expires.
+//
+// errTTL needs to be non-negative. Setting errTTL of 0 (the default) disables negative caching.
+func WithNegativeCache(errTTL time.Duration) CacheOption {
+	return func(c *cacheConfig) {
+		c.errTTL = errTTL
+	}
+}
+
+// WithDispose calls dispose exactly once for each value when it leaves the cache, that is,
+// when it is replaced by a new value, evicted, forgotten (by Forget, ForgetIf, or Purge), or cleaned up after expiration.
+// This is useful for caching values which hold resources to be released, such as connections or file handles.
+//
+// dispose is called after the cache releases its internal lock, so dispose may call methods of the cache.
+// Note that values not stored in the cache are never disposed, such as values retrieved while the key was forgotten,
+// and values remaining in the cache when it is garbage collected.
+// Also note that expired values are disposed only when cleaned up, not as soon as they expire.
+// Values borrowed by (*Cache).GetRef are disposed after all borrows are released.
+// Values consumed by (*Cache).GetAndForget are never disposed, since they are handed over to the caller.
+//
+// The type parameter V needs to be the value type of the cache, otherwise New returns an error.
+func WithDispose[V any](dispose func(v V)) CacheOption {
+	return func(c *cacheConfig) {
+		c.dispose = dispose
+	}
+}
+
+// WithFailureRates tracks the number of successful and failed loads per key,
+// which can be retrieved as failure rates via (*Cache).FailureRates.
+//
+// Counts of a key are kept until the key is not loaded for ttl, and are pruned by the cleaner
+// (see WithCleanupInterval). Note that the counts keep growing if the cleaner is disabled.
+// Purge also resets all counts.
+func WithFailureRates() CacheOption {
+	return func(c *cacheConfig) {
+		c.trackFailureRates = true
+	}
+}
+
+// WithSizeThresholdHook calls hook when the number of items in the cache crosses threshold upward.
+//
+// This is useful as an early warning of unexpected growth of the key cardinality,
+// especially with the built-in map backend which never evicts items.
+//
+// The hook is called once per crossing, in a separate goroutine with the current size.
+// While a previous call of the hook is still running, later crossings are ignored.
+// To be notified again, the size needs to go below threshold (e.g. by expiration, Forget, or Purge) and cross it again.
+//
+// threshold needs to be greater than 0.
+func WithSizeThresholdHook(threshold int, hook func(size int)) CacheOption {
+	return func(c *cacheConfig) {
+		c.sizeThreshold = threshold
+		c.sizeThresholdHook = hook
+	}
+}
+
+// WithShards specifies the number of shards of a cache created with NewSharded.
+// Defaults to the number of CPUs. n needs to be greater than 0.
+//
+// This option cannot be used with New.
+func WithShards(n int) CacheOption {
+	return func(c *cacheConfig) {
+		c.shards = n
+	}
+}
+
+// WithHasher specifies the function to hash keys into shards of a cache created with NewSharded.
+// The key type K needs to match the key type of the cache, otherwise NewSharded returns an error.
+//
+// By default, keys of string, integer, and boolean kinds are hashed automatically.
+// Keys of other kinds (e.g. structs) need the hasher to be specified.
+func WithHasher[K comparable](hasher func(key K) uint64) CacheOption {
+	return func(c *cacheConfig) {
+		c.hasher = hasher
+	}
+}
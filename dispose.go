@@ -0,0 +1,162 @@
+package sc
+
+// RemovalReason represents why an item was removed from the cache. See WithRemovalCallback.
+type RemovalReason int
+
+const (
+	// RemovalEvicted means the item was evicted by the backend to make room for new items, or by Resize.
+	RemovalEvicted RemovalReason = iota
+	// RemovalExpired means the item was expired, and removed by the cleaner, DeleteExpired, or ForgetExpired.
+	RemovalExpired
+	// RemovalDeleted means the key was forgotten, e.g. by Forget, ForgetIf, or an invalidation.
+	// Items consumed by GetAndForget are also reported as deleted.
+	RemovalDeleted
+	// RemovalReplaced means a new item was stored for the same key.
+	RemovalReplaced
+	// RemovalPurged means all items were removed by Purge or Flush.
+	RemovalPurged
+)
+
+// String returns the name of the reason.
+func (r RemovalReason) String() string {
+	switch r {
+	case RemovalEvicted:
+		return "Evicted"
+	case RemovalExpired:
+		return "Expired"
+	case RemovalDeleted:
+		return "Deleted"
+	case RemovalReplaced:
+		return "Replaced"
+	case RemovalPurged:
+		return "Purged"
+	default:
+		return "Unknown"
+	}
+}
+
+// removal is an item removed while holding mu, to be notified to the removal callback after releasing mu.
+type removal[K comparable, V any] struct {
+	key    K
+	v      V
+	reason RemovalReason
+}
+
+// tracksRemovals returns true if removed needs to be called for each value leaving the cache.
+func (c *cache[K, V]) tracksRemovals() bool {
+	return c.dispose != nil || c.onRemove != nil
+}
+
+// removed is called when val leaves the cache, either by being replaced, evicted, forgotten, or expired.
+// c.mu must be held by the caller, and the caller must release the lock with unlock.
+func (c *cache[K, V]) removed(key K, val value[V], reason RemovalReason) {
+	if c.onRemove != nil {
+		c.removals = append(c.removals, removal[K, V]{key, val.v, reason})
+	}
+	if c.dispose == nil {
+		return
+	}
+	if l, ok := c.leases[key]; ok {
+		delete(c.leases, key)
+		if l.count > 0 {
+			// Dispose when all borrows are released - see GetRef
+			l.removed = true
+			return
+		}
+	}
+	c.disposals = append(c.disposals, val.v)
+}
+
+// evicted is called by the backend when val is evicted to make room for a new value.
+// c.mu is held by the caller.
+func (c *cache[K, V]) evicted(key K, val value[V]) {
+	c.stats.evictions.Add(1)
+	if c.metrics != nil {
+		c.evictedKeys = append(c.evictedKeys, key)
+	}
+	if c.onEvict != nil {
+		c.onEvict(key, val.v)
+	}
+	c.removed(key, val, RemovalEvicted)
+}
+
+// unlock releases c.mu, and then disposes the values removed while holding the lock.
+// Values are disposed outside the lock so that the dispose function may call methods of the cache.
+// Evictions and removals are also notified to the metrics hook and the removal callback outside the lock,
+// for the same reason.
+func (c *cache[K, V]) unlock() {
+	if len(c.disposals) == 0 && len(c.evictedKeys) == 0 && len(c.removals) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	disposals, evictedKeys, removals := c.disposals, c.evictedKeys, c.removals
+	c.disposals, c.evictedKeys, c.removals = nil, nil, nil
+	c.mu.Unlock()
+	for _, key := range evictedKeys {
+		c.metrics.OnEviction(key)
+	}
+	for _, r := range removals {
+		c.onRemove(r.key, r.v, r.reason)
+	}
+	for _, v := range disposals {
+		c.dispose(v)
+	}
+}
+
+// delete deletes the value for key. c.mu must be held by the caller.
+func (c *cache[K, V]) delete(key K) {
+	if c.tracksRemovals() {
+		if val, ok := c.values.Peek(key); ok {
+			c.removed(key, val, RemovalDeleted)
+		}
+	}
+	c.values.Delete(key)
+}
+
+// consume deletes the value for key, handing it over to the caller: the removal is reported to the removal callback,
+// but the value is never disposed, even after all borrows (see GetRef) are released. See GetAndForget.
+// c.mu must be held by the caller, and the caller must release the lock with unlock.
+func (c *cache[K, V]) consume(key K) {
+	if c.onRemove != nil {
+		if val, ok := c.values.Peek(key); ok {
+			c.removals = append(c.removals, removal[K, V]{key, val.v, RemovalDeleted})
+		}
+	}
+	// Borrows are released as usual, but no longer dispose the value
+	delete(c.leases, key)
+	c.values.Delete(key)
+}
+
+// deleteIf deletes all values that match the predicate for the reason. c.mu must be held by the caller.
+func (c *cache[K, V]) deleteIf(reason RemovalReason, predicate func(key K, val value[V]) bool) {
+	c.values.DeleteIf(func(key K, val value[V]) bool {
+		if predicate(key, val) {
+			c.removed(key, val, reason)
+			return true
+		}
+		return false
+	})
+}
+
+// deleteIfN is similar to deleteIf, but calls predicate for at most n values. See backend.DeleteIfN.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) deleteIfN(n int, reason RemovalReason, predicate func(key K, val value[V]) bool) (visited int, wrapped bool) {
+	return c.values.DeleteIfN(n, func(key K, val value[V]) bool {
+		if predicate(key, val) {
+			c.removed(key, val, reason)
+			return true
+		}
+		return false
+	})
+}
+
+// purge deletes all values. c.mu must be held by the caller.
+func (c *cache[K, V]) purge() {
+	if c.tracksRemovals() {
+		c.values.Range(func(key K, val value[V]) bool {
+			c.removed(key, val, RemovalPurged)
+			return true
+		})
+	}
+	c.values.Purge()
+}
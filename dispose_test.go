@@ -0,0 +1,332 @@
+package sc
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resource is a value which records whether it has been disposed.
+type resource struct {
+	name     string
+	disposed int
+}
+
+func TestCache_Dispose(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var all []*resource
+			replaceFn := func(ctx context.Context, key string) (*resource, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				r := &resource{name: key}
+				all = append(all, r)
+				return r, nil
+			}
+			var cache *Cache[string, *resource]
+			dispose := func(r *resource) {
+				mu.Lock()
+				r.disposed++
+				mu.Unlock()
+				cache.Len() // dispose may call methods of the cache
+			}
+			cache, err := New[string, *resource](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithDispose(dispose))...)
+			assert.NoError(t, err)
+			disposed := func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				var names []string
+				for _, r := range all {
+					assert.LessOrEqual(t, r.disposed, 1, "disposed more than once")
+					if r.disposed > 0 {
+						names = append(names, r.name)
+					}
+				}
+				return names
+			}
+
+			for i := 0; i < 5; i++ {
+				_, _ = cache.Get(context.Background(), "k"+strconv.Itoa(i))
+			}
+			assert.Empty(t, disposed())
+
+			// replaced
+			cache.Set("k0", &resource{name: "k0-set"})
+			assert.Equal(t, []string{"k0"}, disposed())
+
+			// forgotten
+			cache.Forget("k1")
+			cache.Forget("k1")
+			cache.ForgetIf(func(key string) bool { return key == "k2" })
+			assert.Equal(t, []string{"k0", "k1", "k2"}, disposed())
+
+			// purged
+			cache.Purge()
+			assert.Equal(t, []string{"k0", "k1", "k2", "k3", "k4"}, disposed())
+		})
+	}
+}
+
+func TestCache_Dispose_Evicted(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range evictingCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key int) (int, error) {
+				return key, nil
+			}
+			var mu sync.Mutex
+			var disposed []int
+			cache, err := New[int, int](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithDispose(func(v int) {
+					mu.Lock()
+					disposed = append(disposed, v)
+					mu.Unlock()
+				}))...)
+			assert.NoError(t, err)
+
+			for i := 0; i < 15; i++ {
+				_, _ = cache.Get(context.Background(), i)
+			}
+			assert.Equal(t, []int{0, 1, 2, 3, 4}, disposed)
+		})
+	}
+}
+
+func TestCache_Dispose_Expired(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	disposed := make(chan string, 1)
+	cache, err := New[string, string](replaceFn, 100*time.Millisecond, 100*time.Millisecond,
+		WithCleanupInterval(200*time.Millisecond), WithDispose(func(v string) { disposed <- v }))
+	assert.NoError(t, err)
+
+	_, _ = cache.Get(context.Background(), "k1")
+	select {
+	case v := <-disposed:
+		assert.Equal(t, "value-k1", v)
+	case <-time.After(time.Second):
+		t.Error("expired value was not disposed")
+	}
+}
+
+func TestCache_Dispose_GetAndForget(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (*resource, error) {
+				return &resource{name: key}, nil
+			}
+			var mu sync.Mutex
+			var disposed []string
+			dispose := func(r *resource) {
+				mu.Lock()
+				defer mu.Unlock()
+				r.disposed++
+				disposed = append(disposed, r.name)
+			}
+			cache, err := New[string, *resource](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithDispose(dispose))...)
+			assert.NoError(t, err)
+
+			// consumed value is handed over to the caller without being disposed
+			_, _ = cache.Get(context.Background(), "k1")
+			r, err := cache.GetAndForget(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "k1", r.name)
+			mu.Lock()
+			assert.Equal(t, 0, r.disposed)
+			mu.Unlock()
+			_, ok := cache.Peek("k1")
+			assert.False(t, ok)
+
+			// consumed value is not disposed even after borrows are released
+			r2, release, err := cache.GetRef(context.Background(), "k2")
+			assert.NoError(t, err)
+			r, err = cache.GetAndForget(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Same(t, r2, r)
+			release()
+
+			// other values are disposed as usual
+			cache.Set("k3", &resource{name: "k3"})
+			cache.Forget("k3")
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, []string{"k3"}, disposed)
+			assert.Equal(t, 0, r.disposed)
+		})
+	}
+}
+
+func TestCache_Dispose_InvalidType(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	_, err := New[string, string](replaceFn, time.Minute, time.Minute, WithDispose(func(v int) {}))
+	assert.Error(t, err)
+}
+
+func TestCache_EvictionCallback(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range evictingCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key int) (string, error) {
+				return "value-" + strconv.Itoa(key), nil
+			}
+			evicted := make(map[int]string)
+			cache, err := New[int, string](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithEvictionCallback(func(key int, v string) { evicted[key] = v }))...)
+			assert.NoError(t, err)
+
+			for i := 0; i < 10; i++ {
+				_, _ = cache.Get(context.Background(), i)
+			}
+			cache.Forget(9) // not an eviction
+			assert.Empty(t, evicted)
+
+			for i := 10; i < 15; i++ {
+				_, _ = cache.Get(context.Background(), i)
+			}
+			assert.Equal(t, map[int]string{0: "value-0", 1: "value-1", 2: "value-2", 3: "value-3"}, evicted)
+		})
+	}
+
+	t.Run("invalid type", func(t *testing.T) {
+		t.Parallel()
+
+		replaceFn := func(ctx context.Context, key int) (string, error) { return "", nil }
+		_, err := New[int, string](replaceFn, time.Minute, time.Minute, WithEvictionCallback(func(key string, v string) {}))
+		assert.Error(t, err)
+	})
+}
+
+func TestCache_ExpirationCallback(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			var mu sync.Mutex
+			expired := make(map[string]string)
+			var cache *Cache[string, string]
+			onExpire := func(key string, v string) {
+				mu.Lock()
+				expired[key] = v
+				mu.Unlock()
+				cache.Len() // onExpire may call methods of the cache
+			}
+			cache, err := New[string, string](replaceFn, 100*time.Millisecond, 100*time.Millisecond,
+				append(c.cacheOpts, WithCleanupInterval(150*time.Millisecond), WithExpirationCallback(onExpire))...)
+			assert.NoError(t, err)
+
+			// t=0ms, k1 is retrieved
+			_, _ = cache.Get(context.Background(), "k1")
+			// t=100ms, k2 is retrieved
+			time.Sleep(100 * time.Millisecond)
+			_, _ = cache.Get(context.Background(), "k2")
+
+			// t=200ms, only k1 is removed by the cleaner at t=150ms
+			time.Sleep(100 * time.Millisecond)
+			mu.Lock()
+			assert.Equal(t, map[string]string{"k1": "value-k1"}, expired)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestCache_RemovalCallback(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	var elapsed atomic.Int64
+	now := func() time.Time { return base.Add(time.Duration(elapsed.Load())) }
+	var mu sync.Mutex
+	var removals []string
+	var cache *Cache[string, string]
+	onRemove := func(key string, v string, reason RemovalReason) {
+		mu.Lock()
+		removals = append(removals, key+"="+v+":"+reason.String())
+		mu.Unlock()
+		cache.Len() // onRemove may call methods of the cache
+	}
+	removed := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		r := removals
+		removals = nil
+		return r
+	}
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute,
+		WithLRUBackend(3), WithClock(now), WithCleanupInterval(0), WithRemovalCallback(onRemove))
+	assert.NoError(t, err)
+
+	for i := 1; i <= 4; i++ {
+		_, _ = cache.Get(context.Background(), "k"+strconv.Itoa(i))
+	}
+	assert.Equal(t, []string{"k1=value-k1:Evicted"}, removed())
+
+	cache.Set("k2", "new-value")
+	assert.Equal(t, []string{"k2=value-k2:Replaced"}, removed())
+
+	cache.Forget("k3")
+	assert.Equal(t, []string{"k3=value-k3:Deleted"}, removed())
+
+	elapsed.Add(int64(2 * time.Minute))
+	cache.Set("k5", "value-k5")
+	assert.Empty(t, removed())
+	assert.Equal(t, 2, cache.DeleteExpired())
+	assert.ElementsMatch(t, []string{"k2=new-value:Expired", "k4=value-k4:Expired"}, removed())
+
+	cache.Purge()
+	assert.Equal(t, []string{"k5=value-k5:Purged"}, removed())
+
+	_, err = New[string, string](replaceFn, time.Minute, time.Minute,
+		WithRemovalCallback(func(key int, v string, reason RemovalReason) {}))
+	assert.Error(t, err)
+}
+
+func TestRemovalReason_String(t *testing.T) {
+	assert.Equal(t, "Evicted", RemovalEvicted.String())
+	assert.Equal(t, "Expired", RemovalExpired.String())
+	assert.Equal(t, "Deleted", RemovalDeleted.String())
+	assert.Equal(t, "Replaced", RemovalReplaced.String())
+	assert.Equal(t, "Purged", RemovalPurged.String())
+	assert.Equal(t, "Unknown", RemovalReason(-1).String())
+}
@@ -0,0 +1,97 @@
+package sc
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+var (
+	// ErrNilReplaceFn is returned by New when replaceFn is nil.
+	ErrNilReplaceFn = errors.New("replaceFn cannot be nil")
+	// ErrNilKeyFn is returned by NewWithKeyFunc when keyFn is nil.
+	ErrNilKeyFn = errors.New("keyFn cannot be nil")
+	// ErrNegativeDuration is matched by the errors returned by New (and GetWithTTL) when freshFor or ttl is negative.
+	ErrNegativeDuration = errors.New("freshFor and ttl needs to be non-negative")
+	// ErrFreshForExceedsTTL is matched by the errors returned by New (and GetWithTTL) when freshFor is longer than ttl.
+	ErrFreshForExceedsTTL = errors.New("freshFor cannot be longer than ttl")
+	// ErrCapacityRequired is matched by the errors returned by New (and Resize) when a bounded backend is specified
+	// with capacity of 0 or less, i.e. ErrInvalidLRUCapacity, ErrInvalid2QCapacity, and ErrInvalidFIFOCapacity.
+	ErrCapacityRequired = errors.New("capacity needs to be greater than 0")
+	// ErrInvalidOption is matched by the errors returned by New when an option is given an invalid value,
+	// or does not accept the key and value types of the cache. The error message describes the invalid option.
+	ErrInvalidOption = errors.New("invalid option")
+
+	// ErrInvalidLRUCapacity is returned by New when LRU backend is specified with capacity of 0 or less.
+	ErrInvalidLRUCapacity = fmt.Errorf("%w for LRU cache", ErrCapacityRequired)
+	// ErrInvalid2QCapacity is returned by New when 2Q backend is specified with capacity of 0 or less.
+	ErrInvalid2QCapacity = fmt.Errorf("%w for 2Q cache", ErrCapacityRequired)
+	// ErrInvalidFIFOCapacity is returned by New when FIFO backend is specified with capacity of 0 or less.
+	ErrInvalidFIFOCapacity = fmt.Errorf("%w for FIFO cache", ErrCapacityRequired)
+	// ErrNilBatchFn is returned by NewBatched when batchFn is nil.
+	// It matches ErrNilReplaceFn, since batchFn serves as replaceFn of the cache.
+	ErrNilBatchFn = fmt.Errorf("%w: got nil batchFn", ErrNilReplaceFn)
+
+	// ErrNotFound is returned by Get when the batch function of a cache created with NewBatched
+	// did not return a value for the key.
+	ErrNotFound = errors.New("value not found")
+	// ErrBusy is returned by TryGetOrError when an item needs to be loaded,
+	// but the number of ongoing replaceFn calls has reached the limit.
+	ErrBusy = errors.New("too many concurrent replacements")
+	// ErrTooManyWaiters is returned by Get when it needs to wait for an ongoing replacement,
+	// but the number of callers waiting for the replacement has reached the limit. See WithMaxWaitersPerKey.
+	ErrTooManyWaiters = errors.New("too many callers waiting for the replacement")
+
+	// ErrSkipCache can be returned by replaceFn (or the batch function of NewBatched) together with a valid value,
+	// to return the value to the callers without storing it in the cache.
+	// This is useful for values which must not be reused, such as degraded fallbacks during an outage of the data source.
+	//
+	// The callers waiting for the retrieval receive the value with a nil error. Any item already stored for the key
+	// is kept as it is, so a stale item continues to be served if the value was retrieved in the background.
+	// Errors wrapping ErrSkipCache are treated in the same way.
+	ErrSkipCache = errors.New("skip caching the value")
+	// ErrReplacePanic is matched by errors returned when replaceFn panics. See WithRecoverReplaceFn and PanicError.
+	ErrReplacePanic = errors.New("replaceFn panicked")
+)
+
+// PanicError is returned to the callers when replaceFn panics, if WithRecoverReplaceFn is specified.
+// Without the option, PanicError is returned only to the callers waiting for the retrieval,
+// since the panic propagates in the goroutine which called replaceFn.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine which panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v\n\n%s", ErrReplacePanic, e.Value, e.Stack)
+}
+
+// Is reports whether target is ErrReplacePanic.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrReplacePanic
+}
+
+// Unwrap returns the panicked value if it is an error, nil otherwise.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// invalidOption returns an error matching ErrInvalidOption, described by msg.
+func invalidOption(msg string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidOption, msg)
+}
+
+// recovered calls fn, converting a panic into *PanicError if WithRecoverReplaceFn is specified.
+func (c *cache[K, V]) recovered(fn func() error) (err error) {
+	if c.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+	}
+	return fn()
+}
@@ -0,0 +1,52 @@
+package sc
+
+// loadCounts is the number of loads of a single key, for tracking the failure rate. See WithFailureRates.
+type loadCounts struct {
+	successes, failures uint64
+	// lastLoaded is when the latest load of the key started.
+	lastLoaded monoTime
+}
+
+// recordLoad records the result of a load of key, started at loadedAt.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) recordLoad(key K, loadedAt monoTime, err error) {
+	if c.loads == nil {
+		return
+	}
+	counts := c.loads[key]
+	if err == nil {
+		counts.successes++
+	} else {
+		counts.failures++
+	}
+	counts.lastLoaded = max(counts.lastLoaded, loadedAt)
+	c.loads[key] = counts
+}
+
+// pruneLoads removes load counts of keys which have not been loaded for ttl, just like values expire.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) pruneLoads(now monoTime) {
+	for key, counts := range c.loads {
+		if counts.lastLoaded+monoTime(c.ttl) < now {
+			delete(c.loads, key)
+		}
+	}
+}
+
+// FailureRates returns the ratio of failed loads (calls to replaceFn returning an error) to all loads, for each key.
+// This helps distinguishing problematic keys that (intermittently) fail to load from a globally failing data source.
+//
+// Only keys loaded at least once recently are included. See WithFailureRates for how long the counts are kept.
+// Returns nil if the cache was not created with WithFailureRates option.
+func (c *cache[K, V]) FailureRates() map[K]float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loads == nil {
+		return nil
+	}
+	rates := make(map[K]float64, len(c.loads))
+	for key, counts := range c.loads {
+		rates[key] = float64(counts.failures) / float64(counts.successes+counts.failures)
+	}
+	return rates
+}
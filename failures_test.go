@@ -0,0 +1,76 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_FailureRates(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var flaky bool
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				switch key {
+				case "error":
+					return "", errors.New("test error")
+				case "flaky":
+					flaky = !flaky
+					if flaky {
+						return "", errors.New("test error")
+					}
+				}
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 0, 0, append(c.cacheOpts, WithFailureRates(), WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			for i := 0; i < 4; i++ {
+				for _, key := range []string{"k1", "error", "flaky"} {
+					_, _ = cache.Get(context.Background(), key)
+					time.Sleep(time.Millisecond) // ensure the value is expired
+				}
+			}
+			assert.Equal(t, map[string]float64{"k1": 0, "error": 1, "flaky": 0.5}, cache.FailureRates())
+
+			cache.Purge()
+			assert.Empty(t, cache.FailureRates())
+		})
+	}
+}
+
+func TestCache_FailureRates_Prune(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "", errors.New("test error")
+	}
+	cache, err := New[string, string](replaceFn, 250*time.Millisecond, 250*time.Millisecond, WithFailureRates(), WithCleanupInterval(100*time.Millisecond))
+	assert.NoError(t, err)
+
+	_, _ = cache.Get(context.Background(), "k1")
+	assert.Equal(t, map[string]float64{"k1": 1}, cache.FailureRates())
+	time.Sleep(500 * time.Millisecond)
+	assert.Empty(t, cache.FailureRates())
+}
+
+func TestCache_FailureRates_Disabled(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute)
+	assert.NoError(t, err)
+
+	_, _ = cache.Get(context.Background(), "k1")
+	assert.Nil(t, cache.FailureRates())
+}
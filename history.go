@@ -0,0 +1,75 @@
+package sc
+
+import (
+	"time"
+)
+
+// statsHistory is a ring buffer of Stats snapshots.
+type statsHistory struct {
+	samples []Stats
+	next    int // next is the index to write the next sample to
+	full    bool
+}
+
+func newStatsHistory(size int) *statsHistory {
+	return &statsHistory{
+		samples: make([]Stats, size),
+	}
+}
+
+func (h *statsHistory) add(s Stats) {
+	h.samples[h.next] = s
+	h.next++
+	if h.next == len(h.samples) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// list returns the samples, from the oldest to the newest.
+func (h *statsHistory) list() []Stats {
+	if !h.full {
+		return append(make([]Stats, 0, h.next), h.samples[:h.next]...)
+	}
+	list := make([]Stats, 0, len(h.samples))
+	list = append(list, h.samples[h.next:]...)
+	return append(list, h.samples[:h.next]...)
+}
+
+// statsRecorder is launched as a single goroutine to regularly record stats snapshots.
+// Similarly to cleaner, statsRecorder holds reference to cache, not Cache - this allows finalizers to be run on Cache.
+type statsRecorder[K comparable, V any] struct {
+	closer chan struct{}
+	// done is closed when the context given by WithContext is done. nil if not given.
+	done <-chan struct{}
+	c    *cache[K, V]
+}
+
+func startStatsRecorder[K comparable, V any](c *cache[K, V], config cacheConfig) *statsRecorder[K, V] {
+	r := &statsRecorder[K, V]{
+		closer: make(chan struct{}),
+		done:   config.done(),
+		c:      c,
+	}
+	go r.run(config.statsHistoryInterval)
+	return r
+}
+
+func (r *statsRecorder[K, V]) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.c.recordStats()
+		case <-r.closer:
+			return
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *statsRecorder[K, V]) stop() {
+	close(r.closer)
+}
@@ -0,0 +1,106 @@
+package sc
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_statsHistory(t *testing.T) {
+	t.Parallel()
+
+	stats := func(hits uint64) Stats {
+		return Stats{HitStats: HitStats{Hits: hits}}
+	}
+
+	h := newStatsHistory(3)
+	assert.Equal(t, []Stats{}, h.list())
+
+	h.add(stats(1))
+	h.add(stats(2))
+	assert.Equal(t, []Stats{stats(1), stats(2)}, h.list())
+
+	h.add(stats(3))
+	assert.Equal(t, []Stats{stats(1), stats(2), stats(3)}, h.list())
+
+	h.add(stats(4))
+	h.add(stats(5))
+	assert.Equal(t, []Stats{stats(3), stats(4), stats(5)}, h.list())
+}
+
+func TestCache_StatsHistory(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+
+	t.Run("invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](replaceFn, 0, 0, WithStatsHistory(0, 10))
+		assert.Error(t, err)
+		_, err = New[string, string](replaceFn, 0, 0, WithStatsHistory(time.Second, 0))
+		assert.Error(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		cache, err := New[string, string](replaceFn, time.Minute, time.Minute)
+		assert.NoError(t, err)
+		assert.Nil(t, cache.StatsHistory())
+	})
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, append(c.cacheOpts, WithStatsHistory(200*time.Millisecond, 3))...)
+			assert.NoError(t, err)
+
+			// t=0ms
+			_, _ = cache.Get(context.Background(), "k1")
+			// t=100ms
+			time.Sleep(100 * time.Millisecond)
+			assert.Empty(t, cache.StatsHistory())
+			_, _ = cache.Get(context.Background(), "k1")
+
+			// t=300ms, 1 sample at t=200ms
+			time.Sleep(200 * time.Millisecond)
+			history := cache.StatsHistory()
+			if assert.Len(t, history, 1) {
+				assert.Equal(t, HitStats{1, 0, 1, 1, 0, 0}, history[0].HitStats)
+				assert.Equal(t, 1, history[0].Size)
+			}
+			_, _ = cache.Get(context.Background(), "k2")
+
+			// t=900ms, samples at t=400ms, 600ms, 800ms
+			time.Sleep(600 * time.Millisecond)
+			history = cache.StatsHistory()
+			if assert.Len(t, history, 3) {
+				for _, s := range history {
+					assert.Equal(t, HitStats{1, 0, 2, 2, 0, 0}, s.HitStats)
+					assert.Equal(t, 2, s.Size)
+				}
+			}
+		})
+	}
+}
+
+// TestCache_StatsHistory_Finalizer tests that the finalizer stops both the cleaner and the stats recorder.
+// Since there's not really a good way of ensuring call to the finalizer, this just increases the test coverage.
+func TestCache_StatsHistory_Finalizer(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(_ context.Context, _ struct{}) (string, error) { return "", nil }
+	c, err := New(replaceFn, time.Hour, time.Hour, WithCleanupInterval(time.Second), WithStatsHistory(time.Second, 10))
+	assert.NoError(t, err)
+
+	_, _ = c.Get(context.Background(), struct{}{})
+	runtime.GC() // finalizer is called and background goroutines are stopped
+}
@@ -0,0 +1,88 @@
+// Package flight provides the core of request coalescing shared by github.com/motoki317/sc and its singleflight
+// package: an in-flight call whose result is shared by the callers waiting for it.
+package flight
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrGoexit is passed to the finish function of Call.Run when fn calls runtime.Goexit.
+var ErrGoexit = errors.New("runtime.Goexit was called")
+
+// PanicError is passed to the finish function of Call.Run when fn panics.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine which panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Call is an in-flight or completed call. The result of the call is held by the owner of the Call,
+// and needs to be written before the call is completed, so that the waiting callers can read it after Wait returns.
+type Call struct {
+	// done is closed when the call completes.
+	done chan struct{}
+}
+
+// NewCall creates a new in-flight call.
+func NewCall() *Call {
+	return &Call{done: make(chan struct{})}
+}
+
+// Done returns a channel which is closed when the call completes.
+func (c *Call) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait waits for the call to complete.
+func (c *Call) Wait() {
+	<-c.done
+}
+
+// Complete completes the call, waking up the waiting callers. Complete must be called exactly once.
+func (c *Call) Complete() {
+	close(c.done)
+}
+
+// Run calls fn, then finish, and then completes the call.
+//
+// finish is called and the call is completed even if fn panics or calls runtime.Goexit,
+// so that the waiting callers are never blocked forever.
+// finish receives *PanicError or ErrGoexit describing such an abnormal exit of fn, or nil if fn returned normally.
+// After the call is completed, the panic is propagated to the caller of Run,
+// or the goroutine continues to exit by runtime.Goexit.
+func (c *Call) Run(fn func(), finish func(aborted error)) {
+	returned := false
+	var aborted error
+	var panicked any
+	defer func() {
+		if !returned && aborted == nil {
+			// recover returns nil during runtime.Goexit - let the goroutine continue to exit
+			aborted = ErrGoexit
+		}
+		finish(aborted)
+		c.Complete()
+		if panicked != nil {
+			panic(panicked)
+		}
+	}()
+	func() {
+		defer func() {
+			if returned {
+				return
+			}
+			if r := recover(); r != nil {
+				panicked = r
+				aborted = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		fn()
+		returned = true
+	}()
+}
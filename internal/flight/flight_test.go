@@ -0,0 +1,69 @@
+package flight_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/motoki317/sc/internal/flight"
+)
+
+func TestCall_Run(t *testing.T) {
+	t.Parallel()
+
+	c := flight.NewCall()
+	var got error = flight.ErrGoexit
+	c.Run(func() {}, func(aborted error) {
+		select {
+		case <-c.Done():
+			t.Error("call should not complete before finish")
+		default:
+		}
+		got = aborted
+	})
+	require.NoError(t, got)
+	c.Wait()
+}
+
+func TestCall_Run_Panic(t *testing.T) {
+	t.Parallel()
+
+	c := flight.NewCall()
+	var got error
+	func() {
+		defer func() {
+			require.Equal(t, "test panic", recover())
+		}()
+		c.Run(func() {
+			panic("test panic")
+		}, func(aborted error) {
+			got = aborted
+		})
+	}()
+	c.Wait()
+	var pe *flight.PanicError
+	require.ErrorAs(t, got, &pe)
+	require.Equal(t, "test panic", pe.Value)
+	require.NotEmpty(t, pe.Stack)
+}
+
+func TestCall_Run_Goexit(t *testing.T) {
+	t.Parallel()
+
+	c := flight.NewCall()
+	var got error
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		c.Run(func() {
+			runtime.Goexit()
+		}, func(aborted error) {
+			got = aborted
+		})
+		t.Error("runtime.Goexit should not be stopped")
+	}()
+	<-exited
+	c.Wait()
+	require.ErrorIs(t, got, flight.ErrGoexit)
+}
@@ -0,0 +1,35 @@
+package sc
+
+// ReceiveInvalidation forgets about the key just like Forget, but without publishing the invalidation.
+// Call this method for invalidations received from caches in other processes. See WithInvalidationPublisher.
+func (c *cache[K, V]) ReceiveInvalidation(key K) {
+	c.forget(c.normalizeKey(key))
+}
+
+// invalidations wraps predicate to collect the matched keys, and returns a function to publish them.
+// The returned function needs to be called after releasing c.mu. See WithInvalidationPublisher.
+func (c *cache[K, V]) invalidations(predicate func(key K) bool) (wrapped func(key K) bool, publish func()) {
+	if c.publishInvalidation == nil {
+		return predicate, func() {}
+	}
+
+	var keys []K
+	matched := make(map[K]struct{})
+	wrapped = func(key K) bool {
+		if !predicate(key) {
+			return false
+		}
+		// The predicate may be called multiple times for the same key
+		if _, ok := matched[key]; !ok {
+			matched[key] = struct{}{}
+			keys = append(keys, key)
+		}
+		return true
+	}
+	publish = func() {
+		for _, key := range keys {
+			c.publishInvalidation(key)
+		}
+	}
+	return wrapped, publish
+}
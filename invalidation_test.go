@@ -0,0 +1,89 @@
+package sc
+
+import (
+	"cmp"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Invalidation(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+
+			// Two caches subscribing to each other's invalidations
+			var mu sync.Mutex
+			var published []string
+			var caches [2]*Cache[string, string]
+			for i := range caches {
+				i := i
+				publish := func(key string) {
+					mu.Lock()
+					published = append(published, key)
+					mu.Unlock()
+					caches[1-i].ReceiveInvalidation(key)
+				}
+				cache, err := New[string, string](replaceFn, time.Minute, time.Minute, append(c.cacheOpts, WithInvalidationPublisher(publish))...)
+				assert.NoError(t, err)
+				caches[i] = cache
+			}
+			for _, cache := range caches {
+				for _, key := range []string{"k1", "k2", "k3", "x1"} {
+					_, _ = cache.Get(context.Background(), key)
+				}
+			}
+
+			caches[0].Forget("k1")
+			assert.ElementsMatch(t, []string{"k2", "k3", "x1"}, caches[0].Keys())
+			assert.ElementsMatch(t, []string{"k2", "k3", "x1"}, caches[1].Keys())
+			// received invalidations are not published again
+			assert.Equal(t, []string{"k1"}, published)
+
+			published = nil
+			caches[1].ForgetIf(func(key string) bool { return strings.HasPrefix(key, "k") })
+			assert.ElementsMatch(t, []string{"x1"}, caches[0].Keys())
+			assert.ElementsMatch(t, []string{"x1"}, caches[1].Keys())
+			assert.ElementsMatch(t, []string{"k2", "k3"}, published)
+
+			published = nil
+			_, _ = caches[0].Get(context.Background(), "k1")
+			_, _ = caches[1].Get(context.Background(), "k2")
+			caches[0].ForgetAll([]string{"k1", "k2"})
+			assert.ElementsMatch(t, []string{"x1"}, caches[0].Keys())
+			assert.ElementsMatch(t, []string{"x1"}, caches[1].Keys())
+			assert.Equal(t, []string{"k1", "k2"}, published)
+
+			published = nil
+			caches[0].ForgetIfSorted(cmp.Compare[string], func(key string) bool { return true })
+			assert.Empty(t, caches[0].Keys())
+			assert.Empty(t, caches[1].Keys())
+			assert.Equal(t, []string{"x1"}, published)
+
+			// other methods do not publish
+			published = nil
+			_, _ = caches[0].Get(context.Background(), "k1")
+			caches[0].Purge()
+			assert.Empty(t, published)
+		})
+	}
+}
+
+func TestCache_Invalidation_InvalidType(t *testing.T) {
+	t.Parallel()
+
+	fn := func(ctx context.Context, s string) (string, error) { return "", nil }
+	_, err := New[string, string](fn, 0, 0, WithInvalidationPublisher(func(key int) {}))
+	assert.Error(t, err)
+}
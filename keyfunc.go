@@ -0,0 +1,113 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// originalKey is the context key to pass the original key of KeyFuncCache to replaceFn.
+type originalKey struct{}
+
+// KeyFuncCache represents a cache instance whose keys are not comparable, such as slices or structs containing them.
+// See NewWithKeyFunc.
+// All methods are safe to be called from multiple goroutines.
+type KeyFuncCache[K any, V any] struct {
+	c     *Cache[string, V]
+	keyFn func(key K) string
+}
+
+// NewWithKeyFunc creates a new cache instance for keys which are not comparable, and therefore cannot be the keys
+// of Cache.
+//
+// keyFn derives a string from each key, under which the item is stored. Keys deriving the same string are regarded
+// as the same key, sharing the same item and coalescing their retrievals.
+// replaceFn receives the original key: if concurrent callers give different keys deriving the same string,
+// replaceFn receives the key of the caller which started the retrieval.
+//
+// The original key is passed to replaceFn through the context. Therefore, if WithBackgroundContext is used,
+// the function needs to derive the context from the given parent, otherwise background refreshes fail.
+// Options parameterized by the key type (e.g. WithTTLFunc) need to be given string as the key type.
+//
+// See New for the details of the other arguments.
+func NewWithKeyFunc[K any, V any](keyFn func(key K) string, replaceFn func(ctx context.Context, key K) (V, error), freshFor, ttl time.Duration, options ...CacheOption) (*KeyFuncCache[K, V], error) {
+	if keyFn == nil {
+		return nil, ErrNilKeyFn
+	}
+	if replaceFn == nil {
+		return nil, ErrNilReplaceFn
+	}
+	fn := func(ctx context.Context, _ string) (V, error) {
+		key, ok := ctx.Value(originalKey{}).(K)
+		if !ok {
+			var zero V
+			return zero, errors.New("original key is missing from the context")
+		}
+		return replaceFn(ctx, key)
+	}
+	c, err := New[string, V](fn, freshFor, ttl, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyFuncCache[K, V]{c: c, keyFn: keyFn}, nil
+}
+
+// with returns ctx carrying key for replaceFn.
+func (k *KeyFuncCache[K, V]) with(ctx context.Context, key K) context.Context {
+	return context.WithValue(ctx, originalKey{}, key)
+}
+
+// Get is the same as (*Cache).Get.
+func (k *KeyFuncCache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return k.c.Get(k.with(ctx, key), k.keyFn(key))
+}
+
+// GetIfExists is the same as (*Cache).GetIfExists.
+func (k *KeyFuncCache[K, V]) GetIfExists(key K) (v V, ok bool) {
+	return k.c.GetIfExists(k.keyFn(key))
+}
+
+// Peek is the same as (*Cache).Peek.
+func (k *KeyFuncCache[K, V]) Peek(key K) (v V, ok bool) {
+	return k.c.Peek(k.keyFn(key))
+}
+
+// Notify is the same as (*Cache).Notify.
+func (k *KeyFuncCache[K, V]) Notify(ctx context.Context, key K) {
+	k.c.Notify(k.with(ctx, key), k.keyFn(key))
+}
+
+// Refresh is the same as (*Cache).Refresh.
+func (k *KeyFuncCache[K, V]) Refresh(ctx context.Context, key K) (V, error) {
+	return k.c.Refresh(k.with(ctx, key), k.keyFn(key))
+}
+
+// Set is the same as (*Cache).Set.
+func (k *KeyFuncCache[K, V]) Set(key K, v V) {
+	k.c.Set(k.keyFn(key), v)
+}
+
+// Forget is the same as (*Cache).Forget.
+func (k *KeyFuncCache[K, V]) Forget(key K) {
+	k.c.Forget(k.keyFn(key))
+}
+
+// Purge is the same as (*Cache).Purge.
+func (k *KeyFuncCache[K, V]) Purge() {
+	k.c.Purge()
+}
+
+// Len is the same as (*Cache).Len.
+func (k *KeyFuncCache[K, V]) Len() int {
+	return k.c.Len()
+}
+
+// Stats is the same as (*Cache).Stats.
+func (k *KeyFuncCache[K, V]) Stats() Stats {
+	return k.c.Stats()
+}
+
+// Close is the same as (*Cache).Close.
+func (k *KeyFuncCache[K, V]) Close() {
+	k.c.Close()
+}
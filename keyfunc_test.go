@@ -0,0 +1,78 @@
+package sc
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithKeyFunc(t *testing.T) {
+	t.Parallel()
+
+	keyFn := func(key []string) string { return strings.Join(key, ",") }
+	fn := func(ctx context.Context, key []string) (int, error) { return len(key), nil }
+
+	_, err := NewWithKeyFunc[[]string, int](nil, fn, 0, 0)
+	assert.ErrorIs(t, err, ErrNilKeyFn)
+	_, err = NewWithKeyFunc[[]string, int](keyFn, nil, 0, 0)
+	assert.ErrorIs(t, err, ErrNilReplaceFn)
+	_, err = NewWithKeyFunc[[]string, int](keyFn, fn, 0, 0, WithLRUBackend(0))
+	assert.ErrorIs(t, err, ErrInvalidLRUCapacity)
+}
+
+func TestKeyFuncCache(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			keyFn := func(key []string) string { return strings.Join(key, ",") }
+			replaceFn := func(ctx context.Context, key []string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + strings.Join(key, "+"), nil
+			}
+			cache, err := NewWithKeyFunc[[]string, string](keyFn, replaceFn, 250*time.Millisecond, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+			defer cache.Close()
+
+			v, err := cache.Get(context.Background(), []string{"a", "b"})
+			assert.NoError(t, err)
+			assert.Equal(t, "value-a+b", v)
+			// keys deriving the same string share the item
+			v, err = cache.Get(context.Background(), []string{"a", "b"})
+			assert.NoError(t, err)
+			assert.Equal(t, "value-a+b", v)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			v, ok := cache.GetIfExists([]string{"a", "b"})
+			assert.True(t, ok)
+			assert.Equal(t, "value-a+b", v)
+			_, ok = cache.Peek([]string{"b", "a"})
+			assert.False(t, ok)
+
+			// background refreshes receive the original key
+			time.Sleep(300 * time.Millisecond)
+			cache.Notify(context.Background(), []string{"a", "b"})
+			assert.Eventually(t, func() bool { return atomic.LoadInt64(&cnt) == 2 }, time.Second, 10*time.Millisecond)
+			v, err = cache.Refresh(context.Background(), []string{"c"})
+			assert.NoError(t, err)
+			assert.Equal(t, "value-c", v)
+
+			cache.Set([]string{"d"}, "set")
+			v, ok = cache.GetIfExists([]string{"d"})
+			assert.True(t, ok)
+			assert.Equal(t, "set", v)
+			assert.Equal(t, 3, cache.Len())
+			cache.Forget([]string{"d"})
+			assert.Equal(t, 2, cache.Len())
+			cache.Purge()
+			assert.Equal(t, 0, cache.Len())
+		})
+	}
+}
@@ -79,7 +79,12 @@ func (l *List[T]) Remove(e *Element[T]) T {
 
 // PushFront adds a new value to the front of the list.
 func (l *List[T]) PushFront(value T) *Element[T] {
-	e := &Element[T]{Value: value}
+	return l.PushElementFront(&Element[T]{Value: value})
+}
+
+// PushElementFront adds the given element to the front of the list.
+// The element must not be in any list, e.g. an element removed by Remove.
+func (l *List[T]) PushElementFront(e *Element[T]) *Element[T] {
 	e.prev = &l.root
 	e.next = l.root.next
 	e.prev.next = e
@@ -88,6 +93,15 @@ func (l *List[T]) PushFront(value T) *Element[T] {
 	return e
 }
 
+// Front returns the first element in the list.
+func (l *List[T]) Front() *Element[T] {
+	if l.len == 0 {
+		return nil
+	}
+
+	return l.root.next
+}
+
 // Back returns the last element in the list.
 func (l *List[T]) Back() *Element[T] {
 	if l.len == 0 {
@@ -102,3 +102,28 @@ func TestInit(t *testing.T) {
 	ll.Init()
 	require.Equal(t, ll.Len(), 0)
 }
+
+func TestList_PushElementFront(t *testing.T) {
+	ll := internal.NewList[int]()
+
+	e1 := ll.PushFront(1)
+	e2 := ll.PushFront(2)
+	ll.Remove(e1)
+	require.Equal(t, 1, ll.Len())
+
+	e1.Value = 3
+	ll.PushElementFront(e1)
+	require.Equal(t, 2, ll.Len())
+	require.Equal(t, e2, ll.Next(e1))
+	require.Equal(t, e2, ll.Back())
+}
+
+func TestList_Front(t *testing.T) {
+	ll := internal.NewList[int]()
+	require.Nil(t, ll.Front())
+
+	e1 := ll.PushFront(1)
+	require.Equal(t, e1, ll.Front())
+	e2 := ll.PushFront(2)
+	require.Equal(t, e2, ll.Front())
+}
@@ -1,6 +1,8 @@
 package lru
 
 import (
+	"math"
+
 	"github.com/motoki317/sc/lru/internal"
 )
 
@@ -11,11 +13,24 @@ type Cache[K comparable, V any] struct {
 	ll      *internal.List[entry[K, V]]
 	items   map[K]*internal.Element[entry[K, V]]
 	options *options
+	// free holds deleted elements for reuse, reducing allocations under churn.
+	// Its length is bounded by the capacity.
+	free []*internal.Element[entry[K, V]]
+	// onEvict is called when an item is evicted to make room for a new item, if non-nil.
+	onEvict func(key K, value V)
+	// weigher returns the weight of an item, nil if the cache is not weighted. See NewWeighted.
+	weigher func(key K, value V) int64
+	// weight is the total weight of the items, and maxWeight is its upper bound. Both are 0 if not weighted.
+	weight, maxWeight int64
+	// cursor is the key of the item DeleteIfN resumes from, if hasCursor is true.
+	cursor    K
+	hasCursor bool
 }
 
 type entry[K comparable, V any] struct {
-	key   K
-	value V
+	key    K
+	value  V
+	weight int64
 }
 
 // New initializes a new lru cache with the given capacity.
@@ -33,41 +48,121 @@ func New[K comparable, V any](cacheOptions ...CacheOption) *Cache[K, V] {
 	return c
 }
 
+// NewWeighted initializes a new lru cache bounded by the total weight of the items, instead of the number of items.
+//
+// weigher returns the weight of an item, such as the approximate memory size of the value in bytes.
+// weigher is called once each time an item is set, and needs to return a non-negative weight.
+// When the total weight exceeds maxWeight, the least recently used items are evicted until the total weight fits.
+// An item heavier than maxWeight by itself is evicted immediately after being set, without evicting the other items.
+// If the key already exists, the old item is deleted without being reported to the evict callback.
+func NewWeighted[K comparable, V any](maxWeight int64, weigher func(key K, value V) int64) *Cache[K, V] {
+	c := New[K, V](WithCapacity(math.MaxInt))
+	c.weigher = weigher
+	c.maxWeight = maxWeight
+	return c
+}
+
+// SetEvictCallback sets a function called when an item is evicted to make room for a new item in Set.
+// The function is not called for items deleted explicitly, such as by Delete or Purge.
+func (c *Cache[K, V]) SetEvictCallback(onEvict func(key K, value V)) {
+	c.onEvict = onEvict
+}
+
 // Len is the number of key value pairs in the cache.
 func (c *Cache[K, V]) Len() int {
 	return c.ll.Len()
 }
 
 // Set the given key value pair.
-// This operation updates the recent usage of the item.
+// This operation updates the recent usage of the item, unless the cache is in FIFO mode (see WithFIFO).
 func (c *Cache[K, V]) Set(key K, value V) {
+	var weight int64
+	if c.weigher != nil {
+		weight = c.weigher(key, value)
+		if weight > c.maxWeight {
+			// Evicting the other items would never make room for this item
+			c.Delete(key)
+			if c.onEvict != nil {
+				c.onEvict(key, value)
+			}
+			return
+		}
+	}
+
 	if element, ok := c.items[key]; ok {
+		c.weight += weight - element.Value.weight
 		element.Value.value = value
-		c.ll.MoveToFront(element)
+		element.Value.weight = weight
+		if !c.options.fifo {
+			c.ll.MoveToFront(element)
+		}
+		c.evictOverweight()
 		return
 	}
 
-	entry := entry[K, V]{
-		key:   key,
-		value: value,
+	ent := entry[K, V]{
+		key:    key,
+		value:  value,
+		weight: weight,
+	}
+
+	// Reuse the oldest element if the capacity is reached
+	if c.ll.Len() >= c.options.capacity {
+		e := c.ll.Back()
+		if e == nil {
+			return // capacity is 0
+		}
+		delete(c.items, e.Value.key)
+		if c.onEvict != nil {
+			c.onEvict(e.Value.key, e.Value.value)
+		}
+		c.weight += weight - e.Value.weight
+		e.Value = ent
+		c.ll.MoveToFront(e)
+		c.items[key] = e
+		c.evictOverweight()
+		return
 	}
 
-	e := c.ll.PushFront(entry)
-	if c.ll.Len() > c.options.capacity {
-		c.deleteElement(c.ll.Back())
+	var e *internal.Element[entry[K, V]]
+	if n := len(c.free); n > 0 {
+		e = c.free[n-1]
+		c.free[n-1] = nil
+		c.free = c.free[:n-1]
+		e.Value = ent
+		c.ll.PushElementFront(e)
+	} else {
+		e = c.ll.PushFront(ent)
 	}
 	c.items[key] = e
+	c.weight += weight
+	c.evictOverweight()
+}
+
+// evictOverweight evicts the least recently used items until the total weight fits in maxWeight.
+func (c *Cache[K, V]) evictOverweight() {
+	for c.weigher != nil && c.weight > c.maxWeight {
+		key, value, ok := c.DeleteOldest()
+		if !ok {
+			return
+		}
+		if c.onEvict != nil {
+			c.onEvict(key, value)
+		}
+	}
 }
 
 // Get an item from the cache.
-// This operation updates recent usage of the item.
+// This operation updates recent usage of the item, unless the cache is in FIFO mode (see WithFIFO).
 func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
 	e, ok := c.items[key]
 	if !ok {
 		return
 	}
 
-	c.ll.MoveToFront(e)
+	if !c.options.fifo {
+		c.ll.MoveToFront(e)
+	}
 	return e.Value.value, true
 }
 
@@ -81,6 +176,18 @@ func (c *Cache[K, V]) Peek(key K) (value V, ok bool) {
 	return e.Value.value, true
 }
 
+// Range calls f for each item in the cache, from the most recently used to the least recently used
+// (or from the newest to the oldest in FIFO mode).
+// Iteration stops if f returns false.
+// This operation does not update the recent usage of the items, and f must not modify the cache.
+func (c *Cache[K, V]) Range(f func(key K, value V) bool) {
+	for e := c.ll.Front(); e != nil; e = c.ll.Next(e) {
+		if !f(e.Value.key, e.Value.value) {
+			return
+		}
+	}
+}
+
 // Delete an item from the cache.
 func (c *Cache[K, V]) Delete(key K) {
 	if e, ok := c.items[key]; ok {
@@ -97,32 +204,105 @@ func (c *Cache[K, V]) DeleteIf(predicate func(key K, value V) bool) {
 	}
 }
 
+// DeleteIfN is similar to DeleteIf, but calls predicate for at most n items, from the least recently used one
+// towards the most recently used one. This bounds the time spent in a single call for large caches.
+//
+// Subsequent calls resume from the item next to the last visited one, so that repeated calls visit all items in turn.
+// The position is reset to the least recently used item after the most recently used one is visited (wrapped is true),
+// or if the item at the position has been deleted in the meantime.
+// Note that items used between the calls are moved to the front, and may be visited twice or not at all in a round.
+func (c *Cache[K, V]) DeleteIfN(n int, predicate func(key K, value V) bool) (visited int, wrapped bool) {
+	e := c.ll.Back()
+	if c.hasCursor {
+		if ce, ok := c.items[c.cursor]; ok {
+			e = ce
+		}
+		var zero K
+		c.cursor, c.hasCursor = zero, false // do not retain reference to the key
+	}
+	for ; e != nil && visited < n; visited++ {
+		prev := c.ll.Prev(e)
+		if predicate(e.Value.key, e.Value.value) {
+			c.deleteElement(e)
+		}
+		e = prev
+	}
+	if e == nil {
+		return visited, true
+	}
+	c.cursor, c.hasCursor = e.Value.key, true
+	return visited, false
+}
+
 // DeleteOldest deletes the oldest item from the cache.
 func (c *Cache[K, V]) DeleteOldest() (key K, value V, ok bool) {
 	if e := c.ll.Back(); e != nil {
+		key, value = e.Value.key, e.Value.value
 		c.deleteElement(e)
-		return e.Value.key, e.Value.value, true
+		return key, value, true
 	}
 	return
 }
 
 func (c *Cache[K, V]) deleteElement(e *internal.Element[entry[K, V]]) {
 	delete(c.items, e.Value.key)
+	c.weight -= e.Value.weight
 	c.ll.Remove(e)
+	if len(c.free) < c.options.capacity {
+		e.Value = entry[K, V]{} // do not retain references to the key and value
+		c.free = append(c.free, e)
+	}
 }
 
 // Purge deletes all items from the cache.
 func (c *Cache[K, V]) Purge() {
+	c.free = nil
+	c.weight = 0
 	c.ll.Init()
 	for key := range c.items {
 		delete(c.items, key)
 	}
 }
 
+// Resize changes the capacity of the cache, evicting the oldest items if the cache is larger than the new capacity.
+// Returns the number of evicted items. The evict callback is called for each evicted item.
+// For weighted caches (see NewWeighted), Resize bounds the number of items in addition to the total weight.
+func (c *Cache[K, V]) Resize(capacity int) (evicted int) {
+	c.options.capacity = capacity
+	for c.ll.Len() > capacity {
+		key, value, _ := c.DeleteOldest()
+		if c.onEvict != nil {
+			c.onEvict(key, value)
+		}
+		evicted++
+	}
+	if len(c.free) > capacity {
+		clear(c.free[capacity:])
+		c.free = c.free[:capacity]
+	}
+	return evicted
+}
+
+// Size returns the number of items in the cache. This is the same as Len.
 func (c *Cache[K, V]) Size() int {
 	return c.Len()
 }
 
+// Capacity returns the maximum number of items in the cache.
+// Returns -1 for weighted caches (see NewWeighted), which have no upper bound in the number of items.
 func (c *Cache[K, V]) Capacity() int {
+	if c.weigher != nil {
+		return -1
+	}
 	return c.options.capacity
 }
+
+// Weight returns the total weight of the items in the cache. Always 0 if the cache is not weighted.
+func (c *Cache[K, V]) Weight() int64 {
+	return c.weight
+}
+
+// MaxWeight returns the maximum total weight of the items in the cache. Always 0 if the cache is not weighted.
+func (c *Cache[K, V]) MaxWeight() int64 {
+	return c.maxWeight
+}
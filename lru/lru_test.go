@@ -36,6 +36,69 @@ func TestCapacity(t *testing.T) {
 	}
 }
 
+func TestZeroCapacity(t *testing.T) {
+	c := lru.New[int, int](lru.WithCapacity(0))
+	c.Set(1, 1)
+
+	require.Equal(t, 0, c.Len())
+	_, ok := c.Peek(1)
+	require.False(t, ok, "expected key to be evicted")
+
+	c.Delete(1) // should not panic
+	require.Equal(t, 0, c.Len())
+}
+
+func TestCache_Reuse(t *testing.T) {
+	c := lru.New[int, int](lru.WithCapacity(3))
+
+	// elements are reused on eviction
+	for i := 0; i < 10; i++ {
+		c.Set(i, i*10)
+	}
+	require.Equal(t, 3, c.Len())
+	for i := 0; i < 10; i++ {
+		v, ok := c.Peek(i)
+		require.Equal(t, i >= 7, ok, "key %d", i)
+		if ok {
+			require.Equal(t, i*10, v)
+		}
+	}
+
+	// elements are reused after deletion
+	c.Delete(7)
+	c.DeleteOldest()
+	c.Set(10, 100)
+	c.Set(11, 110)
+	require.Equal(t, 3, c.Len())
+	for _, i := range []int{9, 10, 11} {
+		v, ok := c.Peek(i)
+		require.True(t, ok, "key %d", i)
+		require.Equal(t, i*10, v)
+	}
+	key, _, _ := c.DeleteOldest()
+	require.Equal(t, 9, key)
+}
+
+func TestCache_SetEvictCallback(t *testing.T) {
+	c := lru.New[int, int](lru.WithCapacity(2))
+	var evicted [][2]int
+	c.SetEvictCallback(func(key int, value int) {
+		evicted = append(evicted, [2]int{key, value})
+	})
+
+	c.Set(1, 10)
+	c.Set(2, 20)
+	c.Set(1, 11) // update is not an eviction
+	require.Empty(t, evicted)
+
+	c.Set(3, 30)
+	require.Equal(t, [][2]int{{2, 20}}, evicted)
+
+	c.Delete(1) // explicit deletion is not an eviction
+	c.Purge()
+	require.Equal(t, [][2]int{{2, 20}}, evicted)
+}
+
 func TestCache_Get(t *testing.T) {
 	t.Run("missing", func(t *testing.T) {
 		c := lru.New[int, int]()
@@ -145,6 +208,64 @@ func TestCache_DeleteIf(t *testing.T) {
 	require.False(t, ok)
 }
 
+func TestCache_DeleteIfN(t *testing.T) {
+	c := lru.New[int, int]()
+	for i := 1; i <= 5; i++ {
+		c.Set(i, i*10)
+	}
+
+	var keys []int
+	predicate := func(key int, value int) bool {
+		keys = append(keys, key)
+		return key%2 == 0
+	}
+	// from the least recently used, resuming from the last position
+	visited, wrapped := c.DeleteIfN(2, predicate)
+	require.Equal(t, 2, visited)
+	require.False(t, wrapped)
+	visited, wrapped = c.DeleteIfN(2, predicate)
+	require.Equal(t, 2, visited)
+	require.False(t, wrapped)
+	visited, wrapped = c.DeleteIfN(2, predicate)
+	require.Equal(t, 1, visited)
+	require.True(t, wrapped)
+	require.Equal(t, []int{1, 2, 3, 4, 5}, keys)
+	require.Equal(t, 3, c.Len())
+
+	// starts over after wrapping, and when the item at the position is deleted
+	keys = nil
+	_, _ = c.DeleteIfN(1, predicate)
+	c.Delete(3)
+	_, wrapped = c.DeleteIfN(5, predicate)
+	require.True(t, wrapped)
+	require.Equal(t, []int{1, 1, 5}, keys)
+}
+
+func TestCache_Range(t *testing.T) {
+	c := lru.New[int, int]()
+
+	c.Set(1, 10)
+	c.Set(2, 20)
+	c.Set(3, 30)
+	_, _ = c.Get(1)
+
+	var keys, values []int
+	c.Range(func(key int, value int) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	require.Equal(t, []int{1, 3, 2}, keys)
+	require.Equal(t, []int{10, 30, 20}, values)
+
+	keys = nil
+	c.Range(func(key int, value int) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	require.Equal(t, []int{1, 3}, keys)
+}
+
 func TestCache_DeleteOldest(t *testing.T) {
 	t.Run("missing", func(t *testing.T) {
 		c := lru.New[int, int]()
@@ -203,3 +324,135 @@ func TestCache_Capacity(t *testing.T) {
 	c.Set(1, 1)
 	require.Equal(t, 10, c.Capacity())
 }
+
+func TestCache_Resize(t *testing.T) {
+	c := lru.New[int, int](lru.WithCapacity(4))
+	var evicted []int
+	c.SetEvictCallback(func(key int, value int) {
+		evicted = append(evicted, key)
+	})
+
+	for i := 1; i <= 4; i++ {
+		c.Set(i, i)
+	}
+	c.Get(1)
+
+	// shrinking evicts the oldest items
+	require.Equal(t, 2, c.Resize(2))
+	require.Equal(t, []int{2, 3}, evicted)
+	require.Equal(t, 2, c.Capacity())
+	require.Equal(t, 2, c.Len())
+
+	// growing evicts nothing
+	require.Equal(t, 0, c.Resize(3))
+	c.Set(5, 5)
+	require.Equal(t, []int{2, 3}, evicted)
+	c.Set(6, 6)
+	require.Equal(t, []int{2, 3, 4}, evicted)
+	require.Equal(t, 3, c.Len())
+}
+
+func TestCache_Weighted(t *testing.T) {
+	c := lru.NewWeighted[int, string](10, func(key int, value string) int64 { return int64(len(value)) })
+	var evicted []int
+	c.SetEvictCallback(func(key int, value string) {
+		evicted = append(evicted, key)
+	})
+	require.Equal(t, -1, c.Capacity())
+	require.EqualValues(t, 10, c.MaxWeight())
+
+	c.Set(1, "aaa")
+	c.Set(2, "bbb")
+	c.Set(3, "ccc")
+	require.EqualValues(t, 9, c.Weight())
+	c.Get(1)
+
+	// the least recently used items are evicted until the total weight fits
+	c.Set(4, "dddd")
+	require.Equal(t, []int{2}, evicted)
+	require.EqualValues(t, 10, c.Weight())
+
+	// updating an item updates the weight
+	c.Set(1, "a")
+	require.EqualValues(t, 8, c.Weight())
+	c.Set(4, "dddddddd")
+	require.Equal(t, []int{2, 3}, evicted)
+	require.EqualValues(t, 9, c.Weight())
+
+	// an item heavier than the maximum is evicted immediately
+	c.Set(5, "eeeeeeeeeee")
+	require.Equal(t, []int{2, 3, 5}, evicted)
+	require.EqualValues(t, 9, c.Weight())
+	require.Equal(t, 2, c.Len())
+
+	c.Set(6, "f")
+	c.Delete(6)
+	require.EqualValues(t, 9, c.Weight())
+	c.Set(7, "gg")
+	c.Purge()
+	require.EqualValues(t, 0, c.Weight())
+}
+
+func TestCache_Weighted_Oversized(t *testing.T) {
+	c := lru.NewWeighted[int, int64](50, func(key int, value int64) int64 { return value })
+	var evicted []int
+	c.SetEvictCallback(func(key int, value int64) {
+		evicted = append(evicted, key)
+	})
+	for i := 0; i < 5; i++ {
+		c.Set(i, 10)
+	}
+
+	// the other items are kept intact
+	c.Set(5, 51)
+	require.Equal(t, []int{5}, evicted)
+	require.Equal(t, 5, c.Len())
+	require.EqualValues(t, 50, c.Weight())
+	_, ok := c.Peek(5)
+	require.False(t, ok)
+
+	// an existing item is deleted when updated to an item heavier than the maximum
+	c.Set(0, 51)
+	require.Equal(t, []int{5, 0}, evicted)
+	require.Equal(t, 4, c.Len())
+	require.EqualValues(t, 40, c.Weight())
+	_, ok = c.Peek(0)
+	require.False(t, ok)
+}
+
+func BenchmarkCache_Churn(b *testing.B) {
+	c := lru.New[int, int](lru.WithCapacity(100))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(i, i)
+	}
+}
+
+func TestCache_FIFO(t *testing.T) {
+	c := lru.New[int, int](lru.WithCapacity(3), lru.WithFIFO())
+	c.Set(1, 1)
+	c.Set(2, 2)
+	c.Set(3, 3)
+
+	// neither Get nor Set of existing items updates the order
+	_, ok := c.Get(1)
+	require.True(t, ok)
+	c.Set(2, 20)
+	var keys []int
+	c.Range(func(key int, _ int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	require.Equal(t, []int{3, 2, 1}, keys)
+
+	// the oldest inserted item is evicted
+	c.Set(4, 4)
+	_, ok = c.Peek(1)
+	require.False(t, ok)
+	v, ok := c.Peek(2)
+	require.True(t, ok)
+	require.Equal(t, 20, v)
+	require.Equal(t, 3, c.Len())
+}
@@ -25,9 +25,20 @@ func WithCapacity(capacity int) CacheOption {
 	})
 }
 
+// WithFIFO configures the cache to evict items in the order they were inserted (First In, First Out),
+// instead of the least recently used items.
+// Get and Set of existing items do not update the order, which makes them cheaper than in the default LRU mode.
+func WithFIFO() CacheOption {
+	return funcCacheOption(func(o *options) {
+		o.fifo = true
+	})
+}
+
 // options for a cache instance.
 type options struct {
 	capacity int
+	// fifo is true if the order of items is not updated on access. See WithFIFO.
+	fifo bool
 }
 
 // defaultOptions returns options with default values set.
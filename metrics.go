@@ -0,0 +1,91 @@
+package sc
+
+import (
+	"time"
+)
+
+// MetricsHook receives events of a cache, for instrumentation with arbitrary metrics libraries
+// (e.g. Prometheus, OpenTelemetry, or statsd). See WithMetricsHook.
+//
+// The methods are called in the same situations as the corresponding counters of HitStats are incremented,
+// and are called after the cache releases its internal lock.
+// The methods may be called concurrently from multiple goroutines, and need to be fast,
+// since they are called synchronously by the methods of the cache.
+type MetricsHook[K comparable] interface {
+	// OnHit is called for each fresh cache hit.
+	OnHit(key K)
+	// OnGraceHit is called for each stale cache hit.
+	OnGraceHit(key K)
+	// OnMiss is called for each cache miss.
+	OnMiss(key K)
+	// OnReplacement is called each time a value for key is retrieved, with the time spent on the retrieval
+	// and the resulting error (nil if succeeded).
+	// When retries are enabled by WithRetry, d includes all attempts.
+	OnReplacement(key K, d time.Duration, err error)
+	// OnEviction is called for each item evicted by the backend to make room for new items.
+	OnEviction(key K)
+}
+
+// WithMetricsHook specifies the hook to receive events of the cache. See MetricsHook.
+//
+// The type parameter K needs to be the key type of the cache, otherwise New returns an error.
+func WithMetricsHook[K comparable](hook MetricsHook[K]) CacheOption {
+	return func(c *cacheConfig) {
+		c.metrics = hook
+	}
+}
+
+func (c *cache[K, V]) onHit(key K) {
+	if c.metrics != nil {
+		c.metrics.OnHit(key)
+	}
+}
+
+func (c *cache[K, V]) onGraceHit(key K) {
+	if c.metrics != nil {
+		c.metrics.OnGraceHit(key)
+	}
+}
+
+func (c *cache[K, V]) onMiss(key K) {
+	if c.metrics != nil {
+		c.metrics.OnMiss(key)
+	}
+}
+
+func (c *cache[K, V]) onReplacement(key K, v V, d time.Duration, err error) {
+	if c.metrics != nil {
+		c.metrics.OnReplacement(key, d, err)
+	}
+	if c.replacementCallback != nil {
+		c.replacementCallback(key, v, err, d)
+	}
+}
+
+type hitEventKind int
+
+const (
+	hitEventHit hitEventKind = iota
+	hitEventGraceHit
+	hitEventMiss
+)
+
+// hitEvent is a hit event recorded while holding the lock, to be notified to the hook after releasing the lock.
+type hitEvent[K comparable] struct {
+	key  K
+	kind hitEventKind
+}
+
+// notifyHitEvents notifies the hit events to the hook. c.mu must not be held by the caller.
+func (c *cache[K, V]) notifyHitEvents(events []hitEvent[K]) {
+	for _, e := range events {
+		switch e.kind {
+		case hitEventHit:
+			c.metrics.OnHit(e.key)
+		case hitEventGraceHit:
+			c.metrics.OnGraceHit(e.key)
+		case hitEventMiss:
+			c.metrics.OnMiss(e.key)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingHook records the events of a cache.
+type recordingHook struct {
+	mu                       sync.Mutex
+	hits, graceHits, misses  []string
+	replacements, evictions  []string
+	errors                   []error
+	replacementDurationTotal time.Duration
+}
+
+func (h *recordingHook) OnHit(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hits = append(h.hits, key)
+}
+
+func (h *recordingHook) OnGraceHit(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.graceHits = append(h.graceHits, key)
+}
+
+func (h *recordingHook) OnMiss(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.misses = append(h.misses, key)
+}
+
+func (h *recordingHook) OnReplacement(key string, d time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.replacements = append(h.replacements, key)
+	h.errors = append(h.errors, err)
+	h.replacementDurationTotal += d
+}
+
+func (h *recordingHook) OnEviction(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.evictions = append(h.evictions, key)
+}
+
+func TestCache_MetricsHook(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(2) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				time.Sleep(10 * time.Millisecond)
+				if key == "fail" {
+					return "", errors.New("error")
+				}
+				return "value-" + key, nil
+			}
+			hook := &recordingHook{}
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, time.Minute,
+				append(c.cacheOpts, WithMetricsHook[string](hook))...)
+			assert.NoError(t, err)
+
+			_, _ = cache.Get(context.Background(), "k1") // miss
+			_, _ = cache.Get(context.Background(), "k1") // hit
+			_, _ = cache.GetIfExists("k1")               // hit
+			_, _ = cache.GetIfExists("k2")               // miss
+			_, _ = cache.Peek("k2")                      // not reported
+			_, _ = cache.Get(context.Background(), "fail")
+			_, _ = cache.GetMulti(context.Background(), []string{"k1", "k2"}) // hit, miss
+			time.Sleep(60 * time.Millisecond)
+			_, _ = cache.Get(context.Background(), "k1") // grace hit
+			time.Sleep(20 * time.Millisecond)
+
+			hook.mu.Lock()
+			defer hook.mu.Unlock()
+			assert.Equal(t, []string{"k1", "k1", "k1"}, hook.hits)
+			assert.Equal(t, []string{"k1"}, hook.graceHits)
+			assert.Equal(t, []string{"k1", "k2", "fail", "k2"}, hook.misses)
+			assert.Equal(t, []string{"k1", "fail", "k2", "k1"}, hook.replacements)
+			assert.Equal(t, []error{nil, errors.New("error"), nil, nil}, hook.errors)
+			assert.GreaterOrEqual(t, hook.replacementDurationTotal, 40*time.Millisecond)
+
+			stats := cache.Stats()
+			assert.EqualValues(t, len(hook.hits), stats.Hits)
+			assert.EqualValues(t, len(hook.graceHits), stats.GraceHits)
+			assert.EqualValues(t, len(hook.misses), stats.Misses)
+			assert.EqualValues(t, len(hook.replacements), stats.Replacements)
+			assert.EqualValues(t, len(hook.evictions), stats.Evictions)
+		})
+	}
+}
+
+func TestCache_MetricsHook_Eviction(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	hook := &recordingHook{}
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute, WithLRUBackend(2), WithMetricsHook[string](hook))
+	assert.NoError(t, err)
+
+	_, _ = cache.Get(context.Background(), "k1")
+	_, _ = cache.Get(context.Background(), "k2")
+	_, _ = cache.Get(context.Background(), "k3")
+	cache.Set("k4", "value")
+	assert.Equal(t, []string{"k1", "k2"}, hook.evictions)
+}
+
+func TestCache_MetricsHook_InvalidType(t *testing.T) {
+	t.Parallel()
+
+	fn := func(ctx context.Context, key int) (string, error) { return "", nil }
+	_, err := New[int, string](fn, 0, 0, WithMetricsHook[string](&recordingHook{}))
+	assert.Error(t, err)
+}
+
+func TestCache_ReplacementCallback(t *testing.T) {
+	t.Parallel()
+
+	type replacement struct {
+		key, value string
+		err        error
+	}
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				time.Sleep(10 * time.Millisecond)
+				if key == "fail" {
+					return "", errors.New("error")
+				}
+				return "value-" + key, nil
+			}
+			var mu sync.Mutex
+			var replacements []replacement
+			var minDuration time.Duration = -1
+			onReplacement := func(key string, v string, err error, d time.Duration) {
+				mu.Lock()
+				defer mu.Unlock()
+				replacements = append(replacements, replacement{key, v, err})
+				if minDuration < 0 || d < minDuration {
+					minDuration = d
+				}
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithReplacementCallback(onReplacement))...)
+			assert.NoError(t, err)
+
+			_, _ = cache.Get(context.Background(), "k1")
+			_, _ = cache.Get(context.Background(), "k1") // hit, not reported
+			_, _ = cache.Get(context.Background(), "fail")
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, []replacement{{"k1", "value-k1", nil}, {"fail", "", errors.New("error")}}, replacements)
+			assert.GreaterOrEqual(t, minDuration, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestCache_ReplacementCallback_Batched(t *testing.T) {
+	t.Parallel()
+
+	batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+		values := make(map[string]string, len(keys))
+		for _, key := range keys {
+			if key != "missing" {
+				values[key] = "value-" + key
+			}
+		}
+		return values, nil
+	}
+	var mu sync.Mutex
+	replaced := make(map[string]error)
+	onReplacement := func(key string, v string, err error, d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		replaced[key] = err
+	}
+	cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, WithReplacementCallback(onReplacement))
+	assert.NoError(t, err)
+
+	_, _ = cache.GetMulti(context.Background(), []string{"k1", "k2", "missing"})
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]error{"k1": nil, "k2": nil, "missing": ErrNotFound}, replaced)
+}
+
+func TestCache_ReplacementCallback_InvalidType(t *testing.T) {
+	t.Parallel()
+
+	fn := func(ctx context.Context, key int) (string, error) { return "", nil }
+	_, err := New[int, string](fn, 0, 0, WithReplacementCallback(func(key string, v string, err error, d time.Duration) {}))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
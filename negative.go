@@ -0,0 +1,50 @@
+package sc
+
+import (
+	"context"
+	"errors"
+)
+
+// negative is a cached error result of replaceFn. See WithNegativeCache.
+type negative struct {
+	err error
+	// expiresAt is the time after which the error is no longer served.
+	expiresAt monoTime
+}
+
+// storeError caches err returned by the retrieval of key started at created, if negative caching is enabled.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) storeError(key K, err error, created monoTime) {
+	if c.negatives == nil {
+		return
+	}
+	// Context errors are specific to the call which failed, not to the key - never cache them
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	// Recovered panics are bugs rather than failures of the key - see WithRecoverReplaceFn
+	if errors.Is(err, ErrReplacePanic) {
+		return
+	}
+	c.negatives[key] = negative{err: err, expiresAt: created + monoTime(c.errTTL)}
+}
+
+// cachedError returns the cached error for key, if any.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) cachedError(key K, now monoTime) (error, bool) {
+	n, ok := c.negatives[key]
+	if !ok || n.expiresAt < now {
+		return nil, false
+	}
+	return n.err, true
+}
+
+// pruneErrors removes expired errors from the negative cache.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) pruneErrors(now monoTime) {
+	for key, n := range c.negatives {
+		if n.expiresAt < now {
+			delete(c.negatives, key)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_NegativeCache(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			targetErr := errors.New("test error")
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "", targetErr
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithNegativeCache(250*time.Millisecond))...)
+			assert.NoError(t, err)
+
+			// t=0ms, error is retrieved and cached
+			_, err = cache.Get(context.Background(), "k1")
+			assert.ErrorIs(t, err, targetErr)
+			_, err = cache.Get(context.Background(), "k1")
+			assert.ErrorIs(t, err, targetErr)
+			_, err = cache.GetMulti(context.Background(), []string{"k1"})
+			assert.ErrorIs(t, err, targetErr)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			assert.Equal(t, HitStats{0, 0, 1, 1, 2, 0}, cache.Stats().HitStats)
+			assert.Equal(t, 0, cache.Len())
+			_, ok := cache.GetIfExists("k1")
+			assert.False(t, ok)
+
+			// t=300ms, cached error is expired
+			time.Sleep(300 * time.Millisecond)
+			_, err = cache.Get(context.Background(), "k1")
+			assert.ErrorIs(t, err, targetErr)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+
+			// cached error is cleared by Forget
+			cache.Forget("k1")
+			_, err = cache.Get(context.Background(), "k1")
+			assert.ErrorIs(t, err, targetErr)
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+
+			// cached error is cleared by Set
+			cache.Set("k1", "value")
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value", v)
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+		})
+	}
+}
+
+// TestCache_NegativeCache_Stale ensures cached errors do not hide stale values.
+func TestCache_NegativeCache_Stale(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				if atomic.AddInt64(&cnt, 1) == 1 {
+					return "value", nil
+				}
+				return "", errors.New("test error")
+			}
+			cache, err := New[string, string](replaceFn, 100*time.Millisecond, time.Minute,
+				append(c.cacheOpts, WithNegativeCache(time.Minute))...)
+			assert.NoError(t, err)
+
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value", v)
+
+			// t=200ms, background update fails, but the stale value is still served
+			time.Sleep(200 * time.Millisecond)
+			for i := 0; i < 2; i++ {
+				v, err = cache.Get(context.Background(), "k1")
+				assert.NoError(t, err)
+				assert.Equal(t, "value", v)
+				time.Sleep(50 * time.Millisecond)
+			}
+			assert.EqualValues(t, 0, cache.Stats().ErrorHits)
+		})
+	}
+}
+
+// TestCache_NegativeCache_ForgetIfOlderThan ensures cached errors are cleared along with items forgotten by
+// (*Cache).ForgetIfOlderThan.
+func TestCache_NegativeCache_ForgetIfOlderThan(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			targetErr := errors.New("test error")
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				if atomic.AddInt64(&cnt, 1) == 1 {
+					return "value", nil
+				}
+				return "", targetErr
+			}
+			cache, err := New[string, string](replaceFn, 100*time.Millisecond, time.Minute,
+				append(c.cacheOpts, WithNegativeCache(time.Minute))...)
+			assert.NoError(t, err)
+
+			_, _ = cache.Get(context.Background(), "k1")
+
+			// t=200ms, background update fails, and the error is cached
+			time.Sleep(200 * time.Millisecond)
+			_, _ = cache.Get(context.Background(), "k1")
+			time.Sleep(50 * time.Millisecond)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+
+			// cached error is cleared along with the forgotten item
+			assert.True(t, cache.ForgetIfOlderThan("k1", time.Now()))
+			_, err = cache.Get(context.Background(), "k1")
+			assert.ErrorIs(t, err, targetErr)
+			assert.EqualValues(t, 3, atomic.LoadInt64(&cnt))
+		})
+	}
+}
+
+func TestCache_NegativeCache_Disabled(t *testing.T) {
+	t.Parallel()
+
+	var cnt int64
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt64(&cnt, 1)
+		return "", errors.New("test error")
+	}
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute)
+	assert.NoError(t, err)
+
+	_, err = cache.Get(context.Background(), "k1")
+	assert.Error(t, err)
+	_, err = cache.Get(context.Background(), "k1")
+	assert.Error(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+
+	_, err = New[string, string](replaceFn, time.Minute, time.Minute, WithNegativeCache(-time.Second))
+	assert.Error(t, err)
+}
+
+// TestCache_NegativeCache_ContextError ensures context errors are neither cached nor poison the cache for followers.
+func TestCache_NegativeCache_ContextError(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				if atomic.AddInt64(&cnt, 1) == 1 {
+					ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+					defer cancel()
+					<-ctx.Done()
+					return "partial", ctx.Err()
+				}
+				return "value", nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithNegativeCache(time.Minute))...)
+			assert.NoError(t, err)
+
+			// leader and follower both receive the context error
+			var wg sync.WaitGroup
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := cache.Get(context.Background(), "k1")
+					assert.ErrorIs(t, err, context.DeadlineExceeded)
+				}()
+			}
+			wg.Wait()
+
+			// neither the partial value nor the error is cached
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "value", v)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package otel provides OpenTelemetry metrics for caches of github.com/motoki317/sc.
+//
+// The package is a separate module, so that the core package stays free of dependencies.
+// Use Register to report the stats of a cache as asynchronous instruments, and InstrumentReplaceFn to record
+// the durations of replaceFn calls as a histogram:
+//
+//	replaceFn, _ := otel.InstrumentReplaceFn(meter, "users", getUser)
+//	cache, _ := sc.New[string, *User](replaceFn, time.Minute, 2*time.Minute)
+//	_, _ = otel.Register(meter, "users", cache)
+package otel
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/motoki317/sc"
+)
+
+// CacheNameKey is the attribute key identifying the cache, whose value is the name passed to Register and
+// InstrumentReplaceFn.
+const CacheNameKey = attribute.Key("sc.cache.name")
+
+// Stater is implemented by both *sc.Cache and *sc.ShardedCache.
+type Stater interface {
+	Stats() sc.Stats
+}
+
+// Register registers asynchronous instruments reporting the stats of cache, labeled with name (see CacheNameKey):
+//
+//   - sc.cache.hits, sc.cache.grace_hits, sc.cache.misses, sc.cache.replacements, sc.cache.error_hits,
+//     and sc.cache.evictions: counters of the corresponding fields of sc.HitStats.
+//   - sc.cache.size, sc.cache.capacity, and sc.cache.weight: gauges of the corresponding fields of sc.SizeStats.
+//     Capacity is not reported for caches without a capacity, such as the map backend.
+//
+// Stats are read once per collection. Call Unregister of the returned registration to stop reporting,
+// e.g. when the cache is no longer used.
+func Register(meter metric.Meter, name string, cache Stater) (metric.Registration, error) {
+	counter := func(name, desc string) (metric.Int64ObservableCounter, error) {
+		return meter.Int64ObservableCounter(name, metric.WithDescription(desc), metric.WithUnit("{request}"))
+	}
+	hits, err := counter("sc.cache.hits", "Number of fresh cache hits.")
+	if err != nil {
+		return nil, err
+	}
+	graceHits, err := counter("sc.cache.grace_hits", "Number of stale cache hits.")
+	if err != nil {
+		return nil, err
+	}
+	misses, err := counter("sc.cache.misses", "Number of cache misses.")
+	if err != nil {
+		return nil, err
+	}
+	replacements, err := meter.Int64ObservableCounter("sc.cache.replacements",
+		metric.WithDescription("Number of replaceFn calls."), metric.WithUnit("{call}"))
+	if err != nil {
+		return nil, err
+	}
+	errorHits, err := counter("sc.cache.error_hits", "Number of cached errors returned.")
+	if err != nil {
+		return nil, err
+	}
+	evictions, err := meter.Int64ObservableCounter("sc.cache.evictions",
+		metric.WithDescription("Number of items evicted to make room for new items."), metric.WithUnit("{item}"))
+	if err != nil {
+		return nil, err
+	}
+	size, err := meter.Int64ObservableGauge("sc.cache.size",
+		metric.WithDescription("Current number of items in the cache."), metric.WithUnit("{item}"))
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := meter.Int64ObservableGauge("sc.cache.capacity",
+		metric.WithDescription("Maximum number of items in the cache."), metric.WithUnit("{item}"))
+	if err != nil {
+		return nil, err
+	}
+	weight, err := meter.Int64ObservableGauge("sc.cache.weight",
+		metric.WithDescription("Current total weight of the items in the cache."))
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := metric.WithAttributes(CacheNameKey.String(name))
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := cache.Stats()
+		o.ObserveInt64(hits, int64(stats.Hits), attrs)
+		o.ObserveInt64(graceHits, int64(stats.GraceHits), attrs)
+		o.ObserveInt64(misses, int64(stats.Misses), attrs)
+		o.ObserveInt64(replacements, int64(stats.Replacements), attrs)
+		o.ObserveInt64(errorHits, int64(stats.ErrorHits), attrs)
+		o.ObserveInt64(evictions, int64(stats.Evictions), attrs)
+		o.ObserveInt64(size, int64(stats.Size), attrs)
+		if stats.Capacity >= 0 {
+			o.ObserveInt64(capacity, int64(stats.Capacity), attrs)
+		}
+		o.ObserveInt64(weight, stats.Weight, attrs)
+		return nil
+	}, hits, graceHits, misses, replacements, errorHits, evictions, size, capacity, weight)
+}
+
+// InstrumentReplaceFn wraps fn, so that the time spent in each call of fn is recorded to the histogram
+// sc.cache.replacement.duration in seconds, labeled with name (see CacheNameKey) and whether the call failed
+// (the attribute error.occurred). Pass the returned function to sc.New in place of fn.
+func InstrumentReplaceFn[K comparable, V any](
+	meter metric.Meter, name string, fn func(ctx context.Context, key K) (V, error),
+) (func(ctx context.Context, key K) (V, error), error) {
+	duration, err := meter.Float64Histogram("sc.cache.replacement.duration",
+		metric.WithDescription("Time spent in a single replaceFn call."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	success := metric.WithAttributes(CacheNameKey.String(name), attribute.Bool("error.occurred", false))
+	failure := metric.WithAttributes(CacheNameKey.String(name), attribute.Bool("error.occurred", true))
+	return func(ctx context.Context, key K) (V, error) {
+		start := time.Now()
+		v, err := fn(ctx, key)
+		opt := success
+		if err != nil {
+			opt = failure
+		}
+		duration.Record(ctx, time.Since(start).Seconds(), opt)
+		return v, err
+	}, nil
+}
@@ -0,0 +1,138 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/motoki317/sc"
+	"github.com/motoki317/sc/otel"
+)
+
+// collect returns the collected metrics by their names.
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+// value returns the value of the single data point of an int64 sum or gauge.
+func value(t *testing.T, data metricdata.Aggregation) int64 {
+	var points []metricdata.DataPoint[int64]
+	switch d := data.(type) {
+	case metricdata.Sum[int64]:
+		points = d.DataPoints
+	case metricdata.Gauge[int64]:
+		points = d.DataPoints
+	default:
+		t.Fatalf("unexpected aggregation %T", data)
+	}
+	require.Len(t, points, 1)
+	name, ok := points[0].Attributes.Value(otel.CacheNameKey)
+	require.True(t, ok)
+	require.Equal(t, "test", name.AsString())
+	return points[0].Value
+}
+
+func TestRegister(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	cache, err := sc.New[string, string](replaceFn, time.Minute, time.Minute, sc.WithLRUBackend(2))
+	require.NoError(t, err)
+	reg, err := otel.Register(meter, "test", cache)
+	require.NoError(t, err)
+
+	_, _ = cache.Get(context.Background(), "k1") // miss
+	_, _ = cache.Get(context.Background(), "k1") // hit
+	_, _ = cache.Get(context.Background(), "k2") // miss
+	_, _ = cache.Get(context.Background(), "k3") // miss, evicts k1
+
+	metrics := collect(t, reader)
+	require.EqualValues(t, 1, value(t, metrics["sc.cache.hits"]))
+	require.EqualValues(t, 0, value(t, metrics["sc.cache.grace_hits"]))
+	require.EqualValues(t, 3, value(t, metrics["sc.cache.misses"]))
+	require.EqualValues(t, 3, value(t, metrics["sc.cache.replacements"]))
+	require.EqualValues(t, 0, value(t, metrics["sc.cache.error_hits"]))
+	require.EqualValues(t, 1, value(t, metrics["sc.cache.evictions"]))
+	require.EqualValues(t, 2, value(t, metrics["sc.cache.size"]))
+	require.EqualValues(t, 2, value(t, metrics["sc.cache.capacity"]))
+	require.EqualValues(t, 0, value(t, metrics["sc.cache.weight"]))
+
+	require.NoError(t, reg.Unregister())
+	require.Empty(t, collect(t, reader))
+}
+
+func TestRegister_Sharded(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	replaceFn := func(ctx context.Context, key int) (int, error) {
+		return key, nil
+	}
+	cache, err := sc.NewSharded[int, int](replaceFn, time.Minute, time.Minute, sc.WithShards(4))
+	require.NoError(t, err)
+	_, err = otel.Register(meter, "test", cache)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, _ = cache.Get(context.Background(), i)
+	}
+
+	metrics := collect(t, reader)
+	require.EqualValues(t, 10, value(t, metrics["sc.cache.misses"]))
+	require.EqualValues(t, 10, value(t, metrics["sc.cache.size"]))
+	// map backend has no capacity
+	require.NotContains(t, metrics, "sc.cache.capacity")
+}
+
+func TestInstrumentReplaceFn(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		time.Sleep(10 * time.Millisecond)
+		if key == "fail" {
+			return "", errors.New("error")
+		}
+		return "value-" + key, nil
+	}
+	instrumented, err := otel.InstrumentReplaceFn(meter, "test", replaceFn)
+	require.NoError(t, err)
+	cache, err := sc.New[string, string](instrumented, time.Minute, time.Minute)
+	require.NoError(t, err)
+
+	_, _ = cache.Get(context.Background(), "k1")
+	_, _ = cache.Get(context.Background(), "k1") // hit, not recorded
+	_, _ = cache.Get(context.Background(), "k2")
+	_, _ = cache.Get(context.Background(), "fail")
+
+	histogram, ok := collect(t, reader)["sc.cache.replacement.duration"].(metricdata.Histogram[float64])
+	require.True(t, ok)
+	counts := make(map[bool]uint64)
+	for _, p := range histogram.DataPoints {
+		name, _ := p.Attributes.Value(otel.CacheNameKey)
+		require.Equal(t, "test", name.AsString())
+		failed, _ := p.Attributes.Value(attribute.Key("error.occurred"))
+		counts[failed.AsBool()] += p.Count
+		minDuration, ok := p.Min.Value()
+		require.True(t, ok)
+		require.GreaterOrEqual(t, minDuration, 0.01)
+	}
+	require.Equal(t, map[bool]uint64{false: 2, true: 1}, counts)
+}
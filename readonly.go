@@ -0,0 +1,46 @@
+package sc
+
+import (
+	"context"
+)
+
+// ReadOnlyCache is a read-only view of a Cache. See (*Cache).ReadOnly.
+type ReadOnlyCache[K comparable, V any] interface {
+	// Get is the same as (*Cache).Get.
+	Get(ctx context.Context, key K) (V, error)
+	// GetIfExists is the same as (*Cache).GetIfExists.
+	GetIfExists(key K) (v V, ok bool)
+	// Peek is the same as (*Cache).Peek.
+	Peek(key K) (v V, ok bool)
+	// Stats is the same as (*Cache).Stats.
+	Stats() Stats
+}
+
+// readOnlyCache wraps Cache so that the view cannot be converted back to Cache by type assertion.
+type readOnlyCache[K comparable, V any] struct {
+	c *Cache[K, V]
+}
+
+// ReadOnly returns a read-only view of the cache, which cannot modify the cache by Set, Forget, Purge, and so on.
+// This is useful to share the cache with other modules which should only read from it.
+//
+// Note that Get through the view may still retrieve and store new items, just like Get of the cache.
+func (c *Cache[K, V]) ReadOnly() ReadOnlyCache[K, V] {
+	return readOnlyCache[K, V]{c: c}
+}
+
+func (r readOnlyCache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return r.c.Get(ctx, key)
+}
+
+func (r readOnlyCache[K, V]) GetIfExists(key K) (v V, ok bool) {
+	return r.c.GetIfExists(key)
+}
+
+func (r readOnlyCache[K, V]) Peek(key K) (v V, ok bool) {
+	return r.c.Peek(key)
+}
+
+func (r readOnlyCache[K, V]) Stats() Stats {
+	return r.c.Stats()
+}
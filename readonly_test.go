@@ -0,0 +1,42 @@
+package sc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_ReadOnly(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute)
+	assert.NoError(t, err)
+
+	ro := cache.ReadOnly()
+	_, ok := ro.(*Cache[string, string])
+	assert.False(t, ok, "read-only view should not be converted back to Cache")
+
+	_, ok = ro.Peek("k1")
+	assert.False(t, ok)
+	_, ok = ro.GetIfExists("k1")
+	assert.False(t, ok)
+
+	v, err := ro.Get(context.Background(), "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-k1", v)
+
+	v, ok = ro.Peek("k1")
+	assert.True(t, ok)
+	assert.Equal(t, "value-k1", v)
+	v, ok = ro.GetIfExists("k1")
+	assert.True(t, ok)
+	assert.Equal(t, "value-k1", v)
+
+	assert.Equal(t, cache.Stats(), ro.Stats())
+	assert.Equal(t, HitStats{1, 0, 2, 1, 0, 0}, ro.Stats().HitStats)
+}
@@ -0,0 +1,122 @@
+package sc
+
+import (
+	"context"
+	"sync"
+)
+
+// lease is the borrow count of a value stored in the cache. See GetRef.
+type lease[V any] struct {
+	v     V
+	count int
+	// removed is true if the value has left the cache, and is to be disposed when count reaches 0.
+	removed bool
+}
+
+// ref tracks the borrow taken by a single GetRef call.
+type ref[V any] struct {
+	// lease is the borrow of the value stored in the cache, if the value was served from the cache.
+	lease *lease[V]
+	// call is the call the caller waited for, if the value was retrieved by the call.
+	// The borrow is taken by the call when its value is stored - see lendCall.
+	call *call[V]
+}
+
+// GetRef is similar to Get, but also borrows the returned value until release is called.
+//
+// Combined with WithDispose, a value is disposed only after it leaves the cache *and* all borrows of the value
+// are released. This is useful for caching resources such as connections or file handles,
+// which must not be disposed while still in use by the callers.
+//
+// release must be called exactly once when the caller finishes using the value, even if an error is returned.
+// Later calls to release are ignored.
+// Values not stored in the cache (e.g. values retrieved while the key was forgotten) are never disposed,
+// and borrowing them has no effect. Without WithDispose, GetRef behaves exactly like Get.
+func (c *cache[K, V]) GetRef(ctx context.Context, key K) (v V, release func(), err error) {
+	if c.leases == nil {
+		v, _, err = c.get(ctx, key, getOptions{}, nil)
+		return v, func() {}, err
+	}
+
+	r := &ref[V]{}
+	v, _, err = c.get(ctx, key, getOptions{}, r)
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			c.mu.Lock()
+			c.release(r)
+			c.unlock()
+		})
+	}
+	return v, release, err
+}
+
+// lend borrows the value stored for key on behalf of r, if r is non-nil and has not waited for a call.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) lend(key K, r *ref[V]) {
+	if r == nil || r.call != nil || c.leases == nil {
+		// A ref which waited for a call borrows the value via the call
+		return
+	}
+	r.lease = c.borrow(key)
+}
+
+// attach makes r borrow the value of cl when it is stored, if r is non-nil.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) attach(r *ref[V], cl *call[V]) {
+	if r == nil || c.leases == nil {
+		return
+	}
+	// Strict request coalescing may wait for another call - release the borrow via the previous call first
+	c.release(r)
+	r.call = cl
+	cl.borrowers++
+}
+
+// lendCall borrows the value just stored for key on behalf of the callers of GetRef waiting for cl.
+// Nothing is borrowed if the value did not survive the store, e.g. when it is heavier than the maximum weight.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) lendCall(key K, cl *call[V]) {
+	if cl.borrowers == 0 || c.leases == nil {
+		return
+	}
+	l := c.borrow(key)
+	if l == nil {
+		return
+	}
+	cl.lease = l
+	l.count += cl.borrowers - 1
+}
+
+// borrow increments the borrow count of the value stored for key, returning nil if no value is stored for key.
+// c.mu must be held by the caller.
+func (c *cache[K, V]) borrow(key K) *lease[V] {
+	l, ok := c.leases[key]
+	if !ok {
+		val, ok := c.values.Peek(key)
+		if !ok {
+			return nil
+		}
+		l = &lease[V]{v: val.v}
+		c.leases[key] = l
+	}
+	l.count++
+	return l
+}
+
+// release releases the borrow of r, disposing the value if it has left the cache and is no longer borrowed.
+// c.mu must be held by the caller, and the caller must release the lock with unlock.
+func (c *cache[K, V]) release(r *ref[V]) {
+	l := r.lease
+	if r.call != nil {
+		l = r.call.lease
+	}
+	r.lease, r.call = nil, nil
+	if l == nil {
+		return
+	}
+	l.count--
+	if l.count == 0 && l.removed {
+		c.disposals = append(c.disposals, l.v)
+	}
+}
@@ -0,0 +1,155 @@
+package sc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_GetRef(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (*resource, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				time.Sleep(100 * time.Millisecond)
+				return &resource{name: key + "-" + string(rune('0'+n))}, nil
+			}
+			var mu sync.Mutex
+			var disposed []string
+			dispose := func(r *resource) {
+				mu.Lock()
+				r.disposed++
+				disposed = append(disposed, r.name)
+				mu.Unlock()
+			}
+			getDisposed := func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string(nil), disposed...)
+			}
+			cache, err := New[string, *resource](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithDispose(dispose))...)
+			assert.NoError(t, err)
+
+			// borrowed by the callers waiting for the call
+			var wg sync.WaitGroup
+			releases := make(chan func(), 2)
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					v, release, err := cache.GetRef(context.Background(), "k1")
+					assert.NoError(t, err)
+					assert.Equal(t, "k1-1", v.name)
+					releases <- release
+				}()
+			}
+			wg.Wait()
+			close(releases)
+			// borrowed from the cache
+			v, release, err := cache.GetRef(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "k1-1", v.name)
+
+			// not disposed until all borrows are released
+			cache.Forget("k1")
+			assert.Empty(t, getDisposed())
+			release()
+			release() // ignored
+			assert.Empty(t, getDisposed())
+			for release := range releases {
+				release()
+			}
+			assert.Equal(t, []string{"k1-1"}, getDisposed())
+
+			// values released before removal are disposed on removal
+			_, release, err = cache.GetRef(context.Background(), "k1")
+			assert.NoError(t, err)
+			release()
+			assert.Equal(t, []string{"k1-1"}, getDisposed())
+			cache.Set("k1", &resource{name: "k1-set"})
+			assert.Equal(t, []string{"k1-1", "k1-2"}, getDisposed())
+
+			// values not stored in the cache are never disposed
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				cache.Forget("k2")
+			}()
+			v, release, err = cache.GetRef(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "k2-3", v.name)
+			release()
+			cache.Purge()
+			assert.Equal(t, []string{"k1-1", "k1-2", "k1-set"}, getDisposed())
+		})
+	}
+}
+
+func TestCache_GetRef_NoDispose(t *testing.T) {
+	t.Parallel()
+
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		return "value-" + key, nil
+	}
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute)
+	assert.NoError(t, err)
+
+	v, release, err := cache.GetRef(context.Background(), "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-k1", v)
+	release()
+}
+
+func TestCache_GetRef_MaxWeight(t *testing.T) {
+	t.Parallel()
+
+	var cnt int64
+	replaceFn := func(ctx context.Context, key string) (*resource, error) {
+		n := atomic.AddInt64(&cnt, 1)
+		if n == 1 {
+			return &resource{name: "oversized"}, nil
+		}
+		return &resource{name: key + "-" + string(rune('0'+n))}, nil
+	}
+	weigher := func(key string, r *resource) int64 {
+		if r.name == "oversized" {
+			return 100
+		}
+		return 1
+	}
+	var disposed []string
+	dispose := func(r *resource) {
+		r.disposed++
+		disposed = append(disposed, r.name)
+	}
+	cache, err := New[string, *resource](replaceFn, time.Minute, time.Minute,
+		WithMaxWeight(10, weigher), WithDispose(dispose))
+	assert.NoError(t, err)
+
+	// the oversized value is evicted right after being stored, and is not borrowed
+	v, release, err := cache.GetRef(context.Background(), "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, "oversized", v.name)
+	release()
+	assert.Equal(t, []string{"oversized"}, disposed)
+
+	// the next value for the same key is borrowed as usual
+	v, release, err = cache.GetRef(context.Background(), "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, "k1-2", v.name)
+	cache.Forget("k1")
+	assert.Equal(t, []string{"oversized"}, disposed)
+	release()
+	assert.Equal(t, []string{"oversized", "k1-2"}, disposed)
+	assert.Equal(t, 1, v.disposed)
+}
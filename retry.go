@@ -0,0 +1,32 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// retry calls fn until it succeeds, or until the attempts configured by WithRetry are exhausted.
+// Returns the number of times fn was called. The time spent in each call of fn is recorded to the stats.
+func (c *cache[K, V]) retry(ctx context.Context, fn func() error) (attempts int) {
+	for {
+		start := time.Now()
+		err := fn()
+		c.timers.record(time.Since(start))
+		attempts++
+		if err == nil || errors.Is(err, ErrSkipCache) || attempts >= c.retryAttempts || errors.Is(err, ErrNotFound) ||
+			errors.Is(err, ErrReplacePanic) || ctx.Err() != nil {
+			return attempts
+		}
+		if c.retryBackoff == nil {
+			continue
+		}
+		timer := time.NewTimer(c.retryBackoff(attempts))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts
+		}
+	}
+}
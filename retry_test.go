@@ -0,0 +1,116 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Retry(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				n := atomic.AddInt64(&cnt, 1)
+				if key == "fail" || n < 3 {
+					return "", errors.New("transient error")
+				}
+				return "result-" + key, nil
+			}
+			var mu sync.Mutex
+			var backoffs []int
+			backoff := func(attempt int) time.Duration {
+				mu.Lock()
+				backoffs = append(backoffs, attempt)
+				mu.Unlock()
+				return 50 * time.Millisecond
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute,
+				append(c.cacheOpts, WithRetry(3, backoff))...)
+			assert.NoError(t, err)
+
+			// coalesced callers share the retries
+			t0 := time.Now()
+			var wg sync.WaitGroup
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					v, err := cache.Get(context.Background(), "k1")
+					assert.NoError(t, err)
+					assert.Equal(t, "result-k1", v)
+				}()
+			}
+			wg.Wait()
+			assert.InDelta(t, 100*time.Millisecond, time.Since(t0), float64(50*time.Millisecond))
+			assert.EqualValues(t, 3, cnt)
+			assert.Equal(t, []int{1, 2}, backoffs)
+			assert.EqualValues(t, 3, cache.HitStats().Replacements)
+
+			// the last error is returned after all attempts fail
+			_, err = cache.Get(context.Background(), "fail")
+			assert.EqualError(t, err, "transient error")
+			assert.EqualValues(t, 6, cnt)
+		})
+	}
+}
+
+func TestCache_Retry_NotFound(t *testing.T) {
+	t.Parallel()
+
+	var cnt int64
+	batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+		atomic.AddInt64(&cnt, 1)
+		return nil, nil
+	}
+	cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, WithRetry(3, nil))
+	assert.NoError(t, err)
+
+	_, err = cache.Get(context.Background(), "k1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualValues(t, 1, cnt)
+}
+
+func Test_cache_retry_ContextDone(t *testing.T) {
+	t.Parallel()
+
+	c := &cache[string, string]{retryAttempts: 3, retryBackoff: func(int) time.Duration { return time.Hour }}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	var cnt int
+	attempts := c.retry(ctx, func() error {
+		cnt++
+		return errors.New("error")
+	})
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, 1, cnt)
+
+	// no more attempts after ctx is done
+	attempts = c.retry(ctx, func() error {
+		cnt++
+		return errors.New("error")
+	})
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, 2, cnt)
+}
+
+func TestNew_InvalidRetry(t *testing.T) {
+	t.Parallel()
+
+	fn := func(ctx context.Context, s string) (string, error) { return "", nil }
+	_, err := New[string, string](fn, 0, 0, WithRetry(-1, nil))
+	assert.Error(t, err)
+}
@@ -43,6 +43,7 @@ func nonStrictCaches(cap int) []testCase {
 		{name: "map cache", cacheOpts: []CacheOption{WithMapBackend(cap)}},
 		{name: "LRU cache", cacheOpts: []CacheOption{WithLRUBackend(cap)}},
 		{name: "2Q cache", cacheOpts: []CacheOption{With2QBackend(cap)}},
+		{name: "FIFO cache", cacheOpts: []CacheOption{WithFIFOBackend(cap)}},
 	}
 }
 
@@ -0,0 +1,432 @@
+package sc
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// NewSharded creates a new cache instance which partitions keys across multiple independent caches (shards).
+//
+// Each shard has its own lock, backend, and ongoing calls, reducing lock contention under high parallelism
+// across many keys. Keys are assigned to shards by hashing - see WithShards and WithHasher.
+//
+// Options other than WithShards and WithHasher apply to each shard, except that the capacity is divided among shards.
+// Note that size-based options such as WithSizeThresholdHook therefore observe the size of each shard.
+//
+// See New for the details of the other arguments.
+func NewSharded[K comparable, V any](replaceFn replaceFunc[K, V], freshFor, ttl time.Duration, options ...CacheOption) (*ShardedCache[K, V], error) {
+	config := cacheConfig{shards: runtime.NumCPU()}
+	for _, option := range options {
+		option(&config)
+	}
+	if config.shards <= 0 {
+		return nil, invalidOption("number of shards needs to be greater than 0")
+	}
+	var hasher func(key K) uint64
+	if config.hasher != nil {
+		var ok bool
+		if hasher, ok = config.hasher.(func(key K) uint64); !ok {
+			return nil, invalidOption("hasher needs to accept the key type of the cache")
+		}
+	} else {
+		var ok bool
+		if hasher, ok = defaultHasher[K](); !ok {
+			return nil, invalidOption("hasher needs to be specified for the key type of the cache")
+		}
+	}
+
+	if config.keyNormalizer != nil {
+		normalize, ok := config.keyNormalizer.(func(key K) K)
+		if !ok {
+			return nil, invalidOption("key normalizer needs to accept the key type of the cache")
+		}
+		// Keys normalizing to the same key need to be assigned to the same shard
+		hash := hasher
+		hasher = func(key K) uint64 { return hash(normalize(key)) }
+	}
+
+	n := config.shards
+	shardOptions := append(options[:len(options):len(options)], func(c *cacheConfig) {
+		c.shards = 0
+		c.hasher = nil
+		c.capacity = (c.capacity + n - 1) / n
+		c.maxWeight = (c.maxWeight + int64(n) - 1) / int64(n)
+	})
+	shards := make([]*Cache[K, V], n)
+	for i := range shards {
+		c, err := New(replaceFn, freshFor, ttl, shardOptions...)
+		if err != nil {
+			return nil, err
+		}
+		shards[i] = c
+	}
+	return &ShardedCache[K, V]{shards: shards, hasher: hasher}, nil
+}
+
+// defaultHasher returns the function to hash keys of K into shards, or false if K cannot be hashed automatically.
+func defaultHasher[K comparable]() (func(key K) uint64, bool) {
+	seed := maphash.MakeSeed()
+	switch reflect.TypeOf((*K)(nil)).Elem().Kind() {
+	case reflect.String:
+		return func(key K) uint64 {
+			if s, ok := any(key).(string); ok {
+				return maphash.String(seed, s)
+			}
+			return maphash.String(seed, reflect.ValueOf(key).String())
+		}, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(key K) uint64 {
+			return mix64(uint64(reflect.ValueOf(key).Int()))
+		}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(key K) uint64 {
+			return mix64(reflect.ValueOf(key).Uint())
+		}, true
+	case reflect.Bool:
+		return func(key K) uint64 {
+			if reflect.ValueOf(key).Bool() {
+				return 1
+			}
+			return 0
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+// mix64 scatters the bits of x, so that sequential integer keys are evenly distributed among shards.
+func mix64(x uint64) uint64 {
+	// splitmix64 finalizer
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// ShardedCache represents a cache instance partitioned into multiple shards. See NewSharded.
+// All methods are safe to be called from multiple goroutines.
+//
+// Methods operating on a single key behave the same as those of Cache.
+// Methods operating on all keys (e.g. Purge) are applied to each shard in turn, and are therefore not atomic
+// across shards.
+type ShardedCache[K comparable, V any] struct {
+	shards []*Cache[K, V]
+	hasher func(key K) uint64
+}
+
+// shard returns the shard responsible for key.
+func (s *ShardedCache[K, V]) shard(key K) *Cache[K, V] {
+	return s.shards[s.hasher(key)%uint64(len(s.shards))]
+}
+
+// Get is the same as (*Cache).Get.
+func (s *ShardedCache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return s.shard(key).Get(ctx, key)
+}
+
+// GetFresh is the same as (*Cache).GetFresh.
+func (s *ShardedCache[K, V]) GetFresh(ctx context.Context, key K) (V, error) {
+	return s.shard(key).GetFresh(ctx, key)
+}
+
+// GetWithStatus is the same as (*Cache).GetWithStatus.
+func (s *ShardedCache[K, V]) GetWithStatus(ctx context.Context, key K) (V, Status, error) {
+	return s.shard(key).GetWithStatus(ctx, key)
+}
+
+// TryGetOrError is the same as (*Cache).TryGetOrError.
+func (s *ShardedCache[K, V]) TryGetOrError(ctx context.Context, key K) (V, error) {
+	return s.shard(key).TryGetOrError(ctx, key)
+}
+
+// GetWith is the same as (*Cache).GetWith.
+func (s *ShardedCache[K, V]) GetWith(ctx context.Context, key K, fn replaceFunc[K, V]) (V, error) {
+	return s.shard(key).GetWith(ctx, key, fn)
+}
+
+// GetWithOptions is the same as (*Cache).GetWithOptions.
+func (s *ShardedCache[K, V]) GetWithOptions(ctx context.Context, key K, options ...CallOption) (V, error) {
+	return s.shard(key).GetWithOptions(ctx, key, options...)
+}
+
+// TryGet is the same as (*Cache).TryGet.
+func (s *ShardedCache[K, V]) TryGet(ctx context.Context, key K) (v V, ok bool) {
+	return s.shard(key).TryGet(ctx, key)
+}
+
+// GetIfExists is the same as (*Cache).GetIfExists.
+func (s *ShardedCache[K, V]) GetIfExists(key K) (v V, ok bool) {
+	return s.shard(key).GetIfExists(key)
+}
+
+// GetIfExistsDetailed is the same as (*Cache).GetIfExistsDetailed.
+func (s *ShardedCache[K, V]) GetIfExistsDetailed(key K) (v V, state EntryState) {
+	return s.shard(key).GetIfExistsDetailed(key)
+}
+
+// Peek is the same as (*Cache).Peek.
+func (s *ShardedCache[K, V]) Peek(key K) (v V, ok bool) {
+	return s.shard(key).Peek(key)
+}
+
+// Notify is the same as (*Cache).Notify.
+func (s *ShardedCache[K, V]) Notify(ctx context.Context, key K) {
+	s.shard(key).Notify(ctx, key)
+}
+
+// NotifyAll is similar to (*Cache).NotifyAll, acquiring the lock of each shard only once.
+func (s *ShardedCache[K, V]) NotifyAll(ctx context.Context, keys []K) {
+	for i, shardKeys := range s.split(keys) {
+		if len(shardKeys) > 0 {
+			s.shards[i].NotifyAll(ctx, shardKeys)
+		}
+	}
+}
+
+// NotifyChan is the same as (*Cache).NotifyChan.
+func (s *ShardedCache[K, V]) NotifyChan(ctx context.Context, key K) <-chan struct{} {
+	return s.shard(key).NotifyChan(ctx, key)
+}
+
+// GetAndForget is the same as (*Cache).GetAndForget.
+func (s *ShardedCache[K, V]) GetAndForget(ctx context.Context, key K) (V, error) {
+	return s.shard(key).GetAndForget(ctx, key)
+}
+
+// Set is the same as (*Cache).Set.
+func (s *ShardedCache[K, V]) Set(key K, v V) {
+	s.shard(key).Set(key, v)
+}
+
+// Forget is the same as (*Cache).Forget.
+func (s *ShardedCache[K, V]) Forget(key K) {
+	s.shard(key).Forget(key)
+}
+
+// ForgetIfOlderThan is the same as (*Cache).ForgetIfOlderThan.
+func (s *ShardedCache[K, V]) ForgetIfOlderThan(key K, t time.Time) bool {
+	return s.shard(key).ForgetIfOlderThan(key, t)
+}
+
+// GetMulti is similar to (*Cache).GetMulti, retrieving keys of each shard concurrently.
+//
+// If retrieval of any key fails, GetMulti returns the error of one of the failed keys,
+// together with the values that succeeded.
+func (s *ShardedCache[K, V]) GetMulti(ctx context.Context, keys []K) (map[K]V, error) {
+	keysByShard := s.split(keys)
+	results := make([]map[K]V, len(s.shards))
+	errs := make([]error, len(s.shards))
+	var wg sync.WaitGroup
+	for i, shardKeys := range keysByShard {
+		if len(shardKeys) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, shardKeys []K) {
+			defer wg.Done()
+			results[i], errs[i] = s.shards[i].GetMulti(ctx, shardKeys)
+		}(i, shardKeys)
+	}
+	wg.Wait()
+
+	values := make(map[K]V, len(keys))
+	var firstErr error
+	for i := range s.shards {
+		for key, v := range results[i] {
+			values[key] = v
+		}
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+	}
+	return values, firstErr
+}
+
+// GetMultiIfExists is similar to (*Cache).GetMultiIfExists, taking the lock of each shard only once.
+// Note that items of different shards may be retrieved at different times.
+func (s *ShardedCache[K, V]) GetMultiIfExists(keys []K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for i, shardKeys := range s.split(keys) {
+		if len(shardKeys) == 0 {
+			continue
+		}
+		for key, v := range s.shards[i].GetMultiIfExists(shardKeys) {
+			values[key] = v
+		}
+	}
+	return values
+}
+
+// LoadAll is similar to (*Cache).LoadAll, retrieving keys of each shard concurrently just like GetMulti.
+func (s *ShardedCache[K, V]) LoadAll(ctx context.Context, keys []K) error {
+	return loadAll(ctx, keys, s.GetMulti)
+}
+
+// ForgetAll is similar to (*Cache).ForgetAll, forgetting keys of each shard at once.
+// Note that keys of different shards may be forgotten at different times.
+func (s *ShardedCache[K, V]) ForgetAll(keys []K) {
+	for i, shardKeys := range s.split(keys) {
+		if len(shardKeys) > 0 {
+			s.shards[i].ForgetAll(shardKeys)
+		}
+	}
+}
+
+// split splits keys by the shards they belong to.
+func (s *ShardedCache[K, V]) split(keys []K) [][]K {
+	keysByShard := make([][]K, len(s.shards))
+	for _, key := range keys {
+		i := s.hasher(key) % uint64(len(s.shards))
+		keysByShard[i] = append(keysByShard[i], key)
+	}
+	return keysByShard
+}
+
+// ForgetIf is the same as (*Cache).ForgetIf.
+func (s *ShardedCache[K, V]) ForgetIf(predicate func(key K) bool) {
+	for _, c := range s.shards {
+		c.ForgetIf(predicate)
+	}
+}
+
+// Purge is the same as (*Cache).Purge.
+func (s *ShardedCache[K, V]) Purge() {
+	for _, c := range s.shards {
+		c.Purge()
+	}
+}
+
+// DeleteExpired is the same as (*Cache).DeleteExpired.
+func (s *ShardedCache[K, V]) DeleteExpired() int {
+	var removed int
+	for _, c := range s.shards {
+		removed += c.DeleteExpired()
+	}
+	return removed
+}
+
+// ForgetExpired is the same as (*Cache).ForgetExpired.
+func (s *ShardedCache[K, V]) ForgetExpired() int {
+	var removed int
+	for _, c := range s.shards {
+		removed += c.ForgetExpired()
+	}
+	return removed
+}
+
+// Keys is the same as (*Cache).Keys.
+func (s *ShardedCache[K, V]) Keys() []K {
+	var keys []K
+	for _, c := range s.shards {
+		keys = append(keys, c.Keys()...)
+	}
+	return keys
+}
+
+// Range is the same as (*Cache).Range. Shards are iterated one by one.
+func (s *ShardedCache[K, V]) Range(f func(key K, value V) bool) {
+	for _, c := range s.shards {
+		cont := true
+		c.Range(func(key K, value V) bool {
+			cont = f(key, value)
+			return cont
+		})
+		if !cont {
+			return
+		}
+	}
+}
+
+// Len is the same as (*Cache).Len.
+func (s *ShardedCache[K, V]) Len() int {
+	var n int
+	for _, c := range s.shards {
+		n += c.Len()
+	}
+	return n
+}
+
+// Stats returns cache metrics aggregated over all shards.
+// Capacity is -1 for map backend, just like (*Cache).Stats.
+func (s *ShardedCache[K, V]) Stats() Stats {
+	var stats Stats
+	var counts []uint64
+	for _, c := range s.shards {
+		st := c.Stats()
+		stats.Hits += st.Hits
+		stats.GraceHits += st.GraceHits
+		stats.Misses += st.Misses
+		stats.Replacements += st.Replacements
+		stats.ErrorHits += st.ErrorHits
+		stats.Evictions += st.Evictions
+		stats.ReplacementDuration += st.ReplacementDuration
+		stats.MaxReplacementDuration = max(stats.MaxReplacementDuration, st.MaxReplacementDuration)
+		if h := c.timers.histogram; h != nil {
+			// Percentiles cannot be merged - merge the histograms instead
+			if counts == nil {
+				counts = make([]uint64, histogramBuckets)
+			}
+			for i, n := range h.load() {
+				counts[i] += n
+			}
+		}
+		stats.HitStatsDisabled = st.HitStatsDisabled
+		stats.Size += st.Size
+		stats.Weight += st.Weight
+		if st.Capacity < 0 {
+			stats.Capacity = -1
+		} else {
+			stats.Capacity += st.Capacity
+		}
+	}
+	if counts != nil {
+		stats.setPercentiles(counts)
+	}
+	return stats
+}
+
+// InFlight is the same as (*Cache).InFlight.
+func (s *ShardedCache[K, V]) InFlight() int {
+	var n int
+	for _, c := range s.shards {
+		n += c.InFlight()
+	}
+	return n
+}
+
+// String is similar to (*Cache).String, summing up the size, capacity, and in-flight count of the shards.
+func (s *ShardedCache[K, V]) String() string {
+	stats := s.Stats()
+	return fmt.Sprintf("ShardedCache(Backend: %s, Shards: %d, Size: %d, Capacity: %d, InFlight: %d)",
+		s.BackendType(), len(s.shards), stats.Size, stats.Capacity, s.InFlight())
+}
+
+// Resize is similar to (*Cache).Resize, dividing newCapacity among shards just like NewSharded.
+func (s *ShardedCache[K, V]) Resize(newCapacity int) error {
+	n := len(s.shards)
+	for _, c := range s.shards {
+		if err := c.Resize((newCapacity + n - 1) / n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackendType is the same as (*Cache).BackendType. All shards use the same backend.
+func (s *ShardedCache[K, V]) BackendType() string {
+	return s.shards[0].BackendType()
+}
+
+// Close is the same as (*Cache).Close, closing all shards.
+func (s *ShardedCache[K, V]) Close() {
+	for _, c := range s.shards {
+		c.Close()
+	}
+}
@@ -0,0 +1,167 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSharded(t *testing.T) {
+	t.Parallel()
+
+	fn := func(ctx context.Context, s string) (string, error) { return "", nil }
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewSharded[string, string](fn, 0, 0)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, c.shards)
+		assert.Equal(t, -1, c.Stats().Capacity)
+	})
+
+	t.Run("invalid shards", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewSharded[string, string](fn, 0, 0, WithShards(0))
+		assert.Error(t, err)
+	})
+
+	t.Run("shards with New", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := New[string, string](fn, 0, 0, WithShards(4))
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid hasher type", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewSharded[string, string](fn, 0, 0, WithHasher(func(key int) uint64 { return uint64(key) }))
+		assert.Error(t, err)
+	})
+
+	t.Run("hasher required", func(t *testing.T) {
+		t.Parallel()
+
+		type key struct{ a, b int }
+		fn := func(ctx context.Context, k key) (string, error) { return "", nil }
+		_, err := NewSharded[key, string](fn, 0, 0)
+		assert.Error(t, err)
+		_, err = NewSharded[key, string](fn, 0, 0, WithHasher(func(k key) uint64 { return uint64(k.a ^ k.b) }))
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid option", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewSharded[string, string](fn, 0, 0, WithLRUBackend(0))
+		assert.Error(t, err)
+	})
+
+	t.Run("capacity is divided", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := NewSharded[string, string](fn, 0, 0, WithShards(4), WithLRUBackend(10))
+		assert.NoError(t, err)
+		assert.Len(t, c.shards, 4)
+		assert.Equal(t, 3, c.shards[0].Stats().Capacity)
+		assert.Equal(t, 12, c.Stats().Capacity)
+
+		assert.NoError(t, c.Resize(20))
+		assert.Equal(t, 20, c.Stats().Capacity)
+		assert.Error(t, c.Resize(0))
+	})
+}
+
+func Test_defaultHasher(t *testing.T) {
+	t.Parallel()
+
+	type id string
+	h, ok := defaultHasher[id]()
+	assert.True(t, ok)
+	assert.Equal(t, h("a"), h("a"))
+	assert.NotEqual(t, h("a"), h("b"))
+
+	hi, ok := defaultHasher[int]()
+	assert.True(t, ok)
+	assert.NotEqual(t, hi(1), hi(2))
+
+	_, ok = defaultHasher[struct{}]()
+	assert.False(t, ok)
+}
+
+func TestShardedCache(t *testing.T) {
+	t.Parallel()
+
+	var cnt int64
+	replaceFn := func(ctx context.Context, key int) (string, error) {
+		atomic.AddInt64(&cnt, 1)
+		if key < 0 {
+			return "", errors.New("negative key")
+		}
+		return "value-" + strconv.Itoa(key), nil
+	}
+	cache, err := NewSharded[int, string](replaceFn, time.Minute, time.Minute, WithShards(4))
+	assert.NoError(t, err)
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		v, err := cache.Get(context.Background(), i)
+		assert.NoError(t, err)
+		assert.Equal(t, "value-"+strconv.Itoa(i), v)
+	}
+	// keys are distributed among shards
+	for _, c := range cache.shards {
+		assert.NotZero(t, c.Len())
+	}
+	assert.Equal(t, 100, cache.Len())
+	assert.Len(t, cache.Keys(), 100)
+	var ranged int
+	cache.Range(func(key int, value string) bool {
+		ranged++
+		return ranged < 50
+	})
+	assert.Equal(t, 50, ranged)
+	assert.EqualValues(t, 100, cnt)
+
+	v, err := cache.Get(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "value-10", v)
+	assert.EqualValues(t, 100, cnt)
+	assert.Equal(t, HitStats{Hits: 1, Misses: 100, Replacements: 100}, cache.Stats().HitStats)
+	assert.Equal(t, 100, cache.Stats().Size)
+
+	cache.Set(10, "new-value")
+	v, ok := cache.GetIfExists(10)
+	assert.True(t, ok)
+	assert.Equal(t, "new-value", v)
+	cache.Forget(10)
+	_, ok = cache.Peek(10)
+	assert.False(t, ok)
+	cache.Set(10, "new-value")
+	assert.False(t, cache.ForgetIfOlderThan(10, time.Now().Add(-time.Minute)))
+	assert.True(t, cache.ForgetIfOlderThan(10, time.Now().Add(time.Minute)))
+	_, ok = cache.Peek(10)
+	assert.False(t, ok)
+
+	values, err := cache.GetMulti(context.Background(), []int{1, 2, 200, -1})
+	assert.EqualError(t, err, "negative key")
+	assert.Equal(t, map[int]string{1: "value-1", 2: "value-2", 200: "value-200"}, values)
+
+	assert.Equal(t, map[int]string{1: "value-1", 2: "value-2"}, cache.GetMultiIfExists([]int{1, 2, 10}))
+
+	cache.ForgetAll([]int{0, 1, 2, 3})
+	assert.Equal(t, 96, cache.Len())
+	cache.ForgetIf(func(key int) bool { return key%2 == 0 })
+	assert.Equal(t, 48, cache.Len())
+	cache.NotifyAll(context.Background(), []int{0, 1, 2, 3})
+	assert.Eventually(t, func() bool { return cache.Len() == 52 }, time.Second, time.Millisecond)
+	cache.Purge()
+	assert.Equal(t, 0, cache.Len())
+}
@@ -0,0 +1,5 @@
+// Package singleflight provides a generic duplicate function call suppression mechanism.
+//
+// This is the request coalescing of github.com/motoki317/sc without caching:
+// results are shared only among the callers which call Do while the call is in-flight.
+package singleflight
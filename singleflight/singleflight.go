@@ -0,0 +1,92 @@
+package singleflight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/motoki317/sc/internal/flight"
+)
+
+// call is an in-flight or completed Do call.
+type call[V any] struct {
+	*flight.Call
+
+	// These fields are written once before the call completes
+	// and are only read after the call completes.
+	val V
+	err error
+}
+
+// Group represents a class of work and forms a namespace in which units of work can be executed with duplicate
+// suppression. The zero value is not usable, use NewGroup instead.
+// All methods are safe to be called from multiple goroutines.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+// NewGroup creates a new Group.
+func NewGroup[K comparable, V any]() *Group[K, V] {
+	return &Group[K, V]{
+		calls: make(map[K]*call[V]),
+	}
+}
+
+// Do calls fn for key, making sure that only one call for key is in-flight at a time.
+// If a call for key is already in-flight, Do waits for it and returns the same result instead of calling fn again.
+//
+// fn is called in the goroutine of the caller which started the call, with ctx of that caller detached from its
+// cancellation, so that the cancellation of one caller does not fail the others. Its values are still inherited.
+// The caller which started the call always waits for fn to return. Other callers stop waiting when their ctx is done,
+// returning ctx.Err().
+//
+// If fn panics, the waiting callers receive an error, and the panic is propagated to the caller which started the call.
+// Likewise, if fn calls runtime.Goexit, the waiting callers receive an error, and the goroutine of the caller which
+// started the call continues to exit.
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.Done():
+			return c.val, c.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+	c := &call[V]{Call: flight.NewCall()}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.run(ctx, key, c, fn)
+	return c.val, c.err
+}
+
+// run calls fn and completes c, even if fn panics or calls runtime.Goexit.
+func (g *Group[K, V]) run(ctx context.Context, key K, c *call[V], fn func(ctx context.Context) (V, error)) {
+	c.Run(func() {
+		c.val, c.err = fn(context.WithoutCancel(ctx))
+	}, func(aborted error) {
+		if aborted != nil {
+			c.err = fmt.Errorf("singleflight: %w", aborted)
+		}
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key) // there may be a new call if the key was forgotten
+		}
+		g.mu.Unlock()
+	})
+}
+
+// Forget forgets about the in-flight call for key, if any.
+// Subsequent Do calls for key call fn again instead of waiting for the forgotten call,
+// although callers already waiting for the forgotten call still receive its result.
+//
+// This is useful when the result of the in-flight call is known to be outdated, e.g. after an update of the data.
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
@@ -0,0 +1,179 @@
+package singleflight_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/motoki317/sc/singleflight"
+)
+
+func TestGroup_Do(t *testing.T) {
+	t.Parallel()
+
+	g := singleflight.NewGroup[string, string]()
+	var cnt int64
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt64(&cnt, 1)
+		time.Sleep(100 * time.Millisecond)
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Do(context.Background(), "k1", fn)
+			require.NoError(t, err)
+			require.Equal(t, "value", v)
+		}()
+	}
+	wg.Wait()
+	require.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+
+	// results are not cached
+	_, err := g.Do(context.Background(), "k1", fn)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+}
+
+func TestGroup_Do_Error(t *testing.T) {
+	t.Parallel()
+
+	g := singleflight.NewGroup[string, string]()
+	targetErr := errors.New("test error")
+	_, err := g.Do(context.Background(), "k1", func(ctx context.Context) (string, error) {
+		return "", targetErr
+	})
+	require.ErrorIs(t, err, targetErr)
+}
+
+func TestGroup_Do_Cancel(t *testing.T) {
+	t.Parallel()
+
+	g := singleflight.NewGroup[string, string]()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_, _ = g.Do(context.Background(), "k1", func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			return "value", nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := g.Do(ctx, "k1", func(ctx context.Context) (string, error) {
+		t.Error("fn should not be called")
+		return "", nil
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	close(release)
+}
+
+func TestGroup_Forget(t *testing.T) {
+	t.Parallel()
+
+	g := singleflight.NewGroup[string, int]()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := g.Do(context.Background(), "k1", func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, v)
+	}()
+	<-started
+
+	g.Forget("k1")
+	v, err := g.Do(context.Background(), "k1", func(ctx context.Context) (int, error) {
+		return 2, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+	close(release)
+	<-done
+}
+
+func TestGroup_Do_Panic(t *testing.T) {
+	t.Parallel()
+
+	g := singleflight.NewGroup[string, string]()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer func() {
+			require.Equal(t, "test panic", recover())
+		}()
+		_, _ = g.Do(context.Background(), "k1", func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			panic("test panic")
+		})
+	}()
+	<-started
+
+	errc := make(chan error)
+	go func() {
+		_, err := g.Do(context.Background(), "k1", func(ctx context.Context) (string, error) {
+			return "", nil
+		})
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	require.ErrorContains(t, <-errc, "test panic")
+}
+
+func TestGroup_Do_Goexit(t *testing.T) {
+	t.Parallel()
+
+	g := singleflight.NewGroup[string, string]()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		_, _ = g.Do(context.Background(), "k1", func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			runtime.Goexit()
+			return "", nil
+		})
+		t.Error("runtime.Goexit should not be stopped")
+	}()
+	<-started
+
+	errc := make(chan error)
+	go func() {
+		_, err := g.Do(context.Background(), "k1", func(ctx context.Context) (string, error) {
+			return "", nil
+		})
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	require.ErrorContains(t, <-errc, "runtime.Goexit")
+	<-exited
+
+	// the key is not stuck with the exited call
+	v, err := g.Do(context.Background(), "k1", func(ctx context.Context) (string, error) {
+		return "value", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "value", v)
+}
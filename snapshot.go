@@ -0,0 +1,46 @@
+package sc
+
+// Snapshot returns a copy of all items currently stored in the cache. Expired items are excluded.
+//
+// Together with Load, this is useful to warm up the cache after a restart: persist the snapshot before shutting down,
+// and load it on start-up to avoid calling replaceFn for all keys at once.
+// Note that the values are copied shallowly - values referencing shared data (e.g. pointers) are shared with the cache.
+func (c *cache[K, V]) Snapshot() map[K]V {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := c.now()
+	entries := make(map[K]V, c.values.Size())
+	c.values.Range(func(key K, value value[V]) bool {
+		if !value.isExpired(now, value.ttl) {
+			entries[key] = value.v
+		}
+		return true
+	})
+	return entries
+}
+
+// Load stores the given items in the cache as fresh values, as if they were just retrieved by replaceFn.
+// This is useful to warm up the cache with the items returned by Snapshot - see Snapshot.
+//
+// Unlike Set, Load never overwrites the items already stored in the cache (unless expired),
+// nor the items being retrieved, since the given items are likely to be older than those.
+// For bounded backends, items exceeding the capacity evict other items as usual,
+// so loading more items than the capacity does not grow the cache beyond the capacity.
+func (c *cache[K, V]) Load(entries map[K]V) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.unlock()
+	for key, v := range entries {
+		key = c.normalizeKey(key)
+		if _, ok := c.calls[key]; ok {
+			continue
+		}
+		if val, ok := c.values.Peek(key); ok && !val.isExpired(now, val.ttl) {
+			continue
+		}
+		val := value[V]{v: v, created: now}
+		val.freshFor, val.ttl = c.durations(key)
+		c.applyTTLFn(key, &val)
+		c.store(key, val)
+	}
+}
@@ -0,0 +1,78 @@
+package sc
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, 50*time.Millisecond, 100*time.Millisecond, append(c.cacheOpts, WithCleanupInterval(0))...)
+			assert.NoError(t, err)
+
+			_, _ = cache.Get(context.Background(), "k1")
+			time.Sleep(150 * time.Millisecond)
+			_, _ = cache.Get(context.Background(), "k2")
+			_, _ = cache.Get(context.Background(), "k3")
+			// expired items are excluded
+			snapshot := cache.Snapshot()
+			assert.Equal(t, map[string]string{"k2": "value-k2", "k3": "value-k3"}, snapshot)
+
+			// load into a new cache
+			restarted, err := New[string, string](replaceFn, 50*time.Millisecond, 100*time.Millisecond, c.cacheOpts...)
+			assert.NoError(t, err)
+			restarted.Set("k3", "newer-k3")
+			restarted.Load(snapshot)
+
+			v, err := restarted.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k2", v)
+			// existing items are not overwritten
+			v, err = restarted.Get(context.Background(), "k3")
+			assert.NoError(t, err)
+			assert.Equal(t, "newer-k3", v)
+			assert.EqualValues(t, 3, cnt)
+			assert.Equal(t, HitStats{2, 0, 0, 0, 0, 0}, restarted.Stats().HitStats)
+		})
+	}
+}
+
+func TestCache_Load_Capacity(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range evictingCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			entries := make(map[string]string)
+			for i := 0; i < 20; i++ {
+				entries["k"+strconv.Itoa(i)] = "value"
+			}
+			cache.Load(entries)
+			assert.Equal(t, 10, cache.Len())
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package sc
+
+import (
+	"slices"
+)
+
+// RangeSorted calls f sequentially for each item currently stored in the cache, in the order of keys sorted by cmp.
+// Expired items are skipped. If f returns false, RangeSorted stops the iteration.
+//
+// cmp should return a negative number when a < b, a positive number when a > b, and zero when a == b,
+// as in slices.SortFunc. For ordered keys, cmp.Compare can be used.
+//
+// RangeSorted takes a snapshot of the items (and sorts them) while holding the lock,
+// and calls f after releasing the lock. f may therefore call other methods of the cache.
+// Use this instead of Keys when reproducible iteration order is needed, at the cost of sorting.
+func (c *cache[K, V]) RangeSorted(cmp func(a, b K) int, f func(key K, value V) bool) {
+	type item struct {
+		key K
+		v   V
+	}
+
+	c.mu.Lock()
+	now := c.now() // Record time after acquiring the lock to exclude as many expired items as possible
+	items := make([]item, 0, c.values.Size())
+	c.values.Range(func(key K, value value[V]) bool {
+		if !value.isExpired(now, value.ttl) {
+			items = append(items, item{key, value.v})
+		}
+		return true
+	})
+	c.mu.Unlock()
+
+	slices.SortFunc(items, func(a, b item) int { return cmp(a.key, b.key) })
+	for _, it := range items {
+		if !f(it.key, it.v) {
+			return
+		}
+	}
+}
+
+// ForgetIfSorted is similar to ForgetIf, but calls the predicate in the order of keys sorted by cmp.
+// The predicate is called exactly once for each key either stored in the cache or being retrieved.
+//
+// This is useful when the predicate has side effects, or when reproducible behavior is needed (e.g. in tests).
+// See RangeSorted for cmp.
+func (c *cache[K, V]) ForgetIfSorted(cmp func(a, b K) int, predicate func(key K) bool) {
+	predicate, publish := c.invalidations(predicate)
+	defer publish()
+	c.mu.Lock()
+	defer c.unlock()
+
+	keys := make([]K, 0, c.values.Size()+len(c.calls))
+	for key := range c.calls {
+		keys = append(keys, key)
+	}
+	for key := range c.negatives {
+		if _, ok := c.calls[key]; !ok { // Avoid calling the predicate twice for the same key
+			keys = append(keys, key)
+		}
+	}
+	c.values.Range(func(key K, _ value[V]) bool {
+		if _, ok := c.calls[key]; ok {
+			return true
+		}
+		if _, ok := c.negatives[key]; ok {
+			return true
+		}
+		keys = append(keys, key)
+		return true
+	})
+
+	slices.SortFunc(keys, cmp)
+	for _, key := range keys {
+		if predicate(key) {
+			delete(c.calls, key)
+			delete(c.negatives, key)
+			c.delete(key)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package sc
+
+import (
+	"cmp"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_RangeSorted(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			for _, key := range []string{"k3", "k1", "k4", "k2"} {
+				_, _ = cache.Get(context.Background(), key)
+			}
+
+			var keys, values []string
+			cache.RangeSorted(cmp.Compare[string], func(key string, value string) bool {
+				keys = append(keys, key)
+				values = append(values, value)
+				return true
+			})
+			assert.Equal(t, []string{"k1", "k2", "k3", "k4"}, keys)
+			assert.Equal(t, []string{"value-k1", "value-k2", "value-k3", "value-k4"}, values)
+
+			// stop iteration
+			keys = nil
+			cache.RangeSorted(cmp.Compare[string], func(key string, _ string) bool {
+				keys = append(keys, key)
+				return key != "k2"
+			})
+			assert.Equal(t, []string{"k1", "k2"}, keys)
+
+			// f may call methods of the cache
+			cache.RangeSorted(cmp.Compare[string], func(key string, _ string) bool {
+				cache.Forget(key)
+				return true
+			})
+			assert.Equal(t, 0, cache.Len())
+		})
+	}
+}
+
+func TestCache_ForgetIfSorted(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				if key == "k5" {
+					time.Sleep(250 * time.Millisecond)
+				}
+				return "value-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+
+			for _, key := range []string{"k3", "k1", "k4", "k2"} {
+				_, _ = cache.Get(context.Background(), key)
+			}
+			cache.Notify(context.Background(), "k5") // in-flight
+			time.Sleep(50 * time.Millisecond)
+
+			var called []string
+			cache.ForgetIfSorted(cmp.Compare[string], func(key string) bool {
+				called = append(called, key)
+				return key == "k2" || key == "k5"
+			})
+			assert.Equal(t, []string{"k1", "k2", "k3", "k4", "k5"}, called)
+			assert.ElementsMatch(t, []string{"k1", "k3", "k4"}, cache.Keys())
+
+			// the result of the forgotten call is not stored
+			time.Sleep(300 * time.Millisecond)
+			assert.ElementsMatch(t, []string{"k1", "k3", "k4"}, cache.Keys())
+		})
+	}
+}
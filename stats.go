@@ -2,6 +2,11 @@ package sc
 
 import (
 	"fmt"
+	"log/slog"
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
 )
 
 type HitStats struct {
@@ -14,6 +19,78 @@ type HitStats struct {
 	// Replacements is the number of times replaceFn is called.
 	// Note that this field is incremented after replaceFn finishes to reduce lock time.
 	Replacements uint64
+	// ErrorHits is the number of cached errors returned in (*Cache).Get. See WithNegativeCache.
+	// These are counted neither as hits nor misses.
+	ErrorHits uint64
+	// Evictions is the number of items evicted by the backend to make room for new items.
+	// Always 0 for map backend, which has no capacity. A steadily increasing count suggests the capacity is too small.
+	Evictions uint64
+}
+
+// Status represents how an item was served by (*Cache).GetWithStatus.
+// Each status corresponds to a counter of HitStats.
+type Status int
+
+const (
+	// StatusHit means a fresh item was served from the cache.
+	StatusHit Status = iota
+	// StatusGraceHit means a stale item was served from the cache, while a new item is retrieved in the background.
+	StatusGraceHit
+	// StatusMiss means the caller waited for replaceFn to retrieve the item, either by calling it or by joining
+	// an ongoing call of another caller. Also returned when the call failed without waiting, e.g. with ErrBusy.
+	StatusMiss
+	// StatusErrorHit means a cached error was returned. See WithNegativeCache.
+	StatusErrorHit
+)
+
+// String returns the name of the status.
+func (s Status) String() string {
+	switch s {
+	case StatusHit:
+		return "Hit"
+	case StatusGraceHit:
+		return "GraceHit"
+	case StatusMiss:
+		return "Miss"
+	case StatusErrorHit:
+		return "ErrorHit"
+	default:
+		return "Unknown"
+	}
+}
+
+// EntryState represents the state of an item returned by (*Cache).GetIfExistsDetailed.
+type EntryState int
+
+const (
+	// EntryMissing means the item does not exist in the cache, or is expired.
+	EntryMissing EntryState = iota
+	// EntryFresh means the item is fresh.
+	EntryFresh
+	// EntryStale means the item is stale, i.e. older than freshFor but younger than ttl.
+	EntryStale
+)
+
+// String returns the name of the state.
+func (s EntryState) String() string {
+	switch s {
+	case EntryMissing:
+		return "Missing"
+	case EntryFresh:
+		return "Fresh"
+	case EntryStale:
+		return "Stale"
+	default:
+		return "Unknown"
+	}
+}
+
+// hitStatus returns s, or StatusMiss if the caller has waited for an ongoing call before being served the item.
+func hitStatus(s Status, waited bool) Status {
+	if waited {
+		return StatusMiss
+	}
+	return s
 }
 
 type SizeStats struct {
@@ -22,24 +99,81 @@ type SizeStats struct {
 	// Capacity is the maximum number of allowed items in the cache.
 	//
 	// Note that, for map backend, there is no upper bound in number of items in the cache.
-	// Therefore, Capacity is always -1 for map backend.
+	// Therefore, Capacity is always -1 for map backend. The same applies to weighted backend (see WithMaxWeight).
 	Capacity int
+	// Weight is the current total weight of the items in the cache, for weighted backend (see WithMaxWeight).
+	// Always 0 for other backends.
+	Weight int64
+}
+
+type ReplacementStats struct {
+	// ReplacementDuration is the total time spent in replaceFn calls.
+	// Divide by Replacements to get the mean time, see also (Stats).MeanReplacementDuration.
+	//
+	// For batch replacements (see NewBatched), the time of each batchFn call is counted once.
+	ReplacementDuration time.Duration
+	// MaxReplacementDuration is the longest time spent in a single replaceFn call.
+	MaxReplacementDuration time.Duration
+	// P50ReplacementDuration, P90ReplacementDuration, and P99ReplacementDuration are the percentiles of the time
+	// spent in a single replaceFn call, with a relative error of at most 12.5%.
+	// Always 0 unless WithReplacementPercentiles is specified.
+	P50ReplacementDuration, P90ReplacementDuration, P99ReplacementDuration time.Duration
 }
 
 // Stats represents cache metrics.
 type Stats struct {
 	HitStats
 	SizeStats
+	ReplacementStats
+	// HitStatsDisabled is true if HitStats are not tracked, and therefore always zero. See WithStatsDisabled.
+	HitStatsDisabled bool
 }
 
 // String returns formatted string.
+// The percentiles of replaceFn durations are included only if any replaceFn call has been recorded with
+// WithReplacementPercentiles.
 func (s Stats) String() string {
-	return fmt.Sprintf(
-		"Hits: %d, GraceHits: %d, Misses: %d, Replacements: %d, Hit Ratio: %f, Size: %d, Capacity: %d",
+	str := fmt.Sprintf(
+		"Hits: %d, GraceHits: %d, Misses: %d, Replacements: %d, Hit Ratio: %f, Size: %d, Capacity: %d, Weight: %d, "+
+			"Evictions: %d, Mean Replacement Duration: %v, Max Replacement Duration: %v",
 		s.Hits, s.GraceHits, s.Misses, s.Replacements,
 		s.HitRatio(),
-		s.Size, s.Capacity,
+		s.Size, s.Capacity, s.Weight, s.Evictions,
+		s.MeanReplacementDuration(), s.MaxReplacementDuration,
 	)
+	if s.P99ReplacementDuration > 0 {
+		str += fmt.Sprintf(", P50 Replacement Duration: %v, P90 Replacement Duration: %v, P99 Replacement Duration: %v",
+			s.P50ReplacementDuration, s.P90ReplacementDuration, s.P99ReplacementDuration)
+	}
+	return str
+}
+
+// LogValue implements slog.LogValuer, so that the stats are logged as a group of structured attributes,
+// e.g. with slog.Info("cache", "stats", cache.Stats()).
+// The percentiles of replaceFn durations are included only if they are included in String.
+func (s Stats) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.Uint64("hits", s.Hits),
+		slog.Uint64("grace_hits", s.GraceHits),
+		slog.Uint64("misses", s.Misses),
+		slog.Uint64("replacements", s.Replacements),
+		slog.Uint64("error_hits", s.ErrorHits),
+		slog.Uint64("evictions", s.Evictions),
+		slog.Float64("hit_ratio", s.HitRatio()),
+		slog.Int("size", s.Size),
+		slog.Int("capacity", s.Capacity),
+		slog.Int64("weight", s.Weight),
+		slog.Duration("mean_replacement_duration", s.MeanReplacementDuration()),
+		slog.Duration("max_replacement_duration", s.MaxReplacementDuration),
+	}
+	if s.P99ReplacementDuration > 0 {
+		attrs = append(attrs,
+			slog.Duration("p50_replacement_duration", s.P50ReplacementDuration),
+			slog.Duration("p90_replacement_duration", s.P90ReplacementDuration),
+			slog.Duration("p99_replacement_duration", s.P99ReplacementDuration),
+		)
+	}
+	return slog.GroupValue(attrs...)
 }
 
 // HitRatio returns the hit ratio.
@@ -51,16 +185,252 @@ func (s Stats) HitRatio() float64 {
 	return float64(s.Hits+s.GraceHits) / float64(total)
 }
 
+// MeanReplacementDuration returns the mean time spent in a single replaceFn call.
+func (s Stats) MeanReplacementDuration() time.Duration {
+	if s.Replacements == 0 {
+		return 0
+	}
+	return s.ReplacementDuration / time.Duration(s.Replacements)
+}
+
+// hitCounters holds the counters of HitStats.
+// The counters are atomic so that they can be incremented under the read lock, and read without the lock.
+type hitCounters struct {
+	hits, graceHits, misses, replacements, errorHits, evictions counter
+}
+
+// counter is an atomic counter, which ignores increments if disabled. See WithStatsDisabled.
+type counter struct {
+	atomic.Uint64
+	// disabled is written only before the cache is used.
+	disabled bool
+}
+
+// Add adds delta to the counter, unless the counter is disabled.
+func (c *counter) Add(delta uint64) {
+	if !c.disabled {
+		c.Uint64.Add(delta)
+	}
+}
+
+// disable disables all the counters.
+func (h *hitCounters) disable() {
+	for _, c := range []*counter{&h.hits, &h.graceHits, &h.misses, &h.replacements, &h.errorHits, &h.evictions} {
+		c.disabled = true
+	}
+}
+
+// disabled reports whether the counters are disabled.
+func (h *hitCounters) disabled() bool {
+	return h.hits.disabled
+}
+
+// load returns a snapshot of the counters.
+func (h *hitCounters) load() HitStats {
+	return HitStats{
+		Hits:         h.hits.Load(),
+		GraceHits:    h.graceHits.Load(),
+		Misses:       h.misses.Load(),
+		Replacements: h.replacements.Load(),
+		ErrorHits:    h.errorHits.Load(),
+		Evictions:    h.evictions.Load(),
+	}
+}
+
+// replacementTimers holds the timers of ReplacementStats.
+type replacementTimers struct {
+	total, max atomic.Int64
+	// histogram is nil unless WithReplacementPercentiles is specified.
+	histogram *durationHistogram
+}
+
+// record records a single replaceFn call which took d.
+func (r *replacementTimers) record(d time.Duration) {
+	r.total.Add(int64(d))
+	if r.histogram != nil {
+		r.histogram.record(d)
+	}
+	for {
+		m := r.max.Load()
+		if int64(d) <= m || r.max.CompareAndSwap(m, int64(d)) {
+			return
+		}
+	}
+}
+
+// load returns a snapshot of the timers.
+func (r *replacementTimers) load() ReplacementStats {
+	stats := ReplacementStats{
+		ReplacementDuration:    time.Duration(r.total.Load()),
+		MaxReplacementDuration: time.Duration(r.max.Load()),
+	}
+	if r.histogram != nil {
+		stats.setPercentiles(r.histogram.load())
+	}
+	return stats
+}
+
+// setPercentiles sets the percentiles of ReplacementStats from the bucket counts of durationHistogram.
+func (s *ReplacementStats) setPercentiles(counts []uint64) {
+	s.P50ReplacementDuration = percentile(counts, 0.5, s.MaxReplacementDuration)
+	s.P90ReplacementDuration = percentile(counts, 0.9, s.MaxReplacementDuration)
+	s.P99ReplacementDuration = percentile(counts, 0.99, s.MaxReplacementDuration)
+}
+
+// histogramSubBits is the number of bits of a duration, following the most significant bit,
+// to tell apart the durations in a histogram. The relative error of a bucket is at most 1/2^histogramSubBits.
+const histogramSubBits = 3
+
+// histogramBuckets is the number of buckets needed to hold any non-negative duration:
+// 1<<histogramSubBits buckets for each most significant bit from histogramSubBits to 62, plus those for small durations.
+const histogramBuckets = (64 - histogramSubBits) << histogramSubBits
+
+// durationHistogram is a log-linear histogram of durations, similar to HDR histogram.
+// Durations are counted in buckets, each spanning 1/2^histogramSubBits of the powers of 2 they belong to.
+type durationHistogram struct {
+	counts [histogramBuckets]atomic.Uint64
+}
+
+// histogramBucket returns the index of the bucket d belongs to.
+func histogramBucket(d time.Duration) int {
+	if d < 1<<histogramSubBits {
+		return int(max(d, 0))
+	}
+	exp := bits.Len64(uint64(d)) - 1
+	sub := int(uint64(d)>>(exp-histogramSubBits)) & (1<<histogramSubBits - 1)
+	return (exp-histogramSubBits+1)<<histogramSubBits + sub
+}
+
+// histogramUpperBound returns the largest duration in the bucket of index i.
+func histogramUpperBound(i int) time.Duration {
+	if i < 1<<histogramSubBits {
+		return time.Duration(i)
+	}
+	exp := i>>histogramSubBits + histogramSubBits - 1
+	sub := i & (1<<histogramSubBits - 1)
+	lower := uint64(1<<histogramSubBits+sub) << (exp - histogramSubBits)
+	return time.Duration(lower + 1<<(exp-histogramSubBits) - 1)
+}
+
+// record counts d in its bucket.
+func (h *durationHistogram) record(d time.Duration) {
+	h.counts[histogramBucket(d)].Add(1)
+}
+
+// load returns the counts of the buckets.
+// Note that each count is read individually, so the counts may be slightly inconsistent with each other.
+func (h *durationHistogram) load() []uint64 {
+	counts := make([]uint64, histogramBuckets)
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+	}
+	return counts
+}
+
+// percentile returns the p-th quantile (0 < p <= 1) of the durations counted in the buckets,
+// or 0 if no durations are counted. The result is capped at maxDuration.
+func percentile(counts []uint64, p float64, maxDuration time.Duration) time.Duration {
+	var total uint64
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p * float64(total)))
+	var seen uint64
+	for i, n := range counts {
+		seen += n
+		if seen >= rank {
+			return min(histogramUpperBound(i), maxDuration)
+		}
+	}
+	return maxDuration
+}
+
+// HitStats returns the hit metrics of the cache.
+// Unlike Stats, HitStats never blocks operations of the cache, which is useful for frequent scraping of metrics.
+//
+// Note that each counter is read individually, so the counters may be slightly inconsistent with each other
+// while the cache is being accessed.
+func (c *cache[K, V]) HitStats() HitStats {
+	return c.stats.load()
+}
+
 // Stats returns cache metrics.
 // It is useful for monitoring performance and tuning your cache size/type.
 func (c *cache[K, V]) Stats() Stats {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.statsLocked()
+}
+
+// BackendType returns the name of the backend storing the items of the cache: "map", "lru", "fifo", or "2q".
+// For caches created with WithCapacity, this is the backend selected from the capacity and the eviction policy.
+//
+// This is useful to tell caches apart when comparing their Stats.
+func (c *cache[K, V]) BackendType() string {
+	return c.backend.String()
+}
+
+// statsLocked returns cache metrics. c.mu must be held (at least for reading) by the caller.
+func (c *cache[K, V]) statsLocked() Stats {
 	return Stats{
-		HitStats: c.stats,
+		HitStats: c.stats.load(),
 		SizeStats: SizeStats{
 			Size:     c.values.Size(),
 			Capacity: c.values.Capacity(),
+			Weight:   c.weight(),
 		},
+		ReplacementStats: c.timers.load(),
+		HitStatsDisabled: c.stats.disabled(),
+	}
+}
+
+// InFlight returns the number of distinct keys currently being retrieved, such as by replaceFn.
+// Unlike Stats.Replacements which only increases, this shows the current pressure on the data source
+// and how well concurrent requests are coalesced.
+//
+// Replacements detached from the cache by Forget or Set are not counted, even if they have not finished yet.
+func (c *cache[K, V]) InFlight() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.calls)
+}
+
+// String returns a short summary of the cache for debugging: the backend type, size, capacity,
+// and the number of keys being retrieved (see InFlight).
+// It implements fmt.Stringer, so that the summary is printed by e.g. fmt.Printf("%v", cache).
+func (c *cache[K, V]) String() string {
+	c.mu.RLock()
+	size, capacity, inFlight := c.values.Size(), c.values.Capacity(), len(c.calls)
+	c.mu.RUnlock()
+	return fmt.Sprintf("Cache(Backend: %s, Size: %d, Capacity: %d, InFlight: %d)", c.backend, size, capacity, inFlight)
+}
+
+// weight returns the total weight of the items, or 0 if the backend is not weighted.
+// c.mu must be held (at least for reading) by the caller.
+func (c *cache[K, V]) weight() int64 {
+	if w, ok := c.values.(weightedBackend); ok {
+		return w.Weight()
+	}
+	return 0
+}
+
+// StatsHistory returns the recorded snapshots of cache metrics, from the oldest to the newest.
+// Returns nil if the cache was not created with WithStatsHistory option.
+func (c *cache[K, V]) StatsHistory() []Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.history == nil {
+		return nil
 	}
+	return c.history.list()
+}
+
+// recordStats records a snapshot of cache metrics to the history.
+func (c *cache[K, V]) recordStats() {
+	c.mu.Lock()
+	c.history.add(c.statsLocked())
+	c.mu.Unlock()
 }
@@ -1,8 +1,13 @@
 package sc
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"log/slog"
+	"math"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,10 +23,25 @@ func TestStats_String(t *testing.T) {
 		{
 			name: "simple",
 			stats: Stats{
-				HitStats{1, 2, 3, 4},
-				SizeStats{5, 6},
+				HitStats{1, 2, 3, 4, 0, 7},
+				SizeStats{5, 6, 9},
+				ReplacementStats{8 * time.Second, 3 * time.Second, 0, 0, 0},
+				false,
 			},
-			want: "Hits: 1, GraceHits: 2, Misses: 3, Replacements: 4, Hit Ratio: 0.500000, Size: 5, Capacity: 6",
+			want: "Hits: 1, GraceHits: 2, Misses: 3, Replacements: 4, Hit Ratio: 0.500000, Size: 5, Capacity: 6, Weight: 9, " +
+				"Evictions: 7, Mean Replacement Duration: 2s, Max Replacement Duration: 3s",
+		},
+		{
+			name: "percentiles",
+			stats: Stats{
+				HitStats{1, 2, 3, 4, 0, 7},
+				SizeStats{5, 6, 9},
+				ReplacementStats{8 * time.Second, 3 * time.Second, time.Second, 2 * time.Second, 3 * time.Second},
+				false,
+			},
+			want: "Hits: 1, GraceHits: 2, Misses: 3, Replacements: 4, Hit Ratio: 0.500000, Size: 5, Capacity: 6, Weight: 9, " +
+				"Evictions: 7, Mean Replacement Duration: 2s, Max Replacement Duration: 3s, " +
+				"P50 Replacement Duration: 1s, P90 Replacement Duration: 2s, P99 Replacement Duration: 3s",
 		},
 	}
 	for _, tt := range tests {
@@ -31,6 +51,43 @@ func TestStats_String(t *testing.T) {
 	}
 }
 
+func TestStats_LogValue(t *testing.T) {
+	stats := Stats{
+		HitStats{1, 2, 3, 4, 0, 7},
+		SizeStats{5, 6, 9},
+		ReplacementStats{8 * time.Second, 3 * time.Second, 0, 0, 0},
+		false,
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Info("cache", "stats", stats)
+	assert.Equal(t, "level=INFO msg=cache stats.hits=1 stats.grace_hits=2 stats.misses=3 stats.replacements=4 "+
+		"stats.error_hits=0 stats.evictions=7 stats.hit_ratio=0.5 stats.size=5 stats.capacity=6 stats.weight=9 "+
+		"stats.mean_replacement_duration=2s stats.max_replacement_duration=3s\n", buf.String())
+}
+
+func TestStatus_String(t *testing.T) {
+	assert.Equal(t, "Hit", StatusHit.String())
+	assert.Equal(t, "GraceHit", StatusGraceHit.String())
+	assert.Equal(t, "Miss", StatusMiss.String())
+	assert.Equal(t, "ErrorHit", StatusErrorHit.String())
+	assert.Equal(t, "Unknown", Status(-1).String())
+}
+
+func TestEntryState_String(t *testing.T) {
+	assert.Equal(t, "Missing", EntryMissing.String())
+	assert.Equal(t, "Fresh", EntryFresh.String())
+	assert.Equal(t, "Stale", EntryStale.String())
+	assert.Equal(t, "Unknown", EntryState(-1).String())
+}
+
 func TestStats_HitRatio(t *testing.T) {
 	type fields struct {
 		Hits         uint64
@@ -92,17 +149,17 @@ func TestCache_HitStats(t *testing.T) {
 			v, err := cache.Get(context.Background(), "k1") // Miss -> Sync Replacement
 			assert.NoError(t, err)
 			assert.Equal(t, "result-k1", v)
-			assert.Equal(t, HitStats{0, 0, 1, 1}, cache.Stats().HitStats)
+			assert.Equal(t, HitStats{0, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
 
 			v, err = cache.Get(context.Background(), "k1") // Hit
 			assert.NoError(t, err)
 			assert.Equal(t, "result-k1", v)
-			assert.Equal(t, HitStats{1, 0, 1, 1}, cache.Stats().HitStats)
+			assert.Equal(t, HitStats{1, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
 
 			v, err = cache.Get(context.Background(), "k2") // Miss -> Sync Replacement
 			assert.NoError(t, err)
 			assert.Equal(t, "result-k2", v)
-			assert.Equal(t, HitStats{1, 0, 2, 2}, cache.Stats().HitStats)
+			assert.Equal(t, HitStats{1, 0, 2, 2, 0, 0}, cache.Stats().HitStats)
 
 			time.Sleep(300 * time.Millisecond)
 			v, err = cache.Get(context.Background(), "k1") // Grace Hit
@@ -111,13 +168,52 @@ func TestCache_HitStats(t *testing.T) {
 
 			// Sleep for some time - background fetch causes race condition on Replacements
 			time.Sleep(50 * time.Millisecond)
-			assert.Equal(t, HitStats{1, 1, 2, 3}, cache.Stats().HitStats)
+			assert.Equal(t, HitStats{1, 1, 2, 3, 0, 0}, cache.Stats().HitStats)
+			// HitStats does not need the lock
+			cache.mu.Lock()
+			assert.Equal(t, HitStats{1, 1, 2, 3, 0, 0}, cache.HitStats())
+			cache.mu.Unlock()
 			// assert t=350ms
 			assert.InDelta(t, 350*time.Millisecond, time.Since(t0), float64(100*time.Millisecond))
 		})
 	}
 }
 
+func TestCache_StatsDisabled(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Hour, time.Hour, append(c.cacheOpts, WithStatsDisabled())...)
+			assert.NoError(t, err)
+
+			for _, key := range []string{"k1", "k1", "k2"} {
+				v, err := cache.Get(context.Background(), key)
+				assert.NoError(t, err)
+				assert.Equal(t, "result-"+key, v)
+			}
+			stats := cache.Stats()
+			assert.Equal(t, HitStats{}, stats.HitStats)
+			assert.True(t, stats.HitStatsDisabled)
+			assert.Equal(t, 2, stats.Size)
+			assert.Equal(t, HitStats{}, cache.HitStats())
+
+			// Enabled by default
+			cache, err = New[string, string](replaceFn, time.Hour, time.Hour, c.cacheOpts...)
+			assert.NoError(t, err)
+			_, _ = cache.Get(context.Background(), "k1")
+			assert.False(t, cache.Stats().HitStatsDisabled)
+			assert.Equal(t, HitStats{0, 0, 1, 1, 0, 0}, cache.Stats().HitStats)
+		})
+	}
+}
+
 func TestCache_SizeStats(t *testing.T) {
 	t.Parallel()
 
@@ -156,19 +252,196 @@ func TestCache_SizeStats(t *testing.T) {
 			cache, err := New[string, string](replaceFn, 250*time.Millisecond, 500*time.Millisecond, c.cacheOpts...)
 			assert.NoError(t, err)
 
-			assert.Equal(t, SizeStats{0, 10}, cache.Stats().SizeStats)
+			assert.Equal(t, SizeStats{0, 10, 0}, cache.Stats().SizeStats)
 
 			for i := 0; i < 10; i++ {
 				_, err := cache.Get(context.Background(), "k1-"+strconv.Itoa(i))
 				assert.NoError(t, err)
-				assert.Equal(t, SizeStats{i + 1, 10}, cache.Stats().SizeStats)
+				assert.Equal(t, SizeStats{i + 1, 10, 0}, cache.Stats().SizeStats)
 			}
 
 			for i := 0; i < 10; i++ {
 				_, err := cache.Get(context.Background(), "k2-"+strconv.Itoa(i))
 				assert.NoError(t, err)
-				assert.Equal(t, SizeStats{10, 10}, cache.Stats().SizeStats)
+				assert.Equal(t, SizeStats{10, 10, 0}, cache.Stats().SizeStats)
 			}
+			assert.EqualValues(t, 10, cache.Stats().Evictions)
 		})
 	}
 }
+
+func TestCache_ReplacementStats(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				d, _ := strconv.Atoi(key)
+				time.Sleep(time.Duration(d) * time.Millisecond)
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+			assert.Equal(t, ReplacementStats{}, cache.Stats().ReplacementStats)
+			assert.Zero(t, cache.Stats().MeanReplacementDuration())
+
+			_, _ = cache.Get(context.Background(), "20")
+			_, _ = cache.Get(context.Background(), "100")
+			_, _ = cache.Get(context.Background(), "20") // Hit
+
+			stats := cache.Stats()
+			assert.EqualValues(t, 2, stats.Replacements)
+			assert.GreaterOrEqual(t, stats.ReplacementDuration, 120*time.Millisecond)
+			assert.Less(t, stats.ReplacementDuration, 200*time.Millisecond)
+			assert.GreaterOrEqual(t, stats.MaxReplacementDuration, 100*time.Millisecond)
+			assert.Less(t, stats.MaxReplacementDuration, stats.ReplacementDuration)
+			assert.Equal(t, stats.ReplacementDuration/2, stats.MeanReplacementDuration())
+		})
+	}
+}
+
+func TestCache_ReplacementPercentiles(t *testing.T) {
+	t.Parallel()
+
+	// keys are "<milliseconds to sleep>/<id>"
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		ms, _, _ := strings.Cut(key, "/")
+		d, _ := strconv.Atoi(ms)
+		time.Sleep(time.Duration(d) * time.Millisecond)
+		return "result-" + key, nil
+	}
+	assertPercentiles := func(t *testing.T, stats Stats) {
+		assert.EqualValues(t, 10, stats.Replacements)
+		assert.GreaterOrEqual(t, stats.P50ReplacementDuration, 10*time.Millisecond)
+		assert.Less(t, stats.P50ReplacementDuration, 20*time.Millisecond)
+		assert.GreaterOrEqual(t, stats.P90ReplacementDuration, stats.P50ReplacementDuration)
+		assert.Less(t, stats.P90ReplacementDuration, 20*time.Millisecond)
+		assert.GreaterOrEqual(t, stats.P99ReplacementDuration, 100*time.Millisecond)
+		assert.LessOrEqual(t, stats.P99ReplacementDuration, stats.MaxReplacementDuration)
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		t.Parallel()
+
+		cache, err := New[string, string](replaceFn, time.Minute, time.Minute, WithReplacementPercentiles())
+		assert.NoError(t, err)
+		assert.Equal(t, ReplacementStats{}, cache.Stats().ReplacementStats)
+
+		for i := 0; i < 9; i++ {
+			_, _ = cache.Get(context.Background(), "10/"+strconv.Itoa(i))
+		}
+		_, _ = cache.Get(context.Background(), "100/0")
+		assertPercentiles(t, cache.Stats())
+	})
+
+	t.Run("sharded cache", func(t *testing.T) {
+		t.Parallel()
+
+		cache, err := NewSharded[string, string](replaceFn, time.Minute, time.Minute, WithReplacementPercentiles())
+		assert.NoError(t, err)
+		for i := 0; i < 9; i++ {
+			_, _ = cache.Get(context.Background(), "10/"+strconv.Itoa(i))
+		}
+		_, _ = cache.Get(context.Background(), "100/0")
+		assertPercentiles(t, cache.Stats())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		cache, err := New[string, string](replaceFn, time.Minute, time.Minute)
+		assert.NoError(t, err)
+		_, _ = cache.Get(context.Background(), "10/0")
+		assert.Zero(t, cache.Stats().P99ReplacementDuration)
+	})
+}
+
+func Test_durationHistogram(t *testing.T) {
+	t.Parallel()
+
+	// buckets are contiguous, with a relative error of at most 1/2^histogramSubBits
+	for _, d := range []time.Duration{0, 1, 7, 8, 9, 15, 16, 17, 100, 1000, 12345, time.Second, time.Hour, math.MaxInt64} {
+		i := histogramBucket(d)
+		assert.Less(t, i, histogramBuckets)
+		assert.GreaterOrEqual(t, histogramUpperBound(i), d)
+		assert.LessOrEqual(t, float64(histogramUpperBound(i)-d), float64(d)/(1<<histogramSubBits))
+		if i > 0 {
+			assert.Less(t, histogramUpperBound(i-1), d)
+		}
+	}
+
+	var h durationHistogram
+	assert.Zero(t, percentile(h.load(), 0.5, 0))
+	for i := 0; i < 90; i++ {
+		h.record(time.Millisecond)
+	}
+	for i := 0; i < 9; i++ {
+		h.record(100 * time.Millisecond)
+	}
+	h.record(time.Second)
+	counts := h.load()
+	assert.InEpsilon(t, time.Millisecond, percentile(counts, 0.5, time.Second), 1.0/(1<<histogramSubBits))
+	assert.InEpsilon(t, time.Millisecond, percentile(counts, 0.9, time.Second), 1.0/(1<<histogramSubBits))
+	assert.InEpsilon(t, 100*time.Millisecond, percentile(counts, 0.99, time.Second), 1.0/(1<<histogramSubBits))
+	assert.Equal(t, time.Second, percentile(counts, 1, time.Second))
+}
+
+func TestCache_InFlight(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			release := make(chan struct{})
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				<-release
+				return "result-" + key, nil
+			}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, c.cacheOpts...)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, cache.InFlight())
+
+			cache.Notify(context.Background(), "k1")
+			cache.Notify(context.Background(), "k2")
+			done := make(chan struct{})
+			go func() {
+				_, _ = cache.Get(context.Background(), "k1") // coalesced
+				close(done)
+			}()
+			time.Sleep(50 * time.Millisecond)
+			assert.Equal(t, 2, cache.InFlight())
+
+			close(release)
+			<-done
+			assert.Eventually(t, func() bool { return cache.InFlight() == 0 }, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestCache_String(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	replaceFn := func(ctx context.Context, key string) (string, error) {
+		<-release
+		return "result-" + key, nil
+	}
+	cache, err := New[string, string](replaceFn, time.Minute, time.Minute, WithLRUBackend(10))
+	assert.NoError(t, err)
+	cache.Set("k1", "v1")
+	cache.Notify(context.Background(), "k2")
+	assert.Equal(t, "Cache(Backend: lru, Size: 1, Capacity: 10, InFlight: 1)", cache.String())
+	assert.Equal(t, cache.String(), fmt.Sprintf("%v", cache))
+	close(release)
+
+	sharded, err := NewSharded[string, string](replaceFn, time.Minute, time.Minute, WithLRUBackend(10), WithShards(2))
+	assert.NoError(t, err)
+	defer sharded.Close()
+	sharded.Set("k1", "v1")
+	assert.Equal(t, "ShardedCache(Backend: lru, Shards: 2, Size: 1, Capacity: 10, InFlight: 0)", sharded.String())
+}
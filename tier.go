@@ -0,0 +1,61 @@
+package sc
+
+import (
+	"context"
+	"errors"
+)
+
+// lowerTier is a lower tier cache consulted before replaceFn. See WithLowerTier.
+type lowerTier[K comparable, V any] struct {
+	get func(ctx context.Context, key K) (v V, ok bool, err error)
+	set func(ctx context.Context, key K, v V)
+}
+
+// wrap returns replaceFn which retrieves values from the lower tier, falling back to fn.
+func (t *lowerTier[K, V]) wrap(fn replaceFunc[K, V]) replaceFunc[K, V] {
+	return func(ctx context.Context, key K) (V, error) {
+		v, ok, err := t.get(ctx, key)
+		if err != nil || ok {
+			return v, err
+		}
+		v, err = fn(ctx, key)
+		if err == nil {
+			t.set(ctx, key, v)
+		}
+		return v, err
+	}
+}
+
+// wrapBatch returns batchFn which retrieves values from the lower tier, falling back to fn for the missing keys.
+func (t *lowerTier[K, V]) wrapBatch(fn batchReplaceFunc[K, V]) batchReplaceFunc[K, V] {
+	return func(ctx context.Context, keys []K) (map[K]V, error) {
+		values := make(map[K]V, len(keys))
+		var missing []K
+		for _, key := range keys {
+			v, ok, err := t.get(ctx, key)
+			if err != nil {
+				return values, err
+			}
+			if ok {
+				values[key] = v
+			} else {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) == 0 {
+			return values, nil
+		}
+
+		retrieved, err := fn(ctx, missing)
+		if err != nil && !errors.Is(err, ErrSkipCache) {
+			return values, err
+		}
+		for key, v := range retrieved {
+			if err == nil {
+				t.set(ctx, key, v)
+			}
+			values[key] = v
+		}
+		return values, err
+	}
+}
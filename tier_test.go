@@ -0,0 +1,189 @@
+package sc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTier is a lower tier cache for tests.
+type testTier struct {
+	mu     sync.Mutex
+	values map[string]string
+	// err is returned for errKey, or for all keys if errKey is empty.
+	err    error
+	errKey string
+}
+
+func (t *testTier) get(ctx context.Context, key string) (string, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil && (t.errKey == "" || t.errKey == key) {
+		return "", false, t.err
+	}
+	v, ok := t.values[key]
+	return v, ok, nil
+}
+
+func (t *testTier) set(ctx context.Context, key string, v string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.values[key] = v
+}
+
+func TestCache_LowerTier(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range allCaches(10) {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cnt int64
+			replaceFn := func(ctx context.Context, key string) (string, error) {
+				atomic.AddInt64(&cnt, 1)
+				if key == "error" {
+					return "", errors.New("test error")
+				}
+				return "value-" + key, nil
+			}
+			tier := &testTier{values: map[string]string{"k1": "tier-k1"}}
+			cache, err := New[string, string](replaceFn, time.Minute, time.Minute, append(c.cacheOpts, WithLowerTier(tier.get, tier.set))...)
+			assert.NoError(t, err)
+
+			// lower tier hit - replaceFn is not called
+			v, err := cache.Get(context.Background(), "k1")
+			assert.NoError(t, err)
+			assert.Equal(t, "tier-k1", v)
+			assert.EqualValues(t, 0, atomic.LoadInt64(&cnt))
+
+			// lower tier miss - retrieved value is written to the lower tier
+			v, err = cache.Get(context.Background(), "k2")
+			assert.NoError(t, err)
+			assert.Equal(t, "value-k2", v)
+			assert.EqualValues(t, 1, atomic.LoadInt64(&cnt))
+			assert.Equal(t, map[string]string{"k1": "tier-k1", "k2": "value-k2"}, tier.values)
+
+			// errors are not written
+			_, err = cache.Get(context.Background(), "error")
+			assert.Error(t, err)
+			assert.Len(t, tier.values, 2)
+
+			// errors of the lower tier are returned as is
+			tier.err = errors.New("tier error")
+			_, err = cache.Get(context.Background(), "k3")
+			assert.ErrorIs(t, err, tier.err)
+			assert.EqualValues(t, 2, atomic.LoadInt64(&cnt))
+		})
+	}
+
+	t.Run("batched", func(t *testing.T) {
+		t.Parallel()
+
+		var requested [][]string
+		batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+			requested = append(requested, keys)
+			values := make(map[string]string, len(keys))
+			for _, key := range keys {
+				values[key] = "value-" + key
+			}
+			return values, nil
+		}
+		tier := &testTier{values: map[string]string{"k1": "tier-k1"}}
+		cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, WithLowerTier(tier.get, tier.set))
+		assert.NoError(t, err)
+
+		values, err := cache.GetMulti(context.Background(), []string{"k1", "k2", "k3"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"k1": "tier-k1", "k2": "value-k2", "k3": "value-k3"}, values)
+		assert.Len(t, requested, 1)
+		assert.ElementsMatch(t, []string{"k2", "k3"}, requested[0])
+		assert.Equal(t, map[string]string{"k1": "tier-k1", "k2": "value-k2", "k3": "value-k3"}, tier.values)
+
+		v, err := cache.Get(context.Background(), "k4")
+		assert.NoError(t, err)
+		assert.Equal(t, "value-k4", v)
+		assert.Equal(t, "value-k4", tier.values["k4"])
+	})
+
+	t.Run("batched skip cache", func(t *testing.T) {
+		t.Parallel()
+
+		var requested [][]string
+		batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+			requested = append(requested, keys)
+			values := make(map[string]string, len(keys))
+			for _, key := range keys {
+				values[key] = "fallback-" + key
+			}
+			return values, ErrSkipCache
+		}
+		tier := &testTier{values: map[string]string{"k1": "tier-k1"}}
+		cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, WithLowerTier(tier.get, tier.set))
+		assert.NoError(t, err)
+
+		// values are returned together with the lower tier hits, but written to neither tier
+		values, err := cache.GetMulti(context.Background(), []string{"k1", "k2", "k3"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"k1": "tier-k1", "k2": "fallback-k2", "k3": "fallback-k3"}, values)
+		assert.Equal(t, map[string]string{"k1": "tier-k1"}, tier.values)
+		assert.Len(t, requested, 1)
+		assert.ElementsMatch(t, []string{"k2", "k3"}, requested[0])
+
+		values, err = cache.GetMulti(context.Background(), []string{"k2", "k3"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"k2": "fallback-k2", "k3": "fallback-k3"}, values)
+		assert.Len(t, requested, 2)
+		assert.Equal(t, map[string]string{"k1": "tier-k1"}, tier.values)
+	})
+
+	t.Run("batched lower tier error", func(t *testing.T) {
+		t.Parallel()
+
+		var requested [][]string
+		batchFn := func(ctx context.Context, keys []string) (map[string]string, error) {
+			requested = append(requested, keys)
+			values := make(map[string]string, len(keys))
+			for _, key := range keys {
+				values[key] = "value-" + key
+			}
+			return values, nil
+		}
+		tier := &testTier{values: map[string]string{"k1": "tier-k1"}, err: errors.New("tier error"), errKey: "k2"}
+		cache, err := NewBatched[string, string](batchFn, time.Minute, time.Minute, WithLowerTier(tier.get, tier.set))
+		assert.NoError(t, err)
+
+		// an error of the lower tier fails the whole batch, without calling batchFn
+		values, err := cache.GetMulti(context.Background(), []string{"k1", "k2", "k3"})
+		assert.ErrorIs(t, err, tier.err)
+		assert.Empty(t, values)
+		assert.Empty(t, requested)
+		assert.Equal(t, map[string]string{"k1": "tier-k1"}, tier.values)
+
+		// nothing is cached - the keys are retrieved again once the lower tier recovers
+		tier.mu.Lock()
+		tier.err = nil
+		tier.mu.Unlock()
+		values, err = cache.GetMulti(context.Background(), []string{"k1", "k2", "k3"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"k1": "tier-k1", "k2": "value-k2", "k3": "value-k3"}, values)
+		assert.Len(t, requested, 1)
+		assert.ElementsMatch(t, []string{"k2", "k3"}, requested[0])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		fn := func(ctx context.Context, s string) (string, error) { return "", nil }
+		tier := &testTier{}
+		_, err := New[int, string](func(ctx context.Context, key int) (string, error) { return "", nil }, 0, 0, WithLowerTier(tier.get, tier.set))
+		assert.Error(t, err)
+		_, err = New[string, string](fn, 0, 0, WithLowerTier(tier.get, nil))
+		assert.Error(t, err)
+	})
+}
@@ -1,6 +1,8 @@
 package tq
 
 import (
+	"time"
+
 	"github.com/motoki317/sc/lru"
 )
 
@@ -17,26 +19,50 @@ type Cache[K comparable, V any] struct {
 	size       int
 	recentSize int
 
-	recent      *lru.Cache[K, V]
-	frequent    *lru.Cache[K, V]
-	recentEvict *lru.Cache[K, struct{}]
+	recent   *lru.Cache[K, V]
+	frequent *lru.Cache[K, V]
+	// recentEvict holds the keys recently evicted from recent (ghosts), with the time they were evicted.
+	recentEvict *lru.Cache[K, time.Time]
+	// ghostTTL is the maximum age of ghosts, or 0 if ghosts never expire.
+	ghostTTL time.Duration
+	// onEvict is called when an item is evicted to make room for a new item, if non-nil.
+	onEvict func(key K, value V)
+	// scanningFrequent is true if DeleteIfN is visiting the frequently used items, false if the recently used items.
+	scanningFrequent bool
+	// stats holds the counters returned by Stats.
+	stats TQStats
 }
 
-// New creates a new Cache.
-func New[K comparable, V any](size int) *Cache[K, V] {
+// TQStats represents counters of the 2Q algorithm's internal behavior, which help tuning the cache for a workload.
+type TQStats struct {
+	// RecentEvictions is the number of items evicted from the recently used list. The keys become ghosts.
+	RecentEvictions uint64
+	// FrequentEvictions is the number of items evicted from the frequently used list.
+	FrequentEvictions uint64
+	// GhostHits is the number of keys set again while they were ghosts, and added directly to the frequently used list.
+	GhostHits uint64
+	// Promotions is the number of items moved from the recently used list to the frequently used list by Get or Set.
+	Promotions uint64
+}
+
+// subSizes determines the size of the recently used list and the number of ghosts, from the size of the cache.
+func subSizes(size int) (recentSize, evictSize int) {
 	const (
 		recentRatio = 0.5
 		ghostRatio  = 0.5
 	)
+	return int(float64(size) * recentRatio), int(float64(size) * ghostRatio)
+}
 
+// New creates a new Cache.
+func New[K comparable, V any](size int) *Cache[K, V] {
 	// Determine the sub-sizes
-	recentSize := int(float64(size) * recentRatio)
-	evictSize := int(float64(size) * ghostRatio)
+	recentSize, evictSize := subSizes(size)
 
 	// Allocate the LRUs
 	recent := lru.New[K, V](lru.WithCapacity(size))
 	frequent := lru.New[K, V](lru.WithCapacity(size))
-	recentEvict := lru.New[K, struct{}](lru.WithCapacity(evictSize))
+	recentEvict := lru.New[K, time.Time](lru.WithCapacity(evictSize))
 
 	// Initialize the cache
 	return &Cache[K, V]{
@@ -48,6 +74,28 @@ func New[K comparable, V any](size int) *Cache[K, V] {
 	}
 }
 
+// NewWithGhostTTL creates a new Cache whose ghost entries expire after ghostTTL.
+//
+// Ghost entries are the keys recently evicted from the recently used list, which are promoted to the frequently used
+// list when set again. Expiring ghosts prevents keys which will never return from polluting the frequency signal
+// in churny key spaces: a key set again after ghostTTL is treated as a new key.
+// Expired ghosts are also deleted by DeleteIf.
+//
+// ghostTTL of 0 or less means ghosts never expire, which is the same as New.
+func NewWithGhostTTL[K comparable, V any](size int, ghostTTL time.Duration) *Cache[K, V] {
+	c := New[K, V](size)
+	if ghostTTL > 0 {
+		c.ghostTTL = ghostTTL
+	}
+	return c
+}
+
+// SetEvictCallback sets a function called when an item is evicted to make room for a new item in Set.
+// The function is not called for items deleted explicitly, such as by Delete or Purge.
+func (c *Cache[K, V]) SetEvictCallback(onEvict func(key K, value V)) {
+	c.onEvict = onEvict
+}
+
 // Get looks up a key's value from the cache.
 func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
 	// Check if this is a frequent value
@@ -60,6 +108,7 @@ func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
 	if value, ok = c.recent.Peek(key); ok {
 		c.recent.Delete(key)
 		c.frequent.Set(key, value)
+		c.stats.Promotions++
 		return
 	}
 
@@ -67,6 +116,15 @@ func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
 	return
 }
 
+// Peek looks up a key's value from the cache without promoting it to the frequently used list,
+// and without updating the recent usage. This is the same as lru.Cache.Peek.
+func (c *Cache[K, V]) Peek(key K) (value V, ok bool) {
+	if value, ok = c.frequent.Peek(key); ok {
+		return
+	}
+	return c.recent.Peek(key)
+}
+
 // Set adds a value to the cache.
 func (c *Cache[K, V]) Set(key K, value V) {
 	// Check if the value is frequently used already,
@@ -81,23 +139,31 @@ func (c *Cache[K, V]) Set(key K, value V) {
 	if _, ok := c.recent.Peek(key); ok {
 		c.recent.Delete(key)
 		c.frequent.Set(key, value)
+		c.stats.Promotions++
 		return
 	}
 
 	// If the value was recently evicted, add it to the
 	// frequently used list
-	if _, ok := c.recentEvict.Peek(key); ok {
+	if evictedAt, ok := c.recentEvict.Peek(key); ok && !c.ghostExpired(evictedAt, time.Now()) {
 		c.ensureSpace(true)
 		c.recentEvict.Delete(key)
 		c.frequent.Set(key, value)
+		c.stats.GhostHits++
 		return
 	}
 
 	// Add to the recently seen list
+	c.recentEvict.Delete(key) // the ghost may have expired
 	c.ensureSpace(false)
 	c.recent.Set(key, value)
 }
 
+// ghostExpired reports whether a ghost evicted at evictedAt has expired at now.
+func (c *Cache[K, V]) ghostExpired(evictedAt, now time.Time) bool {
+	return c.ghostTTL > 0 && now.Sub(evictedAt) > c.ghostTTL
+}
+
 // ensureSpace is used to ensure we have space in the cache
 func (c *Cache[K, V]) ensureSpace(recentEvict bool) {
 	// If we have space, nothing to do
@@ -110,13 +176,30 @@ func (c *Cache[K, V]) ensureSpace(recentEvict bool) {
 	// If the recent buffer is larger than
 	// the target, evict from there
 	if recentLen > 0 && (recentLen > c.recentSize || (recentLen == c.recentSize && !recentEvict)) {
-		k, _, _ := c.recent.DeleteOldest()
-		c.recentEvict.Set(k, struct{}{})
+		k, v, _ := c.recent.DeleteOldest()
+		c.recentEvict.Set(k, time.Now())
+		c.stats.RecentEvictions++
+		if c.onEvict != nil {
+			c.onEvict(k, v)
+		}
 		return
 	}
 
 	// Remove from the frequent list otherwise
-	c.frequent.DeleteOldest()
+	k, v, ok := c.frequent.DeleteOldest()
+	if !ok {
+		return
+	}
+	c.stats.FrequentEvictions++
+	if c.onEvict != nil {
+		c.onEvict(k, v)
+	}
+}
+
+// Stats returns the counters of the 2Q algorithm's internal behavior since the cache was created.
+// Evictions by Resize are also counted, while deletions by Delete, DeleteIf or Purge are not.
+func (c *Cache[K, V]) Stats() TQStats {
+	return c.stats
 }
 
 // Len returns the number of items in the cache.
@@ -124,11 +207,63 @@ func (c *Cache[K, V]) Len() int {
 	return c.recent.Len() + c.frequent.Len()
 }
 
+// Range calls f for each item in the cache - frequently used items first, then recently used items,
+// each from the most recently used to the least recently used.
+// Iteration stops if f returns false.
+// This operation does not promote the items, and f must not modify the cache.
+func (c *Cache[K, V]) Range(f func(key K, value V) bool) {
+	cont := true
+	c.frequent.Range(func(key K, value V) bool {
+		cont = f(key, value)
+		return cont
+	})
+	if !cont {
+		return
+	}
+	c.recent.Range(f)
+}
+
 // DeleteIf deletes all elements that match the predicate.
+// Expired ghost entries are also deleted (see NewWithGhostTTL).
 func (c *Cache[K, V]) DeleteIf(predicate func(key K, value V) bool) {
 	c.frequent.DeleteIf(predicate)
 	c.recent.DeleteIf(predicate)
 	// does not add to recentEvict, but that is okay for sc's use-case
+	if c.ghostTTL > 0 {
+		now := time.Now()
+		c.recentEvict.DeleteIf(func(_ K, evictedAt time.Time) bool {
+			return c.ghostExpired(evictedAt, now)
+		})
+	}
+}
+
+// DeleteIfN is similar to DeleteIf, but calls predicate for at most n items.
+// Subsequent calls resume from the item next to the last visited one, visiting the recently used items and then
+// the frequently used items, each from the least recently used one. wrapped is true when all items have been visited
+// in turn, and the next call starts over. See also (*lru.Cache).DeleteIfN.
+// Expired ghost entries are deleted when wrapped.
+func (c *Cache[K, V]) DeleteIfN(n int, predicate func(key K, value V) bool) (visited int, wrapped bool) {
+	if !c.scanningFrequent {
+		v, w := c.recent.DeleteIfN(n, predicate)
+		visited += v
+		if !w {
+			return visited, false
+		}
+		c.scanningFrequent = true
+	}
+	v, w := c.frequent.DeleteIfN(n-visited, predicate)
+	visited += v
+	if !w {
+		return visited, false
+	}
+	c.scanningFrequent = false
+	if c.ghostTTL > 0 {
+		now := time.Now()
+		c.recentEvict.DeleteIf(func(_ K, evictedAt time.Time) bool {
+			return c.ghostExpired(evictedAt, now)
+		})
+	}
+	return visited, true
 }
 
 // Delete removes the provided key from the cache.
@@ -145,10 +280,29 @@ func (c *Cache[K, V]) Purge() {
 	c.recentEvict.Purge()
 }
 
+// Resize changes the size of the cache, evicting items if the cache is larger than the new size.
+// Items are evicted in the same order as Set evicts them to make room for new items, and the evict callback is called
+// for each evicted item. The number of ghost entries is also adjusted to the new size.
+// Returns the number of evicted items.
+func (c *Cache[K, V]) Resize(size int) (evicted int) {
+	recentSize, evictSize := subSizes(size)
+	c.size, c.recentSize = size, recentSize
+	for c.Len() > size {
+		c.ensureSpace(false)
+		evicted++
+	}
+	c.recent.Resize(size)
+	c.frequent.Resize(size)
+	c.recentEvict.Resize(evictSize)
+	return evicted
+}
+
+// Size returns the number of items in the cache. This is the same as Len.
 func (c *Cache[K, V]) Size() int {
 	return c.recent.Size() + c.frequent.Size()
 }
 
+// Capacity returns the maximum number of items in the cache.
 func (c *Cache[K, V]) Capacity() int {
 	return c.size
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"math/rand"
 	"testing"
+	"time"
 )
 
 func Benchmark2Q_Rand(b *testing.B) {
@@ -223,6 +224,36 @@ func TestCache_DeleteIf(t *testing.T) {
 	}
 }
 
+func TestCache_DeleteIfN(t *testing.T) {
+	l := New[int, int](128)
+
+	for i := 1; i <= 4; i++ {
+		l.Set(i, i)
+	}
+	l.Get(1) // 1 is frequently used
+
+	var keys []int
+	predicate := func(key int, value int) bool {
+		keys = append(keys, key)
+		return key%2 == 0
+	}
+	// recently used items first, then frequently used items
+	visited, wrapped := l.DeleteIfN(2, predicate)
+	require.Equal(t, 2, visited)
+	require.False(t, wrapped)
+	visited, wrapped = l.DeleteIfN(2, predicate)
+	require.Equal(t, 2, visited)
+	require.True(t, wrapped)
+	require.Equal(t, []int{2, 3, 4, 1}, keys)
+	require.Equal(t, 2, l.Len())
+
+	// starts over after wrapping
+	keys = nil
+	_, wrapped = l.DeleteIfN(10, predicate)
+	require.True(t, wrapped)
+	require.Equal(t, []int{3, 1}, keys)
+}
+
 func TestCache(t *testing.T) {
 	l := New[int, int](128)
 
@@ -279,3 +310,211 @@ func TestCache_Capacity(t *testing.T) {
 	l.Set(1, 1)
 	require.Equal(t, 10, l.Capacity())
 }
+
+func TestCache_Resize(t *testing.T) {
+	l := New[int, int](4)
+	var evicted []int
+	l.SetEvictCallback(func(key int, value int) {
+		evicted = append(evicted, key)
+	})
+
+	for i := 1; i <= 4; i++ {
+		l.Set(i, i)
+	}
+	l.Get(1) // promote to frequent
+
+	// shrinking evicts the oldest recent items first
+	require.Equal(t, 2, l.Resize(2))
+	require.Equal(t, []int{2, 3}, evicted)
+	require.Equal(t, 2, l.Capacity())
+	require.Equal(t, 2, l.Len())
+	require.Equal(t, 1, l.recentSize)
+	// the oldest ghost is dropped to fit the new size
+	require.Equal(t, 1, l.recentEvict.Len())
+
+	l.Set(3, 3) // ghost hit, evicts from frequent
+	require.Equal(t, []int{2, 3, 1}, evicted)
+	l.Set(2, 2) // not a ghost anymore, evicts from recent
+	require.Equal(t, []int{2, 3, 1, 4}, evicted)
+
+	// growing evicts nothing
+	require.Equal(t, 0, l.Resize(10))
+	for i := 5; i <= 12; i++ {
+		l.Set(i, i)
+	}
+	require.Equal(t, 10, l.Len())
+}
+
+func TestCache_CapacityOne(t *testing.T) {
+	l := New[int, int](1)
+
+	l.Set(1, 1)
+	l.Set(2, 2) // 1 is evicted
+	require.Equal(t, 1, l.Len())
+	_, ok := l.Get(1)
+	require.False(t, ok)
+
+	l.Set(1, 1) // 2 is evicted
+	require.Equal(t, 1, l.Len())
+	v, ok := l.Get(1)
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	_, ok = l.Get(2)
+	require.False(t, ok)
+}
+
+func TestCache_Range(t *testing.T) {
+	l := New[int, int](10)
+
+	l.Set(1, 10)
+	l.Set(2, 20)
+	l.Set(3, 30)
+	l.Get(2) // promote to frequent
+
+	var keys, values []int
+	l.Range(func(key int, value int) bool {
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	require.Equal(t, []int{2, 3, 1}, keys)
+	require.Equal(t, []int{20, 30, 10}, values)
+
+	keys = nil
+	l.Range(func(key int, value int) bool {
+		keys = append(keys, key)
+		return false
+	})
+	require.Equal(t, []int{2}, keys)
+
+	keys = nil
+	l.Range(func(key int, value int) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	require.Equal(t, []int{2, 3}, keys)
+}
+
+func TestCache_Peek(t *testing.T) {
+	l := New[int, int](10)
+
+	l.Set(1, 10)
+	v, ok := l.Peek(1)
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+	// peek does not promote to frequent
+	require.Equal(t, 1, l.recent.Len())
+	require.Equal(t, 0, l.frequent.Len())
+
+	l.Get(1)
+	v, ok = l.Peek(1)
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+
+	_, ok = l.Peek(2)
+	require.False(t, ok)
+}
+
+func TestCache_Peek_NoUpdate(t *testing.T) {
+	l := New[int, int](2)
+
+	// 1 and 2 are frequently used, 1 being the least recently used
+	l.Set(1, 1)
+	l.Set(2, 2)
+	l.Get(1)
+	l.Get(2)
+	require.Equal(t, 2, l.frequent.Len())
+
+	// Peek does not update the recent usage of 1
+	_, ok := l.Peek(1)
+	require.True(t, ok)
+	l.Set(3, 3)
+	_, ok = l.Peek(1)
+	require.False(t, ok)
+	_, ok = l.Peek(2)
+	require.True(t, ok)
+}
+
+func TestCache_SetEvictCallback(t *testing.T) {
+	l := New[int, int](4)
+	var evicted []int
+	l.SetEvictCallback(func(key int, value int) {
+		require.Equal(t, key*10, value)
+		evicted = append(evicted, key)
+	})
+
+	for i := 1; i <= 4; i++ {
+		l.Set(i, i*10)
+	}
+	l.Get(1) // promote to frequent
+	require.Empty(t, evicted)
+
+	l.Set(5, 50) // evicts the oldest recent item
+	require.Equal(t, []int{2}, evicted)
+	l.Set(6, 60)
+	require.Equal(t, []int{2, 3}, evicted)
+
+	l.Delete(4) // explicit deletion is not an eviction
+	l.Purge()
+	require.Equal(t, []int{2, 3}, evicted)
+}
+
+func TestCache_GhostTTL(t *testing.T) {
+	l := NewWithGhostTTL[int, int](4, 50*time.Millisecond)
+
+	// Add 1,2,3,4,5 -> Evict 1
+	for i := 1; i <= 5; i++ {
+		l.Set(i, i)
+	}
+	require.Equal(t, 1, l.recentEvict.Len())
+
+	// Ghost is alive - pull in the recently evicted to frequent -> Evict 2
+	l.Set(1, 1)
+	require.Equal(t, 1, l.frequent.Len())
+
+	// Add 6 -> Evict 3
+	l.Set(6, 6)
+	require.Equal(t, 2, l.recentEvict.Len())
+	time.Sleep(100 * time.Millisecond)
+
+	// Ghost is expired - treated as a new key -> Evict 4
+	l.Set(2, 2)
+	require.Equal(t, 1, l.frequent.Len())
+	_, ok := l.recent.Peek(2)
+	require.True(t, ok)
+
+	// Expired ghosts are swept by DeleteIf
+	require.Equal(t, 2, l.recentEvict.Len())
+	l.DeleteIf(func(int, int) bool { return false })
+	require.Equal(t, 1, l.recentEvict.Len())
+	_, ok = l.recentEvict.Peek(4)
+	require.True(t, ok)
+	require.Equal(t, 4, l.Len())
+}
+
+func TestCache_Stats(t *testing.T) {
+	l := New[int, int](4)
+	require.Equal(t, TQStats{}, l.Stats())
+
+	// Add 1,2,3,4,5 -> Evict 1 from recent
+	for i := 1; i <= 5; i++ {
+		l.Set(i, i)
+	}
+	require.Equal(t, TQStats{RecentEvictions: 1}, l.Stats())
+
+	// Promote 2 by Get, and 3 by Set
+	l.Get(2)
+	l.Set(3, 3)
+	require.Equal(t, TQStats{RecentEvictions: 1, Promotions: 2}, l.Stats())
+
+	// Ghost hit on 1 -> Evict 2 from frequent
+	l.Set(1, 1)
+	require.Equal(t, TQStats{RecentEvictions: 1, FrequentEvictions: 1, GhostHits: 1, Promotions: 2}, l.Stats())
+	_, ok := l.Peek(2)
+	require.False(t, ok)
+
+	// Frequent hits and deletions are not counted
+	l.Get(1)
+	l.Delete(3)
+	require.Equal(t, TQStats{RecentEvictions: 1, FrequentEvictions: 1, GhostHits: 1, Promotions: 2}, l.Stats())
+}
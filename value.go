@@ -1,6 +1,7 @@
 package sc
 
 import (
+	"math"
 	"time"
 )
 
@@ -28,12 +29,33 @@ type value[V any] struct {
 	// Storing created as monoTime instead of time.Time allows GC to skip the scan of values entirely if V does not
 	// contain pointers.
 	created monoTime
+	// freshFor and ttl are the durations of this value, which are usually the same as the cache-wide ones.
+	freshFor, ttl time.Duration
 }
 
+// forever is the duration of values which never become stale or expire. See WithNoExpiration.
+const forever = time.Duration(math.MaxInt64)
+
 func (v *value[V]) isFresh(now monoTime, freshFor time.Duration) bool {
+	if freshFor == forever {
+		return true
+	}
 	return now <= v.created+monoTime(freshFor)
 }
 
 func (v *value[V]) isExpired(now monoTime, ttl time.Duration) bool {
+	if ttl == forever {
+		return false
+	}
 	return v.created+monoTime(ttl) < now
 }
+
+// expireBy shortens freshFor and ttl of the value so that it expires no later than deadline.
+func (v *value[V]) expireBy(deadline monoTime) {
+	if limit := time.Duration(deadline - v.created); limit < v.ttl {
+		v.ttl = limit
+	}
+	if v.ttl < v.freshFor {
+		v.freshFor = v.ttl
+	}
+}
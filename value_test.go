@@ -64,3 +64,31 @@ func Test_value_isExpired(t *testing.T) {
 		})
 	}
 }
+
+func Test_value_expireBy(t *testing.T) {
+	tests := []struct {
+		name         string
+		deadline     monoTime
+		wantFreshFor time.Duration
+		wantTTL      time.Duration
+	}{
+		{"after ttl", monoTime(20 * time.Minute), 5 * time.Minute, 10 * time.Minute},
+		{"exact ttl", monoTime(10 * time.Minute), 5 * time.Minute, 10 * time.Minute},
+		{"before ttl", monoTime(7 * time.Minute), 5 * time.Minute, 7 * time.Minute},
+		{"before freshFor", monoTime(3 * time.Minute), 3 * time.Minute, 3 * time.Minute},
+		{"before created", monoTime(-1 * time.Minute), -1 * time.Minute, -1 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &value[string]{
+				v:        "",
+				created:  0,
+				freshFor: 5 * time.Minute,
+				ttl:      10 * time.Minute,
+			}
+			v.expireBy(tt.deadline)
+			assert.Equal(t, tt.wantFreshFor, v.freshFor)
+			assert.Equal(t, tt.wantTTL, v.ttl)
+		})
+	}
+}